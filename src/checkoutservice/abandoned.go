@@ -0,0 +1,56 @@
+package main
+
+import (
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	// abandonedCheckoutMetric counts the orders failing before the card is
+	// charged, by stage.
+	abandonedCheckoutMetric = "checkout_abandoned_total"
+	// failedAfterChargeMetric counts the orders failing once the card is
+	// charged, by stage.
+	failedAfterChargeMetric = "checkout_failed_after_charge_total"
+)
+
+// AbandonedCheckout describes an order which failed before the card was
+// charged.
+type AbandonedCheckout struct {
+	OrderID string
+	UserID  string
+	Email   string
+	// Stage is the stage of the checkout which failed, e.g. "prep" or
+	// "charge".
+	Stage string
+	// Items holds the priced items of the order, empty if it failed before
+	// they were priced.
+	Items []*pb.OrderItem
+	Err   error
+}
+
+// AbandonHook is told about abandoned checkouts, e.g. for remarketing.
+// CheckoutAbandoned is called on the request path and must not block.
+type AbandonHook interface {
+	CheckoutAbandoned(c *AbandonedCheckout)
+}
+
+// checkoutFailed reports an order of req failing at stage. Failures before
+// the charge are abandoned checkouts, those after it are counted apart as
+// the customer has paid.
+func (cs *checkoutService) checkoutFailed(orderID string, req *pb.PlaceOrderRequest, prep *orderPrep, stage string, charged bool, err error) {
+	if charged {
+		cs.stats().IncCounter(failedAfterChargeMetric, map[string]string{"stage": stage})
+		return
+	}
+	cs.stats().IncCounter(abandonedCheckoutMetric, map[string]string{"stage": stage})
+	if cs.abandonHook != nil {
+		cs.abandonHook.CheckoutAbandoned(&AbandonedCheckout{
+			OrderID: orderID,
+			UserID:  req.GetUserId(),
+			Email:   req.GetEmail(),
+			Stage:   stage,
+			Items:   prep.orderItems,
+			Err:     err,
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingAbandonHook keeps every abandoned checkout it is told about.
+type recordingAbandonHook struct {
+	abandoned []*AbandonedCheckout
+}
+
+func (h *recordingAbandonHook) CheckoutAbandoned(c *AbandonedCheckout) {
+	h.abandoned = append(h.abandoned, c)
+}
+
+func TestPlaceOrderPrepFailureIsAbandoned(t *testing.T) {
+	f := newFakeDownstreams()
+	f.shipping.quoteHook = func(context.Context) error {
+		return status.Error(codes.InvalidArgument, "cannot ship there")
+	}
+	cs := newTestCheckoutService(t, f)
+	m := &recordingMetrics{}
+	cs.metrics = m
+	hook := &recordingAbandonHook{}
+	cs.abandonHook = hook
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err == nil {
+		t.Fatal("PlaceOrder() succeeded, want the shipping quote failure")
+	}
+	if n := m.count(abandonedCheckoutMetric, map[string]string{"stage": "prep"}); n != 1 {
+		t.Errorf("%s{stage=prep} = %d, want 1", abandonedCheckoutMetric, n)
+	}
+	if n := m.count(failedAfterChargeMetric, nil); n != 0 {
+		t.Errorf("%s = %d, want 0", failedAfterChargeMetric, n)
+	}
+	if len(hook.abandoned) != 1 {
+		t.Fatalf("hook told about %d abandoned checkouts, want 1", len(hook.abandoned))
+	}
+	if c := hook.abandoned[0]; c.Stage != "prep" || c.UserID != "user-1" || c.Err == nil {
+		t.Errorf("abandoned checkout = %+v, want a prep failure of user-1", c)
+	}
+}
+
+func TestPlaceOrderShippingFailureIsNotAbandoned(t *testing.T) {
+	f := newFakeDownstreams()
+	f.shipping.shipErr = status.Error(codes.Unavailable, "no carrier available")
+	cs := newTestCheckoutService(t, f)
+	m := &recordingMetrics{}
+	cs.metrics = m
+	hook := &recordingAbandonHook{}
+	cs.abandonHook = hook
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err == nil {
+		t.Fatal("PlaceOrder() succeeded, want the shipping failure")
+	}
+	if n := m.count(failedAfterChargeMetric, map[string]string{"stage": "ship"}); n != 1 {
+		t.Errorf("%s{stage=ship} = %d, want 1", failedAfterChargeMetric, n)
+	}
+	if n := m.count(abandonedCheckoutMetric, nil); n != 0 || len(hook.abandoned) != 0 {
+		t.Errorf("shipping failure counted as an abandoned checkout")
+	}
+}
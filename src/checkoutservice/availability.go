@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// filterAvailable splits items between the ones that can be ordered and the
+// ones the catalog reports as unavailable, before any of them is priced. If
+// the catalog does not support availability checks, every item is kept.
+//
+// With backorders enabled, the unavailable items being restocked are
+// ordered anyway and returned in backorders along with the date they are
+// expected to be available again. Other unavailable items are left out with
+// partial fulfillment and fail the order otherwise.
+func (cs *checkoutService) filterAvailable(ctx context.Context, items []*pb.CartItem) (available, unavailable []*pb.CartItem, backorders map[string]string, err error) {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.GetProductId()
+	}
+	resp, err := cs.clients().catalog().CheckAvailability(ctx, &pb.CheckAvailabilityRequest{ProductIds: ids})
+	if status.Code(err) == codes.Unimplemented {
+		log.Debug("product catalog does not support availability checks")
+		return items, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, downstreamError(err, "failed to check product availability")
+	}
+
+	missing := make(map[string]bool, len(resp.GetUnavailableProductIds()))
+	for _, id := range resp.GetUnavailableProductIds() {
+		missing[id] = true
+	}
+	inStock := 0
+	for _, item := range items {
+		id := item.GetProductId()
+		if !missing[id] {
+			available = append(available, item)
+			inStock++
+			continue
+		}
+		if date, ok := resp.GetRestockDates()[id]; ok && cs.backorders {
+			if backorders == nil {
+				backorders = make(map[string]string)
+			}
+			backorders[id] = date
+			available = append(available, item)
+			continue
+		}
+		unavailable = append(unavailable, item)
+	}
+	if inStock == 0 {
+		return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "none of the products in the cart are available")
+	}
+	if len(unavailable) > 0 {
+		if !cs.partialFulfillment {
+			return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "product %s is not available", unavailable[0].GetProductId())
+		}
+		log.Infof("excluding %d unavailable products from the order", len(unavailable))
+	}
+	if len(backorders) > 0 {
+		log.Infof("backordering %d products", len(backorders))
+	}
+	return available, unavailable, backorders, nil
+}
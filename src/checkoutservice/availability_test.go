@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderExcludesUnavailableProducts(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.unavailable = map[string]bool{"66VCHSJNUP": true}
+	cs := newTestCheckoutService(t, f)
+	cs.partialFulfillment = true
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	order := resp.GetOrder()
+	if len(order.GetItems()) != 1 || order.GetItems()[0].GetItem().GetProductId() != "OLJCESPC7Z" {
+		t.Errorf("order items = %v, want only OLJCESPC7Z", order.GetItems())
+	}
+	if u := order.GetUnavailableItems(); len(u) != 1 || u[0].GetProductId() != "66VCHSJNUP" {
+		t.Errorf("unavailable items = %v, want 66VCHSJNUP", u)
+	}
+	// The unavailable product was never fetched.
+	if f.catalog.calls != 1 {
+		t.Errorf("catalog served %d products, want 1", f.catalog.calls)
+	}
+	if ship := f.shipping.shipped[0].GetItems(); len(ship) != 1 {
+		t.Errorf("shipped items = %v, want a single item", ship)
+	}
+}
+
+func TestPlaceOrderNothingAvailable(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.unavailable = map[string]bool{"66VCHSJNUP": true, "OLJCESPC7Z": true}
+	cs := newTestCheckoutService(t, f)
+	cs.partialFulfillment = true
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("PlaceOrder() = %v, want FailedPrecondition", err)
+	}
+	if f.payment.chargeCount() != 0 {
+		t.Error("order without available products should not be charged")
+	}
+}
+
+func TestPlaceOrderBackordersRestockedProducts(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.unavailable = map[string]bool{"66VCHSJNUP": true}
+	f.catalog.restockDates = map[string]string{"66VCHSJNUP": "2020-07-01"}
+	cs := newTestCheckoutService(t, f)
+	cs.backorders = true
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	var backordered []*pb.OrderItem
+	for _, it := range resp.GetOrder().GetItems() {
+		if it.GetBackordered() {
+			backordered = append(backordered, it)
+		}
+	}
+	if len(backordered) != 1 || backordered[0].GetItem().GetProductId() != "66VCHSJNUP" || backordered[0].GetAvailableDate() != "2020-07-01" {
+		t.Errorf("backordered items = %v, want 66VCHSJNUP available on 2020-07-01", backordered)
+	}
+	if n := len(resp.GetOrder().GetItems()); n != 2 {
+		t.Errorf("order has %d items, want the backordered one included", n)
+	}
+	// The backordered item is paid for but ships later.
+	charged := &pb.Money{CurrencyCode: "USD", Units: 726, Nanos: 980000000}
+	if len(f.payment.charges) != 1 || !proto.Equal(f.payment.charges[0].GetAmount(), charged) {
+		t.Errorf("charges = %v, want one of %v", f.payment.charges, charged)
+	}
+	if ship := f.shipping.shipped[0].GetItems(); len(ship) != 1 || ship[0].GetProductId() != "OLJCESPC7Z" {
+		t.Errorf("shipped items = %v, want only OLJCESPC7Z", ship)
+	}
+}
+
+func TestPlaceOrderUnavailableWithoutRestockDate(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.unavailable = map[string]bool{"66VCHSJNUP": true}
+	cs := newTestCheckoutService(t, f)
+	cs.backorders = true
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("PlaceOrder() = %v, want FailedPrecondition", err)
+	}
+	if f.payment.chargeCount() != 0 {
+		t.Error("order with an unavailable product should not be charged")
+	}
+}
+
+func TestAvailabilityNotCheckedWithoutPartialFulfillment(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if f.catalog.availabilityChecks != 0 {
+		t.Errorf("availability was checked %d times, want none", f.catalog.availabilityChecks)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// Card brands payments can be routed by.
+const (
+	brandAmex       = "amex"
+	brandVisa       = "visa"
+	brandMastercard = "mastercard"
+	brandDiscover   = "discover"
+)
+
+// cardBrand detects the brand of a card from the prefix of its number, or
+// returns "" if unknown.
+func cardBrand(number string) string {
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		p, err := strconv.Atoi(number[:n])
+		if err != nil {
+			return -1
+		}
+		return p
+	}
+	switch p2, p3, p4 := prefix(2), prefix(3), prefix(4); {
+	case p2 == 34 || p2 == 37:
+		return brandAmex
+	case strings.HasPrefix(number, "4"):
+		return brandVisa
+	case p2 >= 51 && p2 <= 55, p4 >= 2221 && p4 <= 2720:
+		return brandMastercard
+	case p4 == 6011, p2 == 65, p3 >= 644 && p3 <= 649:
+		return brandDiscover
+	}
+	return ""
+}
+
+// paymentRoute is a connection to the payment service handling a card
+// brand.
+type paymentRoute struct {
+	addr string
+	conn *grpc.ClientConn
+}
+
+// parsePaymentBrands parses a comma-separated list of BRAND=ADDRESS pairs,
+// e.g. "amex=paymentservice-amex:50051".
+func parsePaymentBrands(v string) (map[string]*paymentRoute, error) {
+	out := make(map[string]*paymentRoute)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid payment route %q, expected BRAND=ADDRESS", pair)
+		}
+		brand := strings.ToLower(strings.TrimSpace(kv[0]))
+		switch brand {
+		case brandAmex, brandVisa, brandMastercard, brandDiscover:
+		default:
+			return nil, fmt.Errorf("unsupported card brand %q, expected %s, %s, %s or %s", brand, brandAmex, brandVisa, brandMastercard, brandDiscover)
+		}
+		out[brand] = &paymentRoute{addr: strings.TrimSpace(kv[1])}
+	}
+	return out, nil
+}
+
+type cardBrandKey struct{}
+
+// withCardBrand returns a context routing the payment calls made with it to
+// the payment service of brand, when configured.
+func withCardBrand(ctx context.Context, brand string) context.Context {
+	return context.WithValue(ctx, cardBrandKey{}, brand)
+}
+
+// paymentClient returns a client of the payment service handling the card
+// brand of ctx, falling back to the primary payment service.
+func (cs *checkoutService) paymentClient(ctx context.Context) pb.PaymentServiceClient {
+	brand, _ := ctx.Value(cardBrandKey{}).(string)
+	if r, ok := cs.paymentBrands[brand]; ok && r.conn != nil {
+		return pb.NewPaymentServiceClient(r.conn)
+	}
+	return cs.clients().payment()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestCardBrand(t *testing.T) {
+	for number, want := range map[string]string{
+		"378282246310005":  brandAmex,
+		"340000000000009":  brandAmex,
+		"4432801561520454": brandVisa,
+		"5555555555554444": brandMastercard,
+		"2223003122003222": brandMastercard,
+		"6011111111111117": brandDiscover,
+		"6445644564456445": brandDiscover,
+		"3530111333300000": "",
+		"":                 "",
+	} {
+		if got := cardBrand(number); got != want {
+			t.Errorf("cardBrand(%q) = %q, want %q", number, got, want)
+		}
+	}
+}
+
+func TestPlaceOrderRoutesPaymentByCardBrand(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	amex := &fakePaymentService{}
+	r := &paymentRoute{addr: startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterPaymentServiceServer(s, amex)
+	})}
+	conn, err := grpc.Dial(r.addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r.conn = conn
+	cs.paymentBrands = map[string]*paymentRoute{brandAmex: r}
+
+	req := testOrderRequest()
+	req.CreditCard.CreditCardNumber = "378282246310005"
+	if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if amex.chargeCount() != 1 || f.payment.chargeCount() != 0 {
+		t.Errorf("got %d Amex and %d default charges of an Amex card, want it charged by Amex", amex.chargeCount(), f.payment.chargeCount())
+	}
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if amex.chargeCount() != 1 || f.payment.chargeCount() != 1 {
+		t.Errorf("got %d Amex and %d default charges after a Visa order, want the Visa card charged by default", amex.chargeCount(), f.payment.chargeCount())
+	}
+}
+
+func TestParsePaymentBrands(t *testing.T) {
+	got, err := parsePaymentBrands("AMEX=payment-amex:50051, visa=payment-visa:50051")
+	if err != nil {
+		t.Fatalf("parsePaymentBrands() failed: %v", err)
+	}
+	if got[brandAmex].addr != "payment-amex:50051" || got[brandVisa].addr != "payment-visa:50051" {
+		t.Errorf("parsePaymentBrands() = %v", got)
+	}
+	for _, bad := range []string{"amex", "amex=", "jcb=payment-jcb:50051"} {
+		if _, err := parsePaymentBrands(bad); err == nil {
+			t.Errorf("parsePaymentBrands(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	// cartExpectedItemsHeader is set by clients to the number of items they
+	// expect the cart to contain.
+	cartExpectedItemsHeader = "x-cart-expected-items"
+
+	defaultCartRetryDelay    = 100 * time.Millisecond
+	defaultCartRetryAttempts = 2
+)
+
+// getUserCartConsistent fetches the user cart and, when enabled, retries a
+// few times if the cart comes back empty although the client indicated that
+// it should contain items. This hides the short window during which a
+// just-added item is not yet visible.
+func (cs *checkoutService) getUserCartConsistent(ctx context.Context, userID string) ([]*pb.CartItem, error) {
+	items, err := cs.getUserCart(ctx, userID)
+	if err != nil || len(items) > 0 || !cs.cartConsistencyRetry || expectedCartItems(ctx) == 0 {
+		return items, err
+	}
+	for i := 0; i < cs.cartRetryAttempts && takeRetry(ctx); i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cs.cartRetryDelay):
+		}
+		log.Debugf("cart of user %q is empty but items were expected, retrying (%d/%d)", userID, i+1, cs.cartRetryAttempts)
+		if items, err = cs.getUserCart(ctx, userID); err != nil || len(items) > 0 {
+			return items, err
+		}
+	}
+	return items, nil
+}
+
+// expectedCartItems returns the number of items the client expects in the
+// cart, or zero if it did not say.
+func expectedCartItems(ctx context.Context) int {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0
+	}
+	v := md.Get(cartExpectedItemsHeader)
+	if len(v) == 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(v[0])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// mergeCartItems merges the items of a guest session into the user cart,
+// summing the quantities of the products found in both. The cart order is
+// kept, guest-only products come last. A product listed several times ends
+// up on a single line, with the first note given for it.
+func mergeCartItems(cart, guest []*pb.CartItem) []*pb.CartItem {
+	merged := make([]*pb.CartItem, 0, len(cart)+len(guest))
+	index := make(map[string]int, len(cart)+len(guest))
+	for _, items := range [][]*pb.CartItem{cart, guest} {
+		for _, it := range items {
+			if i, ok := index[it.GetProductId()]; ok {
+				merged[i].Quantity += it.GetQuantity()
+				merged[i].GiftWrap = merged[i].GiftWrap || it.GetGiftWrap()
+				if merged[i].Note == "" {
+					merged[i].Note = it.GetNote()
+				}
+				continue
+			}
+			index[it.GetProductId()] = len(merged)
+			merged = append(merged, &pb.CartItem{ProductId: it.GetProductId(), Quantity: it.GetQuantity(), GiftWrap: it.GetGiftWrap(), Note: it.GetNote()})
+		}
+	}
+	return merged
+}
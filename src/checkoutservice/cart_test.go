@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestGetUserCartConsistentRetriesEmptyCart(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		hint      string
+		wantItems int
+		wantCalls int
+	}{
+		{"retry enabled with hint", true, "2", 2, 2},
+		{"retry disabled", false, "2", 0, 1},
+		{"no hint", true, "", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.cart.emptyReads = 1
+			cs := newTestCheckoutService(t, f)
+			cs.cartConsistencyRetry = tt.enabled
+			cs.cartRetryAttempts = 2
+			cs.cartRetryDelay = time.Millisecond
+
+			ctx := context.Background()
+			if tt.hint != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(cartExpectedItemsHeader, tt.hint))
+			}
+			items, err := cs.getUserCartConsistent(ctx, "user-1")
+			if err != nil {
+				t.Fatalf("getUserCartConsistent() failed: %v", err)
+			}
+			if len(items) != tt.wantItems {
+				t.Errorf("got %d items, want %d", len(items), tt.wantItems)
+			}
+			if f.cart.getCalls != tt.wantCalls {
+				t.Errorf("GetCart was called %d times, want %d", f.cart.getCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderMergesGuestItems(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		f := newFakeDownstreams()
+		cs := newTestCheckoutService(t, f)
+		cs.mergeGuestCart = enabled
+
+		req := testOrderRequest()
+		req.GuestItems = []*pb.CartItem{
+			{ProductId: "66VCHSJNUP", Quantity: 3},
+			{ProductId: "OLJCESPC7Z", Quantity: 1},
+			{ProductId: "66VCHSJNUP", Quantity: 1},
+		}
+		resp, err := cs.PlaceOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("enabled=%v: PlaceOrder() failed: %v", enabled, err)
+		}
+
+		want := map[string]int32{"OLJCESPC7Z": 1, "66VCHSJNUP": 2}
+		if enabled {
+			want = map[string]int32{"OLJCESPC7Z": 2, "66VCHSJNUP": 6}
+		}
+		items := resp.GetOrder().GetItems()
+		if len(items) != len(want) {
+			t.Fatalf("enabled=%v: order has %d items, want %d", enabled, len(items), len(want))
+		}
+		for _, it := range items {
+			if q := it.GetItem().GetQuantity(); q != want[it.GetItem().GetProductId()] {
+				t.Errorf("enabled=%v: quantity of %s = %d, want %d", enabled, it.GetItem().GetProductId(), q, want[it.GetItem().GetProductId()])
+			}
+		}
+	}
+}
+
+func TestPlaceOrderChargesEveryMergedUnit(t *testing.T) {
+	for _, merge := range []bool{false, true} {
+		f := newFakeDownstreams()
+		f.cart.carts["user-1"] = []*pb.CartItem{{ProductId: "OLJCESPC7Z", Quantity: 1}, {ProductId: "OLJCESPC7Z", Quantity: 2}}
+		f.shipping.quote = &pb.Money{CurrencyCode: "USD"}
+		cs := newTestCheckoutService(t, f)
+		cs.mergeDuplicateItems = merge
+
+		if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+			t.Fatalf("merge=%v: PlaceOrder() failed: %v", merge, err)
+		}
+		// 3 * 19.99 USD, on one line or two.
+		if got := f.payment.charges[0].GetAmount(); got.GetUnits() != 59 || got.GetNanos() != 970000000 {
+			t.Errorf("merge=%v: charged %v, want 59.97 USD", merge, got)
+		}
+	}
+}
+
+func TestPlaceOrderRejectsInvalidGuestItems(t *testing.T) {
+	cs := &checkoutService{}
+	req := testOrderRequest()
+	req.GuestItems = []*pb.CartItem{{ProductId: "OLJCESPC7Z", Quantity: 0}}
+	if status.Code(cs.validateOrderRequest(req)) != codes.InvalidArgument {
+		t.Error("guest item with no quantity should be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// clientFactory provides the clients of the downstream services. Tests
+// replace it to run against in-memory fakes.
+type clientFactory interface {
+	cart() pb.CartServiceClient
+	catalog() pb.ProductCatalogServiceClient
+	currency() pb.CurrencyServiceClient
+	shipping() pb.ShippingServiceClient
+	payment() pb.PaymentServiceClient
+	email() pb.EmailServiceClient
+	sms() pb.SmsServiceClient
+	loyalty() pb.LoyaltyServiceClient
+}
+
+// connClients builds clients on the connections opened by dialServices.
+type connClients struct {
+	cs *checkoutService
+}
+
+func (c connClients) cart() pb.CartServiceClient {
+	return pb.NewCartServiceClient(c.cs.cartSvcConn)
+}
+
+func (c connClients) catalog() pb.ProductCatalogServiceClient {
+	return pb.NewProductCatalogServiceClient(c.cs.productCatalogSvcConn)
+}
+
+func (c connClients) currency() pb.CurrencyServiceClient {
+	return pb.NewCurrencyServiceClient(c.cs.currencySvcConn)
+}
+
+func (c connClients) shipping() pb.ShippingServiceClient {
+	return pb.NewShippingServiceClient(c.cs.shippingSvcConn)
+}
+
+func (c connClients) payment() pb.PaymentServiceClient {
+	return pb.NewPaymentServiceClient(c.cs.paymentSvcConn)
+}
+
+func (c connClients) email() pb.EmailServiceClient {
+	return pb.NewEmailServiceClient(c.cs.emailSvcConn)
+}
+
+func (c connClients) sms() pb.SmsServiceClient {
+	return pb.NewSmsServiceClient(c.cs.smsSvcConn)
+}
+
+func (c connClients) loyalty() pb.LoyaltyServiceClient {
+	return pb.NewLoyaltyServiceClient(c.cs.loyaltySvcConn)
+}
+
+// clients returns the configured client factory, defaulting to the shared
+// downstream connections.
+func (cs *checkoutService) clients() clientFactory {
+	if cs.clientFactory != nil {
+		return cs.clientFactory
+	}
+	return connClients{cs}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// inMemoryClients calls the fakes directly, without any connection.
+type inMemoryClients struct {
+	f *fakeDownstreams
+}
+
+func (c inMemoryClients) cart() pb.CartServiceClient              { return cartClient{c.f.cart} }
+func (c inMemoryClients) catalog() pb.ProductCatalogServiceClient { return catalogClient{c.f.catalog} }
+func (c inMemoryClients) currency() pb.CurrencyServiceClient      { return currencyClient{c.f.currency} }
+func (c inMemoryClients) shipping() pb.ShippingServiceClient      { return shippingClient{c.f.shipping} }
+func (c inMemoryClients) payment() pb.PaymentServiceClient        { return paymentClient{c.f.payment} }
+func (c inMemoryClients) email() pb.EmailServiceClient            { return emailClient{c.f.email} }
+func (c inMemoryClients) sms() pb.SmsServiceClient                { return smsClient{c.f.sms} }
+func (c inMemoryClients) loyalty() pb.LoyaltyServiceClient        { return loyaltyClient{c.f.loyalty} }
+
+type cartClient struct{ s pb.CartServiceServer }
+
+func (c cartClient) AddItem(ctx context.Context, in *pb.AddItemRequest, _ ...grpc.CallOption) (*pb.Empty, error) {
+	return c.s.AddItem(ctx, in)
+}
+
+func (c cartClient) GetCart(ctx context.Context, in *pb.GetCartRequest, _ ...grpc.CallOption) (*pb.Cart, error) {
+	return c.s.GetCart(ctx, in)
+}
+
+func (c cartClient) EmptyCart(ctx context.Context, in *pb.EmptyCartRequest, _ ...grpc.CallOption) (*pb.Empty, error) {
+	return c.s.EmptyCart(ctx, in)
+}
+
+type catalogClient struct {
+	s pb.ProductCatalogServiceServer
+}
+
+func (c catalogClient) ListProducts(ctx context.Context, in *pb.Empty, _ ...grpc.CallOption) (*pb.ListProductsResponse, error) {
+	return c.s.ListProducts(ctx, in)
+}
+
+func (c catalogClient) GetProduct(ctx context.Context, in *pb.GetProductRequest, _ ...grpc.CallOption) (*pb.Product, error) {
+	return c.s.GetProduct(ctx, in)
+}
+
+func (c catalogClient) SearchProducts(ctx context.Context, in *pb.SearchProductsRequest, _ ...grpc.CallOption) (*pb.SearchProductsResponse, error) {
+	return c.s.SearchProducts(ctx, in)
+}
+
+func (c catalogClient) CheckAvailability(ctx context.Context, in *pb.CheckAvailabilityRequest, _ ...grpc.CallOption) (*pb.CheckAvailabilityResponse, error) {
+	return c.s.CheckAvailability(ctx, in)
+}
+
+type currencyClient struct{ s pb.CurrencyServiceServer }
+
+func (c currencyClient) GetSupportedCurrencies(ctx context.Context, in *pb.Empty, _ ...grpc.CallOption) (*pb.GetSupportedCurrenciesResponse, error) {
+	return c.s.GetSupportedCurrencies(ctx, in)
+}
+
+func (c currencyClient) Convert(ctx context.Context, in *pb.CurrencyConversionRequest, _ ...grpc.CallOption) (*pb.Money, error) {
+	return c.s.Convert(ctx, in)
+}
+
+func (c currencyClient) ConvertBatch(ctx context.Context, in *pb.CurrencyConversionBatchRequest, _ ...grpc.CallOption) (*pb.CurrencyConversionBatchResponse, error) {
+	return c.s.ConvertBatch(ctx, in)
+}
+
+type shippingClient struct{ s pb.ShippingServiceServer }
+
+func (c shippingClient) GetQuote(ctx context.Context, in *pb.GetQuoteRequest, _ ...grpc.CallOption) (*pb.GetQuoteResponse, error) {
+	return c.s.GetQuote(ctx, in)
+}
+
+func (c shippingClient) ShipOrder(ctx context.Context, in *pb.ShipOrderRequest, _ ...grpc.CallOption) (*pb.ShipOrderResponse, error) {
+	return c.s.ShipOrder(ctx, in)
+}
+
+type paymentClient struct{ s pb.PaymentServiceServer }
+
+func (c paymentClient) Charge(ctx context.Context, in *pb.ChargeRequest, _ ...grpc.CallOption) (*pb.ChargeResponse, error) {
+	return c.s.Charge(ctx, in)
+}
+
+func (c paymentClient) Capture(ctx context.Context, in *pb.CaptureRequest, _ ...grpc.CallOption) (*pb.CaptureResponse, error) {
+	return c.s.Capture(ctx, in)
+}
+
+func (c paymentClient) Void(ctx context.Context, in *pb.VoidRequest, _ ...grpc.CallOption) (*pb.VoidResponse, error) {
+	return c.s.Void(ctx, in)
+}
+
+type emailClient struct{ s pb.EmailServiceServer }
+
+func (c emailClient) SendOrderConfirmation(ctx context.Context, in *pb.SendOrderConfirmationRequest, _ ...grpc.CallOption) (*pb.Empty, error) {
+	return c.s.SendOrderConfirmation(ctx, in)
+}
+
+type smsClient struct{ s pb.SmsServiceServer }
+
+func (c smsClient) SendOrderConfirmation(ctx context.Context, in *pb.SendSmsConfirmationRequest, _ ...grpc.CallOption) (*pb.Empty, error) {
+	return c.s.SendOrderConfirmation(ctx, in)
+}
+
+type loyaltyClient struct{ s pb.LoyaltyServiceServer }
+
+func (c loyaltyClient) GetPointsBalance(ctx context.Context, in *pb.GetPointsBalanceRequest, _ ...grpc.CallOption) (*pb.PointsBalance, error) {
+	return c.s.GetPointsBalance(ctx, in)
+}
+
+func (c loyaltyClient) RedeemPoints(ctx context.Context, in *pb.RedeemPointsRequest, _ ...grpc.CallOption) (*pb.Empty, error) {
+	return c.s.RedeemPoints(ctx, in)
+}
+
+func TestPlaceOrderWithInMemoryClients(t *testing.T) {
+	f := newFakeDownstreams()
+	// No addresses and no connections: every call goes to the fakes.
+	cs := &checkoutService{clientFactory: inMemoryClients{f}, orders: newOrderStore()}
+
+	req := testOrderRequest()
+	req.UserCurrency = "EUR"
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if resp.GetOrder().GetShippingTrackingId() != "TRACK-1" {
+		t.Errorf("tracking id = %q, want TRACK-1", resp.GetOrder().GetShippingTrackingId())
+	}
+	if f.payment.chargeCount() != 1 || f.email.sentCount() != 1 {
+		t.Errorf("got %d charges and %d confirmations, want one of each", f.payment.chargeCount(), f.email.sentCount())
+	}
+	if len(f.cart.emptied) != 1 || f.cart.emptied[0] != "user-1" {
+		t.Errorf("emptied carts = %v, want [user-1]", f.cart.emptied)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// parseCompression validates the name of a gRPC compressor. Only gzip is
+// supported, an empty value or "none" disables compression.
+func parseCompression(v string) (string, error) {
+	switch v {
+	case "", "none":
+		return "", nil
+	case gzip.Name:
+		return gzip.Name, nil
+	}
+	return "", fmt.Errorf("unsupported compression %q, expected %q or none", v, gzip.Name)
+}
+
+// serverCompressionOptions compresses every response with the named
+// compressor, whatever the request used. Clients must have the compressor
+// registered to decode the responses. Compressed requests are decoded by the
+// compressors registered with the encoding package, gzip included.
+func serverCompressionOptions(compression string) []grpc.ServerOption {
+	if compression != gzip.Name {
+		return nil
+	}
+	return []grpc.ServerOption{grpc.RPCCompressor(grpc.NewGZIPCompressor())}
+}
+
+// clientCompressionOption compresses the requests sent to downstream
+// services with the named compressor, if any.
+func clientCompressionOption(compression string) grpc.DialOption {
+	if compression == "" {
+		return nil
+	}
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(compression))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// payloadRecorder records the size of the payloads received by a client.
+type payloadRecorder struct {
+	mu       sync.Mutex
+	payloads []*stats.InPayload
+}
+
+func (r *payloadRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+func (r *payloadRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+func (r *payloadRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func (r *payloadRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if p, ok := s.(*stats.InPayload); ok {
+		r.mu.Lock()
+		r.payloads = append(r.payloads, p)
+		r.mu.Unlock()
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for in, want := range map[string]string{"": "", "none": "", "gzip": "gzip"} {
+		if got, err := parseCompression(in); err != nil || got != want {
+			t.Errorf("parseCompression(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := parseCompression("snappy"); err == nil {
+		t.Error("parseCompression(\"snappy\") should fail")
+	}
+}
+
+func TestPlaceOrderResponseCompressed(t *testing.T) {
+	f := newFakeDownstreams()
+	var cart []*pb.CartItem
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("PRODUCT%04d", i)
+		f.catalog.products[id] = &pb.Product{Id: id, PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 10}}
+		cart = append(cart, &pb.CartItem{ProductId: id, Quantity: 1})
+	}
+	f.cart.carts["user-1"] = cart
+	cs := newTestCheckoutService(t, f)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(serverCompressionOptions("gzip")...)
+	pb.RegisterCheckoutServiceServer(srv, cs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	rec := &payloadRecorder{}
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(), grpc.WithStatsHandler(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewCheckoutServiceClient(conn).PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if n := len(resp.GetOrder().GetItems()); n != 200 {
+		t.Errorf("order has %d items, want 200", n)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.payloads) != 1 {
+		t.Fatalf("got %d response payloads, want 1", len(rec.payloads))
+	}
+	if p := rec.payloads[0]; p.WireLength >= p.Length {
+		t.Errorf("response took %d bytes on the wire for %d bytes decoded, want it compressed", p.WireLength, p.Length)
+	}
+}
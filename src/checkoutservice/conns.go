@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	warmUpTimeout            = 10 * time.Second
+	defaultMinConnectTimeout = 20 * time.Second
+
+	defaultMaxConnFailure = 30 * time.Second
+	reconnectMetric       = "checkout_downstream_reconnects_total"
+	dialErrorsMetric      = "checkout_dial_errors_total"
+)
+
+// withConnectParams and newTLSCredentials are replaced in tests to inspect
+// the dial options.
+var (
+	withConnectParams = grpc.WithConnectParams
+	newTLSCredentials = credentials.NewTLS
+)
+
+// tlsServerNameEnv names the environment variable overriding the TLS server
+// name of each downstream service, for when the dial target differs from
+// the name in its certificate.
+var tlsServerNameEnv = map[string]string{
+	"productcatalogservice": "PRODUCT_CATALOG_SERVICE_TLS_SERVERNAME",
+	"cartservice":           "CART_SERVICE_TLS_SERVERNAME",
+	"currencyservice":       "CURRENCY_SERVICE_TLS_SERVERNAME",
+	"shippingservice":       "SHIPPING_SERVICE_TLS_SERVERNAME",
+	"emailservice":          "EMAIL_SERVICE_TLS_SERVERNAME",
+	"paymentservice":        "PAYMENT_SERVICE_TLS_SERVERNAME",
+	"smsservice":            "SMS_SERVICE_TLS_SERVERNAME",
+	"loyaltyservice":        "LOYALTY_SERVICE_TLS_SERVERNAME",
+}
+
+// defaultConnectParams returns gRPC's default reconnection policy.
+func defaultConnectParams() grpc.ConnectParams {
+	return grpc.ConnectParams{
+		Backoff:           backoff.DefaultConfig,
+		MinConnectTimeout: defaultMinConnectTimeout,
+	}
+}
+
+// dialOptions returns the options used to dial the given downstream service.
+func (cs *checkoutService) dialOptions(service string) []grpc.DialOption {
+	params := cs.connectParams
+	if params.Backoff.BaseDelay == 0 {
+		params = defaultConnectParams()
+	}
+	opts := []grpc.DialOption{
+		cs.transportCredentials(service),
+		withConnectParams(params),
+	}
+	if opt := clientCompressionOption(cs.downstreamCompression); opt != nil {
+		opts = append(opts, opt)
+	}
+	if cs.traceDials {
+		opts = append(opts, grpc.WithContextDialer(cs.dialer(service)))
+	}
+	return opts
+}
+
+// dialer opens the connections to service, tracing and counting the
+// attempts which fail, e.g. because the address does not resolve or the
+// connection is refused, apart from the errors of the calls.
+func (cs *checkoutService) dialer(service string) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			cs.dialFailed(service, addr, err)
+		}
+		return conn, err
+	}
+}
+
+// dialFailed reports a failure to connect to service at target.
+func (cs *checkoutService) dialFailed(service, target string, err error) {
+	span, _ := cs.trace().StartSpan(context.Background(), "checkout.dial")
+	span.SetTag("service", service)
+	span.SetTag("target", target)
+	span.Finish(err)
+	cs.stats().IncCounter(dialErrorsMetric, map[string]string{"service": service})
+	log.Debugf("failed to connect to %s (%s): %v", service, target, err)
+}
+
+// transportCredentials secures the connection to service with TLS when
+// enabled, honoring its server name override if any.
+func (cs *checkoutService) transportCredentials(service string) grpc.DialOption {
+	if !cs.downstreamTLS {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(newTLSCredentials(&tls.Config{
+		ServerName: cs.tlsServerNames[service],
+	}))
+}
+
+// downstream describes one of the services checkout depends on.
+type downstream struct {
+	name string
+	addr string
+	conn **grpc.ClientConn
+}
+
+func (cs *checkoutService) downstreams() []downstream {
+	ds := []downstream{
+		{"productcatalogservice", cs.productCatalogSvcAddr, &cs.productCatalogSvcConn},
+		{"cartservice", cs.cartSvcAddr, &cs.cartSvcConn},
+		{"currencyservice", cs.currencySvcAddr, &cs.currencySvcConn},
+		{"shippingservice", cs.shippingSvcAddr, &cs.shippingSvcConn},
+		{"emailservice", cs.emailSvcAddr, &cs.emailSvcConn},
+		{"paymentservice", cs.paymentSvcAddr, &cs.paymentSvcConn},
+	}
+	if cs.smsSvcAddr != "" {
+		ds = append(ds, downstream{"smsservice", cs.smsSvcAddr, &cs.smsSvcConn})
+	}
+	if cs.loyaltySvcAddr != "" {
+		ds = append(ds, downstream{"loyaltyservice", cs.loyaltySvcAddr, &cs.loyaltySvcConn})
+	}
+	// Hedged requests use their own connections, to other replicas.
+	if cs.hedgeDelay > 0 && cs.productCatalogHedgeAddr != "" {
+		ds = append(ds, downstream{"productcatalogservice", cs.productCatalogHedgeAddr, &cs.productCatalogHedgeConn})
+	}
+	if cs.hedgeDelay > 0 && cs.currencyHedgeAddr != "" {
+		ds = append(ds, downstream{"currencyservice", cs.currencyHedgeAddr, &cs.currencyHedgeConn})
+	}
+	for _, rc := range cs.currencyRegions {
+		ds = append(ds, downstream{"currencyservice", rc.addr, &rc.conn})
+	}
+	for _, r := range cs.paymentBrands {
+		ds = append(ds, downstream{"paymentservice", r.addr, &r.conn})
+	}
+	return ds
+}
+
+// dialServices creates one connection per downstream service which is then
+// shared by all requests. Connections are established lazily, on first use.
+func (cs *checkoutService) dialServices(ctx context.Context) error {
+	for _, d := range cs.downstreams() {
+		opts := append(cs.dialOptions(d.name), grpc.WithChainUnaryInterceptor(
+			cs.deadlineInterceptor(d.name),
+			cs.retryInterceptor(d.name),
+			cs.budgetInterceptor,
+			cs.downstreamCallInterceptor(d.name, d.addr),
+			rpcBudgetInterceptor))
+		conn, err := grpc.DialContext(ctx, d.addr, opts...)
+		if err != nil {
+			if cs.traceDials {
+				cs.dialFailed(d.name, d.addr, err)
+			}
+			return fmt.Errorf("could not connect %s: %+v", d.name, err)
+		}
+		*d.conn = conn
+	}
+	return nil
+}
+
+// closeConns closes all connections opened by dialServices.
+func (cs *checkoutService) closeConns() {
+	for _, d := range cs.downstreams() {
+		if *d.conn != nil {
+			(*d.conn).Close()
+		}
+	}
+}
+
+// warmUpConnections establishes every downstream connection ahead of the
+// first request by issuing a health check on it. Failures are only logged so
+// that an unavailable dependency does not prevent the service from starting.
+func (cs *checkoutService) warmUpConnections(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, d := range cs.downstreams() {
+		wg.Add(1)
+		go func(d downstream) {
+			defer wg.Done()
+			_, err := healthpb.NewHealthClient(*d.conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+			if err != nil {
+				log.Warnf("failed to warm up connection to %s (%s): %+v", d.name, d.addr, err)
+				return
+			}
+			log.Debugf("warmed up connection to %s (%s)", d.name, d.addr)
+		}(d)
+	}
+	wg.Wait()
+}
+
+// monitoredConn is the part of a *grpc.ClientConn the connection monitor
+// relies on, replaced in tests to simulate failing connections.
+type monitoredConn interface {
+	GetState() connectivity.State
+	ResetConnectBackoff()
+}
+
+// checkConn forces conn to reconnect when it has been in TransientFailure
+// for maxFailure or more, rather than waiting for its backoff to expire.
+// failingSince records since when each connection has been failing.
+func (cs *checkoutService) checkConn(name string, conn monitoredConn, failingSince map[monitoredConn]time.Time, maxFailure time.Duration) {
+	if conn.GetState() != connectivity.TransientFailure {
+		delete(failingSince, conn)
+		return
+	}
+	since, ok := failingSince[conn]
+	if !ok {
+		failingSince[conn] = cs.now()
+		return
+	}
+	if d := cs.now().Sub(since); d >= maxFailure {
+		log.Warnf("connection to %s has been failing for %v, reconnecting", name, d)
+		conn.ResetConnectBackoff()
+		cs.stats().IncCounter(reconnectMetric, map[string]string{"service": name})
+		failingSince[conn] = cs.now()
+	}
+}
+
+// startConnMonitor checks the state of every downstream connection each
+// interval until stopConnMonitor is called.
+func (cs *checkoutService) startConnMonitor(interval, maxFailure time.Duration) {
+	cs.connMonitorDone = make(chan struct{})
+	go func() {
+		failingSince := make(map[monitoredConn]time.Time)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				for _, d := range cs.downstreams() {
+					if *d.conn != nil {
+						cs.checkConn(d.name, *d.conn, failingSince, maxFailure)
+					}
+				}
+			case <-cs.connMonitorDone:
+				return
+			}
+		}
+	}()
+}
+
+func (cs *checkoutService) stopConnMonitor() {
+	if cs.connMonitorDone != nil {
+		close(cs.connMonitorDone)
+	}
+}
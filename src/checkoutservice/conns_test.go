@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestWarmUpConnections(t *testing.T) {
+	addr := startFakeServer(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	})
+	cs := &checkoutService{
+		productCatalogSvcAddr: addr,
+		cartSvcAddr:           addr,
+		currencySvcAddr:       addr,
+		shippingSvcAddr:       addr,
+		emailSvcAddr:          addr,
+		paymentSvcAddr:        addr,
+	}
+	dialTestService(t, cs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cs.warmUpConnections(ctx)
+
+	for _, d := range cs.downstreams() {
+		if s := (*d.conn).GetState(); s != connectivity.Ready {
+			t.Errorf("connection to %s is %v after warm-up, want READY", d.name, s)
+		}
+	}
+}
+
+func TestWarmUpConnectionsDoesNotBlockOnFailure(t *testing.T) {
+	// Reserve a port and close it so that nothing is listening there.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := lis.Addr().String()
+	lis.Close()
+
+	cs := &checkoutService{
+		productCatalogSvcAddr: down,
+		cartSvcAddr:           down,
+		currencySvcAddr:       down,
+		shippingSvcAddr:       down,
+		emailSvcAddr:          down,
+		paymentSvcAddr:        down,
+	}
+	dialTestService(t, cs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		cs.warmUpConnections(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("warm-up did not return after its deadline")
+	}
+}
+
+func TestDialFailuresAreCounted(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := lis.Addr().String()
+	lis.Close()
+
+	m := &recordingMetrics{}
+	tr := &recordingTracer{}
+	cs := &checkoutService{
+		productCatalogSvcAddr: down,
+		cartSvcAddr:           down,
+		currencySvcAddr:       down,
+		shippingSvcAddr:       down,
+		emailSvcAddr:          down,
+		paymentSvcAddr:        down,
+		traceDials:            true,
+		metrics:               m,
+		tracer:                tr,
+	}
+	dialTestService(t, cs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := cs.clients().cart().GetCart(ctx, &pb.GetCartRequest{UserId: "user-1"}); err == nil {
+		t.Fatal("GetCart() succeeded with nothing listening")
+	}
+	if n := m.count(dialErrorsMetric, map[string]string{"service": "cartservice"}); n == 0 {
+		t.Errorf("%s{service=cartservice} was not incremented", dialErrorsMetric)
+	}
+	spans := tr.finished("checkout.dial")
+	if len(spans) == 0 || spans[0].err == nil || spans[0].tags["target"] != down {
+		t.Errorf("dial spans = %v, want a failure to connect to %s", spans, down)
+	}
+}
+
+func TestDialOptionsApplyConnectParams(t *testing.T) {
+	var got []grpc.ConnectParams
+	orig := withConnectParams
+	withConnectParams = func(p grpc.ConnectParams) grpc.DialOption {
+		got = append(got, p)
+		return orig(p)
+	}
+	defer func() { withConnectParams = orig }()
+
+	want := grpc.ConnectParams{
+		Backoff: backoff.Config{
+			BaseDelay:  250 * time.Millisecond,
+			Multiplier: 2,
+			Jitter:     0.2,
+			MaxDelay:   30 * time.Second,
+		},
+		MinConnectTimeout: 5 * time.Second,
+	}
+	addr := startFakeServer(t, func(*grpc.Server) {})
+	cs := &checkoutService{
+		productCatalogSvcAddr: addr,
+		cartSvcAddr:           addr,
+		currencySvcAddr:       addr,
+		shippingSvcAddr:       addr,
+		emailSvcAddr:          addr,
+		paymentSvcAddr:        addr,
+		connectParams:         want,
+	}
+	dialTestService(t, cs)
+
+	if len(got) != len(cs.downstreams()) {
+		t.Fatalf("connect params applied to %d dials, want %d", len(got), len(cs.downstreams()))
+	}
+	for _, p := range got {
+		if p != want {
+			t.Errorf("dialed with connect params %+v, want %+v", p, want)
+		}
+	}
+}
+
+func TestDialOptionsDefaultConnectParams(t *testing.T) {
+	var got grpc.ConnectParams
+	orig := withConnectParams
+	withConnectParams = func(p grpc.ConnectParams) grpc.DialOption {
+		got = p
+		return orig(p)
+	}
+	defer func() { withConnectParams = orig }()
+
+	(&checkoutService{}).dialOptions("cartservice")
+	if got != defaultConnectParams() {
+		t.Errorf("unconfigured service dials with %+v, want the defaults", got)
+	}
+}
+
+func TestDialOptionsTLSServerNameOverride(t *testing.T) {
+	var got []*tls.Config
+	orig := newTLSCredentials
+	newTLSCredentials = func(c *tls.Config) credentials.TransportCredentials {
+		got = append(got, c)
+		return orig(c)
+	}
+	defer func() { newTLSCredentials = orig }()
+
+	cs := &checkoutService{
+		downstreamTLS:  true,
+		tlsServerNames: map[string]string{"currencyservice": "currency.mesh.internal"},
+	}
+	cs.dialOptions("currencyservice")
+	cs.dialOptions("cartservice")
+
+	if len(got) != 2 {
+		t.Fatalf("built %d TLS credentials, want 2", len(got))
+	}
+	if got[0].ServerName != "currency.mesh.internal" {
+		t.Errorf("currencyservice server name = %q, want currency.mesh.internal", got[0].ServerName)
+	}
+	if got[1].ServerName != "" {
+		t.Errorf("cartservice server name = %q, want none", got[1].ServerName)
+	}
+
+	got = nil
+	(&checkoutService{}).dialOptions("currencyservice")
+	if len(got) != 0 {
+		t.Error("TLS credentials should not be used when TLS is disabled")
+	}
+}
+
+type fakeConn struct {
+	state      connectivity.State
+	reconnects int
+}
+
+func (c *fakeConn) GetState() connectivity.State { return c.state }
+func (c *fakeConn) ResetConnectBackoff()         { c.reconnects++ }
+
+func TestCheckConnReconnectsAfterMaxFailure(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	metrics := &recordingMetrics{}
+	cs := &checkoutService{clock: clock.Now, metrics: metrics}
+	conn := &fakeConn{state: connectivity.TransientFailure}
+	failingSince := make(map[monitoredConn]time.Time)
+
+	cs.checkConn("cartservice", conn, failingSince, 30*time.Second)
+	clock.now = clock.now.Add(10 * time.Second)
+	cs.checkConn("cartservice", conn, failingSince, 30*time.Second)
+	if conn.reconnects != 0 {
+		t.Fatalf("reconnects after 10s = %d, want 0", conn.reconnects)
+	}
+
+	clock.now = clock.now.Add(20 * time.Second)
+	cs.checkConn("cartservice", conn, failingSince, 30*time.Second)
+	if conn.reconnects != 1 {
+		t.Fatalf("reconnects after 30s = %d, want 1", conn.reconnects)
+	}
+	if n := metrics.count(reconnectMetric, map[string]string{"service": "cartservice"}); n != 1 {
+		t.Errorf("%s = %d, want 1", reconnectMetric, n)
+	}
+
+	conn.state = connectivity.Ready
+	cs.checkConn("cartservice", conn, failingSince, 30*time.Second)
+	if _, ok := failingSince[conn]; ok {
+		t.Error("ready connection still tracked as failing")
+	}
+}
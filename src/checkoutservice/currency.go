@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+// newConversionRecord describes a conversion from one amount to another for
+// the order audit trail.
+func newConversionRecord(description string, from, to *pb.Money) *pb.ConversionRecord {
+	r := &pb.ConversionRecord{Description: description, From: from, To: to}
+	if f := moneyToFloat(from); f != 0 {
+		r.Rate = moneyToFloat(to) / f
+	}
+	return r
+}
+
+func moneyToFloat(m *pb.Money) float64 {
+	return float64(m.GetUnits()) + float64(m.GetNanos())/1e9
+}
+
+// displayAmount renders m for logs and error messages, e.g. "$19.99".
+func displayAmount(m *pb.Money) string {
+	return money.Format(*m, displayLocale)
+}
+
+// convertCurrencyBatch converts amounts to toCurrency with a single call to
+// the currency service, keeping the order of amounts. If the currency
+// service does not implement batch conversion, every amount is converted
+// individually and the batch call is not attempted again.
+func (cs *checkoutService) convertCurrencyBatch(ctx context.Context, amounts []*pb.Money, toCurrency string) ([]*pb.Money, error) {
+	out := make([]*pb.Money, len(amounts))
+	var (
+		pending []*pb.Money
+		indexes []int
+	)
+	for i, m := range amounts {
+		if m.GetCurrencyCode() == toCurrency {
+			out[i] = m
+			continue
+		}
+		pending = append(pending, m)
+		indexes = append(indexes, i)
+	}
+	if len(pending) == 0 {
+		return out, nil
+	}
+
+	if atomic.LoadInt32(&cs.batchConversionUnsupported) == 0 {
+		resp, err := cs.currencyClient(ctx).ConvertBatch(ctx, &pb.CurrencyConversionBatchRequest{
+			From:   pending,
+			ToCode: toCurrency})
+		switch {
+		case err == nil:
+			if len(resp.GetTo()) != len(pending) {
+				return nil, status.Errorf(codes.Internal, "currency service converted %d amounts, want %d", len(resp.GetTo()), len(pending))
+			}
+			for j, m := range resp.GetTo() {
+				out[indexes[j]] = m
+			}
+			return out, nil
+		case status.Code(err) == codes.Unimplemented:
+			log.Warn("currency service does not support batch conversion, converting amounts individually")
+			atomic.StoreInt32(&cs.batchConversionUnsupported, 1)
+		case status.Code(err) == codes.Unavailable && cs.fallbackRates != nil:
+			for j, m := range pending {
+				converted, err := cs.fallbackConvert(ctx, m, toCurrency)
+				if err != nil {
+					return nil, err
+				}
+				out[indexes[j]] = converted
+			}
+			return out, nil
+		default:
+			return nil, downstreamError(err, "failed to convert currency")
+		}
+	}
+
+	for j, m := range pending {
+		converted, err := cs.convertCurrency(ctx, m, toCurrency)
+		if err != nil {
+			return nil, err
+		}
+		out[indexes[j]] = converted
+	}
+	return out, nil
+}
+
+const currencyFallbackMetric = "checkout_currency_fallback_total"
+
+// fallbackRates are static conversion rates of each currency relative to a
+// common base currency.
+type fallbackRates map[string]float64
+
+// loadFallbackRates reads a rate table in the format of the currency service
+// data file, e.g. {"EUR": "1.0", "USD": "1.1305"}.
+func loadFallbackRates(path string) (fallbackRates, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("invalid fallback rates in %s: %v", path, err)
+	}
+	rates := make(fallbackRates, len(raw))
+	for code, v := range raw {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid fallback rate %q for %s in %s", v, code, path)
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
+
+// convert converts from to toCurrency with the static rates.
+func (r fallbackRates) convert(from *pb.Money, toCurrency string) (*pb.Money, error) {
+	fromRate, ok := r[from.GetCurrencyCode()]
+	if !ok {
+		return nil, fmt.Errorf("no fallback rate for %s", from.GetCurrencyCode())
+	}
+	toRate, ok := r[toCurrency]
+	if !ok {
+		return nil, fmt.Errorf("no fallback rate for %s", toCurrency)
+	}
+	units, frac := math.Modf(moneyToFloat(from) / fromRate * toRate)
+	nanos := math.Round(frac * 1e9)
+	if nanos >= 1e9 {
+		units, nanos = units+1, 0
+	}
+	return &pb.Money{
+		CurrencyCode: toCurrency,
+		Units:        int64(units),
+		Nanos:        int32(nanos)}, nil
+}
+
+// fallbackConvert converts from to toCurrency with the fallback rate table,
+// for when the currency service is unavailable.
+func (cs *checkoutService) fallbackConvert(ctx context.Context, from *pb.Money, toCurrency string) (*pb.Money, error) {
+	span, _ := cs.trace().StartSpan(ctx, "checkout.currency_fallback")
+	span.SetTag("from_currency", from.GetCurrencyCode())
+	span.SetTag("to_currency", toCurrency)
+	result, err := cs.fallbackRates.convert(from, toCurrency)
+	span.Finish(err)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "currency service is unavailable and %v", err)
+	}
+	cs.stats().IncCounter(currencyFallbackMetric, map[string]string{"to_currency": toCurrency})
+	log.Warnf("currency service unavailable, converted %s to %s with fallback rates", from.GetCurrencyCode(), toCurrency)
+	return result, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderRecordsConversions(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.UserCurrency = "EUR"
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+
+	records := resp.GetOrder().GetConversions()
+	want := []struct {
+		description string
+		fromUnits   int64
+	}{
+		{"product:OLJCESPC7Z", 19},
+		{"product:66VCHSJNUP", 349},
+		{"shipping", 8},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d conversion records, want %d: %v", len(records), len(want), records)
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.GetDescription() != w.description {
+			t.Errorf("record %d description = %q, want %q", i, r.GetDescription(), w.description)
+		}
+		if r.GetFrom().GetCurrencyCode() != "USD" || r.GetFrom().GetUnits() != w.fromUnits {
+			t.Errorf("record %d source = %v, want %d USD", i, r.GetFrom(), w.fromUnits)
+		}
+		if r.GetTo().GetCurrencyCode() != "EUR" {
+			t.Errorf("record %d target currency = %q, want EUR", i, r.GetTo().GetCurrencyCode())
+		}
+		if math.Abs(r.GetRate()-0.5) > 1e-6 {
+			t.Errorf("record %d rate = %v, want 0.5", i, r.GetRate())
+		}
+	}
+}
+
+func TestPlaceOrderRecordsSameCurrencyConversions(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	for _, r := range resp.GetOrder().GetConversions() {
+		if r.GetRate() != 1 {
+			t.Errorf("same-currency conversion %q has rate %v, want 1", r.GetDescription(), r.GetRate())
+		}
+	}
+}
+
+func TestPrepOrderItemsBatchConversion(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.batchCurrencyConversion = true
+
+	items, _, _, err := cs.prepOrderItems(context.Background(), f.cart.carts["user-1"], "EUR")
+	if err != nil {
+		t.Fatalf("prepOrderItems() failed: %v", err)
+	}
+	if f.currency.batchCallCount() != 1 || f.currency.callCount() != 0 {
+		t.Errorf("got %d batch and %d single conversions, want a single batch", f.currency.batchCallCount(), f.currency.callCount())
+	}
+	// Results keep the order of the cart.
+	if id := items[0].GetItem().GetProductId(); id != "OLJCESPC7Z" {
+		t.Fatalf("first item = %q, want OLJCESPC7Z", id)
+	}
+	if c := items[0].GetCost(); c.GetCurrencyCode() != "EUR" || c.GetUnits() != 9 {
+		t.Errorf("first item cost = %v, want 9.995 EUR", c)
+	}
+	if c := items[1].GetCost(); c.GetCurrencyCode() != "EUR" || c.GetUnits() != 174 || c.GetNanos() != 500000000 {
+		t.Errorf("second item cost = %v, want 174.50 EUR", c)
+	}
+}
+
+func TestPrepOrderItemsBatchConversionFallback(t *testing.T) {
+	f := newFakeDownstreams()
+	f.currency.batchUnsupported = true
+	cs := newTestCheckoutService(t, f)
+	cs.batchCurrencyConversion = true
+
+	for i := 0; i < 2; i++ {
+		items, _, _, err := cs.prepOrderItems(context.Background(), f.cart.carts["user-1"], "EUR")
+		if err != nil {
+			t.Fatalf("prepOrderItems() failed: %v", err)
+		}
+		if c := items[1].GetCost(); c.GetCurrencyCode() != "EUR" || c.GetUnits() != 174 {
+			t.Errorf("second item cost = %v, want 174.50 EUR", c)
+		}
+	}
+	if f.currency.batchCallCount() != 1 {
+		t.Errorf("batch conversion attempted %d times, want 1", f.currency.batchCallCount())
+	}
+	if f.currency.callCount() != 4 {
+		t.Errorf("got %d single conversions, want 4", f.currency.callCount())
+	}
+}
+
+func TestLoadFallbackRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := ioutil.WriteFile(path, []byte(`{"EUR": "1.0", "USD": "1.25"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rates, err := loadFallbackRates(path)
+	if err != nil {
+		t.Fatalf("loadFallbackRates() failed: %v", err)
+	}
+	got, err := rates.convert(&pb.Money{CurrencyCode: "USD", Units: 10}, "EUR")
+	if err != nil {
+		t.Fatalf("convert() failed: %v", err)
+	}
+	if got.GetCurrencyCode() != "EUR" || got.GetUnits() != 8 || got.GetNanos() != 0 {
+		t.Errorf("convert() = %v, want 8 EUR", got)
+	}
+	if _, err := rates.convert(&pb.Money{CurrencyCode: "USD", Units: 10}, "JPY"); err == nil {
+		t.Error("convert() to a currency without rate should fail")
+	}
+}
+
+func TestConvertCurrencyFallbackRates(t *testing.T) {
+	rates := fallbackRates{"USD": 1, "EUR": 0.8}
+	in := &pb.Money{CurrencyCode: "USD", Units: 10}
+
+	t.Run("service available", func(t *testing.T) {
+		f := newFakeDownstreams()
+		cs := newTestCheckoutService(t, f)
+		cs.fallbackRates = rates
+		m := &recordingMetrics{}
+		cs.metrics = m
+
+		got, err := cs.convertCurrency(context.Background(), in, "EUR")
+		if err != nil {
+			t.Fatalf("convertCurrency() failed: %v", err)
+		}
+		// The fake currency service converts at 0.5.
+		if got.GetUnits() != 5 {
+			t.Errorf("convertCurrency() = %v, want the currency service rate", got)
+		}
+		if n := m.count(currencyFallbackMetric, nil); n != 0 {
+			t.Errorf("fallback used %d times, want none", n)
+		}
+	})
+
+	t.Run("service unavailable", func(t *testing.T) {
+		f := newFakeDownstreams()
+		f.currency.err = status.Error(codes.Unavailable, "connection refused")
+		cs := newTestCheckoutService(t, f)
+		cs.fallbackRates = rates
+		m := &recordingMetrics{}
+		cs.metrics = m
+		tr := &recordingTracer{}
+		cs.tracer = tr
+
+		got, err := cs.convertCurrency(context.Background(), in, "EUR")
+		if err != nil {
+			t.Fatalf("convertCurrency() failed: %v", err)
+		}
+		if got.GetCurrencyCode() != "EUR" || got.GetUnits() != 8 {
+			t.Errorf("convertCurrency() = %v, want 8 EUR from the fallback rates", got)
+		}
+		if n := m.count(currencyFallbackMetric, map[string]string{"to_currency": "EUR"}); n != 1 {
+			t.Errorf("fallback counted %d times, want 1", n)
+		}
+		if spans := tr.finished("checkout.currency_fallback"); len(spans) != 1 {
+			t.Errorf("got %d fallback spans, want 1", len(spans))
+		}
+	})
+
+	t.Run("fallback disabled", func(t *testing.T) {
+		f := newFakeDownstreams()
+		f.currency.err = status.Error(codes.Unavailable, "connection refused")
+		cs := newTestCheckoutService(t, f)
+
+		if _, err := cs.convertCurrency(context.Background(), in, "EUR"); status.Code(err) != codes.Unavailable {
+			t.Errorf("convertCurrency() = %v, want Unavailable", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const defaultDeadlineFloor = 10 * time.Millisecond
+
+// remainingBudgetMetadataKey is the metadata key carrying, in milliseconds,
+// what is left of the deadline of a call, so that downstream services can
+// limit the work they take on.
+const remainingBudgetMetadataKey = "x-remaining-budget-ms"
+
+// parseServiceTimeouts parses a comma-separated list of SERVICE=DURATION
+// pairs, e.g. "cartservice=1s,paymentservice=3s".
+func parseServiceTimeouts(v string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid service timeout %q, expected SERVICE=DURATION", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(kv[1]))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q for %s", kv[1], kv[0])
+		}
+		out[strings.TrimSpace(kv[0])] = d
+	}
+	return out, nil
+}
+
+// serviceContext derives the context of a call to service. The call gets the
+// timeout configured for the service, shortened to what is left of the
+// incoming deadline. If less than the deadline floor is left, the call fails
+// right away with DeadlineExceeded since it would be unlikely to complete.
+func (cs *checkoutService) serviceContext(ctx context.Context, service string) (context.Context, context.CancelFunc, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); left < cs.deadlineFloor || left <= 0 {
+			return nil, nil, status.Errorf(codes.DeadlineExceeded,
+				"only %v left before the deadline, not calling %s", left.Round(time.Millisecond), service)
+		}
+	}
+	if timeout, ok := cs.serviceTimeouts[service]; ok {
+		// The derived context keeps the earliest of both deadlines.
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, cancel, nil
+}
+
+// deadlineInterceptor applies serviceContext to every call made to service.
+func (cs *checkoutService) deadlineInterceptor(service string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel, err := cs.serviceContext(ctx, service)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// budgetInterceptor tells the called service its remaining budget when
+// propagation is enabled. It comes after the retries in the chain so that
+// every attempt is sent what is actually left of the deadline.
+func (cs *checkoutService) budgetInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if deadline, ok := ctx.Deadline(); ok && cs.propagateBudget {
+		budget := time.Until(deadline) / time.Millisecond
+		ctx = metadata.AppendToOutgoingContext(ctx, remainingBudgetMetadataKey, strconv.FormatInt(int64(budget), 10))
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseServiceTimeouts(t *testing.T) {
+	got, err := parseServiceTimeouts("cartservice=1s, paymentservice=250ms")
+	if err != nil {
+		t.Fatalf("parseServiceTimeouts() failed: %v", err)
+	}
+	if got["cartservice"] != time.Second || got["paymentservice"] != 250*time.Millisecond {
+		t.Errorf("parseServiceTimeouts() = %v", got)
+	}
+	for _, v := range []string{"cartservice", "cartservice=soon", "cartservice=-1s"} {
+		if _, err := parseServiceTimeouts(v); err == nil {
+			t.Errorf("parseServiceTimeouts(%q) should have failed", v)
+		}
+	}
+}
+
+func TestDeadlineFloorFailsFast(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.deadlineFloor = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := cs.PlaceOrder(ctx, testOrderRequest())
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("PlaceOrder() = %v, want DeadlineExceeded", err)
+	}
+	if f.cart.getCalls != 0 {
+		t.Errorf("cart service was called %d times, want none", f.cart.getCalls)
+	}
+}
+
+func TestServiceTimeout(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.delay = time.Second
+	cs := newTestCheckoutService(t, f)
+	cs.serviceTimeouts = map[string]time.Duration{"productcatalogservice": 50 * time.Millisecond}
+
+	start := time.Now()
+	_, _, _, err := cs.prepOrderItems(context.Background(), f.cart.carts["user-1"], "USD")
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("prepOrderItems() = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("prepOrderItems() took %v, want the 50ms catalog timeout to apply", elapsed)
+	}
+}
+
+func TestRemainingBudgetIsPropagated(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.propagateBudget = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if _, err := cs.getUserCart(ctx, "user-1"); err != nil {
+			t.Fatalf("getUserCart() failed: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(f.cart.budgets) != 3 {
+		t.Fatalf("cart service got budgets %v, want one per call", f.cart.budgets)
+	}
+	last := int64(5000)
+	for _, v := range f.cart.budgets {
+		budget, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid budget %q: %v", v, err)
+		}
+		if budget <= 0 || budget >= last {
+			t.Errorf("budgets %v are not decreasing within the 5s deadline", f.cart.budgets)
+			break
+		}
+		last = budget
+	}
+}
+
+func TestRemainingBudgetIsUpdatedOnRetries(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.unavailable = 1
+	cs := newTestCheckoutService(t, f)
+	cs.propagateBudget = true
+	cs.serviceRetries = map[string]int{"cartservice": 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := cs.getUserCart(ctx, "user-1"); err != nil {
+		t.Fatalf("getUserCart() failed: %v", err)
+	}
+	if len(f.cart.budgets) != 2 {
+		t.Fatalf("cart service got budgets %v, want one per attempt", f.cart.budgets)
+	}
+	first, _ := strconv.ParseInt(f.cart.budgets[0], 10, 64)
+	retry, _ := strconv.ParseInt(f.cart.budgets[1], 10, 64)
+	if retry >= first {
+		t.Errorf("retry was sent a budget of %dms after %dms, want what is left after the first attempt", retry, first)
+	}
+}
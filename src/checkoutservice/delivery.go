@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	// deliveryDateLayout is the format of requested delivery dates.
+	deliveryDateLayout = "2006-01-02"
+
+	// defaultMaxDeliveryDays is how many days ahead a delivery can be
+	// scheduled by default.
+	defaultMaxDeliveryDays = 30
+)
+
+// validateDeliveryDate checks that the requested delivery date, if any, is
+// a future day at most maxDeliveryDays ahead, and normalizes it. Days are
+// in UTC.
+func (cs *checkoutService) validateDeliveryDate(v *violations, date string) string {
+	if date == "" {
+		return ""
+	}
+	if cs.maxDeliveryDays <= 0 {
+		v.add("delivery_date", "scheduled delivery is not available")
+		return date
+	}
+	d, err := time.Parse(deliveryDateLayout, date)
+	if err != nil {
+		v.add("delivery_date", "%q is not a YYYY-MM-DD date", date)
+		return date
+	}
+	now := cs.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case !d.After(today):
+		v.add("delivery_date", "delivery date %s is not in the future", date)
+	case d.After(today.AddDate(0, 0, cs.maxDeliveryDays)):
+		v.add("delivery_date", "delivery date %s is more than %d days ahead", date, cs.maxDeliveryDays)
+	}
+	return d.Format(deliveryDateLayout)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlaceOrderScheduledDelivery(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.maxDeliveryDays = 30
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 23, 0, 0, 0, time.UTC)}
+	cs.clock = clock.Now
+
+	req := testOrderRequest()
+	req.DeliveryDate = "2020-06-15"
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if got := resp.GetOrder().GetDeliveryDate(); got != "2020-06-15" {
+		t.Errorf("order delivery date = %q, want 2020-06-15", got)
+	}
+	if len(f.shipping.shipped) != 1 || f.shipping.shipped[0].GetDeliveryDate() != "2020-06-15" {
+		t.Errorf("shipping requests %v, want one for 2020-06-15", f.shipping.shipped)
+	}
+}
+
+func TestPlaceOrderRejectsOutOfWindowDeliveryDates(t *testing.T) {
+	for _, date := range []string{"2020-05-31", "2020-06-01", "2020-07-02", "next week"} {
+		f := newFakeDownstreams()
+		cs := newTestCheckoutService(t, f)
+		cs.maxDeliveryDays = 30
+		clock := &fakeClock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+		cs.clock = clock.Now
+
+		req := testOrderRequest()
+		req.DeliveryDate = date
+		if _, err := cs.PlaceOrder(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("delivery on %q: PlaceOrder() error = %v, want InvalidArgument", date, err)
+		}
+		if n := f.payment.chargeCount(); n != 0 {
+			t.Errorf("delivery on %q: card was charged %d times", date, n)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	attachmentFormatCSV = "csv"
+
+	// giftMessageKey is the order metadata entry forwarded to the email
+	// service as the gift message.
+	giftMessageKey = "gift_message"
+
+	defaultMaxConfirmationBytes = 1 << 20
+
+	defaultEmailRateWindow      = time.Minute
+	maxDeferredConfirmations    = 1000
+	deferredConfirmationTimeout = 10 * time.Second
+	deferredConfirmationsMetric = "checkout_confirmations_deferred_total"
+)
+
+// confirmationDebouncer remembers the orders confirmed recently, by
+// confirmationKey, so that a client retrying the checkout of the same cart
+// gets a single email.
+type confirmationDebouncer struct {
+	mu     sync.Mutex
+	window time.Duration
+	sent   map[string]time.Time
+	done   chan struct{}
+}
+
+func newConfirmationDebouncer(window time.Duration) *confirmationDebouncer {
+	return &confirmationDebouncer{window: window, sent: make(map[string]time.Time)}
+}
+
+// confirmationKey identifies the checkouts of the same items by the same
+// user, whatever the order id they were given.
+func confirmationKey(userID string, items []*pb.OrderItem) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q", userID)
+	for _, it := range items {
+		fmt.Fprintf(h, ";%q:%d", it.GetItem().GetProductId(), it.GetItem().GetQuantity())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claim reports whether the confirmation of key should be sent, that is if
+// none was sent within the window preceding now.
+func (d *confirmationDebouncer) claim(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if at, ok := d.sent[key]; ok && now.Sub(at) < d.window {
+		return false
+	}
+	d.sent[key] = now
+	return true
+}
+
+// release forgets key so that a failed confirmation can be retried.
+func (d *confirmationDebouncer) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sent, key)
+}
+
+// prune forgets the confirmations sent more than the window before now.
+func (d *confirmationDebouncer) prune(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, at := range d.sent {
+		if now.Sub(at) >= d.window {
+			delete(d.sent, key)
+		}
+	}
+}
+
+// startPruning prunes the debouncer every window until stopPruning is
+// called.
+func (d *confirmationDebouncer) startPruning(now func() time.Time) {
+	d.done = make(chan struct{})
+	go func() {
+		t := time.NewTicker(d.window)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				d.prune(now())
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+func (d *confirmationDebouncer) stopPruning() {
+	if d.done != nil {
+		close(d.done)
+	}
+}
+
+// emailRateLimiter spaces out the confirmations sent to a same address, at
+// most limit per window, so that bursts of orders from one customer do not
+// get the service throttled by the email provider.
+type emailRateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	slots  map[string][]time.Time
+
+	// deferred holds the confirmations waiting for their slot.
+	deferred     map[int64]*deferredConfirmation
+	nextDeferred int64
+}
+
+// deferredConfirmation is a confirmation scheduled to be sent by timer.
+type deferredConfirmation struct {
+	timer *time.Timer
+	req   *pb.SendOrderConfirmationRequest
+}
+
+func newEmailRateLimiter(limit int, window time.Duration) *emailRateLimiter {
+	return &emailRateLimiter{
+		limit:    limit,
+		window:   window,
+		slots:    make(map[string][]time.Time),
+		deferred: make(map[int64]*deferredConfirmation)}
+}
+
+// reserve books the earliest slot to send a confirmation to email and
+// returns how long to wait for it, zero if it can be sent right away.
+func (l *emailRateLimiter) reserve(email string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for addr, slots := range l.slots {
+		for len(slots) > 0 && now.Sub(slots[0]) >= l.window {
+			slots = slots[1:]
+		}
+		if len(slots) == 0 {
+			delete(l.slots, addr)
+		} else {
+			l.slots[addr] = slots
+		}
+	}
+	slots := l.slots[email]
+	slot := now
+	if len(slots) >= l.limit {
+		if next := slots[len(slots)-l.limit].Add(l.window); next.After(now) {
+			slot = next
+		}
+	}
+	l.slots[email] = append(slots, slot)
+	return slot.Sub(now)
+}
+
+// deferConfirmation sends the confirmation req after delay, in the
+// background. Confirmations are dropped once too many are pending. The span
+// of the deferred confirmation is linked to the origin span of ctx.
+func (cs *checkoutService) deferConfirmation(ctx context.Context, req *pb.SendOrderConfirmationRequest, delay time.Duration) bool {
+	l := cs.emailLimiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	orderID := req.GetOrder().GetOrderId()
+	if len(l.deferred) >= maxDeferredConfirmations {
+		log.Warnf("too many deferred confirmations, dropping the one of order %s", orderID)
+		return false
+	}
+
+	log.Infof("confirmations to %q are rate limited, deferring the one of order %s by %v", req.GetEmail(), orderID, delay)
+	cs.stats().IncCounter(deferredConfirmationsMetric, nil)
+	origin := originSpan(ctx)
+	id := l.nextDeferred
+	l.nextDeferred++
+	// The timer cannot remove the confirmation before it is stored, the
+	// lock is held until then.
+	timer := time.AfterFunc(delay, func() {
+		l.mu.Lock()
+		_, ok := l.deferred[id]
+		delete(l.deferred, id)
+		l.mu.Unlock()
+		if !ok {
+			return
+		}
+		span, ctx := cs.startLinkedSpan(origin, "checkout.deferred_confirmation")
+		span.SetTag("order_id", orderID)
+		ctx, cancel := context.WithTimeout(ctx, deferredConfirmationTimeout)
+		defer cancel()
+		err := cs.sendOrderConfirmation(ctx, req)
+		span.Finish(err)
+		if err != nil {
+			log.Warnf("failed to send deferred order confirmation to %q: %+v", req.GetEmail(), err)
+			return
+		}
+		log.Infof("deferred order confirmation email sent to %q", req.GetEmail())
+	})
+	l.deferred[id] = &deferredConfirmation{timer: timer, req: req}
+	return true
+}
+
+// takeDeferred cancels the deferred confirmations not sent yet and returns
+// them, e.g. to persist them on shutdown.
+func (l *emailRateLimiter) takeDeferred() []*pb.SendOrderConfirmationRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []*pb.SendOrderConfirmationRequest
+	for id, d := range l.deferred {
+		// A timer which already fired is sending its confirmation.
+		if d.timer.Stop() {
+			out = append(out, d.req)
+			delete(l.deferred, id)
+		}
+	}
+	return out
+}
+
+// confirmOrder sends the order confirmation through the channels requested
+// by the client, unless it asked to skip it. Failures are logged but do not
+// fail the order.
+func (cs *checkoutService) confirmOrder(ctx context.Context, req *pb.PlaceOrderRequest, order *pb.OrderResult) {
+	if req.GetSkipConfirmation() {
+		log.Infof("skipping order confirmation for order %s as requested", order.GetOrderId())
+		return
+	}
+	key := confirmationKey(req.GetUserId(), order.GetItems())
+	if cs.confirmations != nil && !cs.confirmations.claim(key, cs.now()) {
+		log.Infof("order confirmation for order %s was already sent for the same cart", order.GetOrderId())
+		return
+	}
+
+	var sent bool
+	channel := req.GetConfirmationChannel()
+	if channel != pb.ConfirmationChannel_CONFIRMATION_CHANNEL_SMS {
+		sent = cs.confirmByEmail(ctx, req, order)
+	}
+	if channel != pb.ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL {
+		sent = cs.confirmBySMS(ctx, req.GetPhoneNumber(), order) || sent
+	}
+	if !sent && cs.confirmations != nil {
+		cs.confirmations.release(key)
+	}
+}
+
+// confirmByEmail sends the order confirmation email, or defers it when the
+// address is rate limited, and reports whether it was sent or deferred.
+func (cs *checkoutService) confirmByEmail(ctx context.Context, req *pb.PlaceOrderRequest, order *pb.OrderResult) bool {
+	attachment, err := newOrderAttachment(req.GetConfirmationAttachmentFormat(), order)
+	if err != nil {
+		log.Warnf("failed to render order confirmation attachment: %+v", err)
+	}
+	if cs.emailLimiter != nil {
+		if delay := cs.emailLimiter.reserve(req.Email, cs.now()); delay > 0 {
+			return cs.deferConfirmation(ctx, newConfirmationRequest(req.Email, order, attachment), delay)
+		}
+	}
+	emailStart := time.Now()
+	err = cs.sendOrderConfirmation(ctx, newConfirmationRequest(req.Email, order, attachment))
+	cs.observeStage(ctx, "email", emailStart)
+	if err != nil {
+		log.Warnf("failed to send order confirmation to %q: %+v", req.Email, err)
+		return false
+	}
+	orderLog.Infof("order confirmation email sent to %q", req.Email)
+	return true
+}
+
+// fitConfirmation shrinks req to at most max bytes. The gift message is
+// truncated first, then rows are dropped from the end of the attachment so
+// that it remains a valid CSV document. It reports whether req fits.
+func fitConfirmation(req *pb.SendOrderConfirmationRequest, max int) bool {
+	over := proto.Size(req) - max
+	if over <= 0 {
+		return true
+	}
+
+	if msg := req.GetGiftMessage(); msg != "" {
+		_, inOrder := req.GetOrder().GetMetadata()[giftMessageKey]
+		cut := over
+		if inOrder {
+			// The message is sent twice, once in the order metadata.
+			cut = (over + 1) / 2
+		}
+		keep := len(msg) - cut
+		if keep < 0 {
+			keep = 0
+		}
+		msg = truncateUTF8(msg, keep)
+		req.GiftMessage = msg
+		if inOrder {
+			// The order is shared with the caller, only the copy sent in
+			// the confirmation is truncated.
+			order := proto.Clone(req.Order).(*pb.OrderResult)
+			order.Metadata[giftMessageKey] = msg
+			req.Order = order
+		}
+		if over = proto.Size(req) - max; over <= 0 {
+			return true
+		}
+	}
+
+	if a := req.GetAttachment(); a != nil && a.GetFormat() == attachmentFormatCSV {
+		a = proto.Clone(a).(*pb.EmailAttachment)
+		req.Attachment = a
+		lines := bytes.SplitAfter(a.Content, []byte("\n"))
+		// Keep the header line, drop item rows until the request fits.
+		n := len(lines)
+		for n > 1 && over > 0 {
+			n--
+			over -= len(lines[n])
+		}
+		a.Content = bytes.Join(lines[:n], nil)
+		if over = proto.Size(req) - max; over <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// newOrderAttachment renders the line items of the order in the requested
+// format so the email service can attach it to the confirmation as-is. An
+// empty format means no attachment is requested.
+func newOrderAttachment(format string, order *pb.OrderResult) (*pb.EmailAttachment, error) {
+	switch format {
+	case "":
+		return nil, nil
+	case attachmentFormatCSV:
+		content, err := orderItemsCSV(order)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.EmailAttachment{
+			Format:      attachmentFormatCSV,
+			Filename:    fmt.Sprintf("order-%s.csv", order.GetOrderId()),
+			ContentType: "text/csv",
+			Content:     content}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attachment format %q", format)
+	}
+}
+
+// orderItemsCSV returns one CSV row per order item, preceded by a header.
+func orderItemsCSV(order *pb.OrderResult) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"product_id", "quantity", "price", "currency"}); err != nil {
+		return nil, err
+	}
+	for _, it := range order.GetItems() {
+		if err := w.Write([]string{
+			it.GetItem().GetProductId(),
+			strconv.Itoa(int(it.GetItem().GetQuantity())),
+			csvAmount(it.GetCost()),
+			it.GetCost().GetCurrencyCode(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvAmount renders a money value as a plain decimal number with two
+// fractional digits, e.g. "19.99", for the CSV attachment which has the
+// currency in its own column. Amounts shown to people use money.Format.
+func csvAmount(m *pb.Money) string {
+	units, nanos := m.GetUnits(), m.GetNanos()
+	sign := ""
+	if units < 0 || nanos < 0 {
+		sign, units, nanos = "-", -units, -nanos
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units, nanos/10000000)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestNewOrderAttachmentCSV(t *testing.T) {
+	order := &pb.OrderResult{
+		OrderId: "1234",
+		Items: []*pb.OrderItem{
+			{
+				Item: &pb.CartItem{ProductId: "OLJCESPC7Z", Quantity: 2},
+				Cost: &pb.Money{CurrencyCode: "USD", Units: 19, Nanos: 990000000},
+			},
+			{
+				Item: &pb.CartItem{ProductId: "66VCHSJNUP", Quantity: 1},
+				Cost: &pb.Money{CurrencyCode: "USD", Units: 349, Nanos: 0},
+			},
+		},
+	}
+
+	a, err := newOrderAttachment(attachmentFormatCSV, order)
+	if err != nil {
+		t.Fatalf("newOrderAttachment() failed: %v", err)
+	}
+	want := "product_id,quantity,price,currency\n" +
+		"OLJCESPC7Z,2,19.99,USD\n" +
+		"66VCHSJNUP,1,349.00,USD\n"
+	if got := string(a.GetContent()); got != want {
+		t.Errorf("attachment content = %q, want %q", got, want)
+	}
+	if a.GetFilename() != "order-1234.csv" || a.GetContentType() != "text/csv" {
+		t.Errorf("unexpected attachment metadata: %v", a)
+	}
+}
+
+func TestNewOrderAttachmentNone(t *testing.T) {
+	a, err := newOrderAttachment("", &pb.OrderResult{})
+	if err != nil || a != nil {
+		t.Errorf("newOrderAttachment(\"\") = %v, %v; want nil, nil", a, err)
+	}
+	if _, err := newOrderAttachment("pdf", &pb.OrderResult{}); err == nil {
+		t.Error("newOrderAttachment(\"pdf\") should fail")
+	}
+}
+
+func TestPlaceOrderSkipConfirmation(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		f := newFakeDownstreams()
+		cs := newTestCheckoutService(t, f)
+
+		req := testOrderRequest()
+		req.SkipConfirmation = skip
+		resp, err := cs.PlaceOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("PlaceOrder(skip_confirmation=%v) failed: %v", skip, err)
+		}
+		if resp.GetOrder().GetShippingTrackingId() == "" {
+			t.Errorf("PlaceOrder(skip_confirmation=%v) did not complete the order", skip)
+		}
+		want := 1
+		if skip {
+			want = 0
+		}
+		if n := f.email.sentCount(); n != want {
+			t.Errorf("PlaceOrder(skip_confirmation=%v) sent %d emails, want %d", skip, n, want)
+		}
+	}
+}
+
+func TestConfirmationDebounce(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cs.clock = clock.Now
+	cs.confirmations = newConfirmationDebouncer(time.Minute)
+
+	// The client retries the checkout of the same cart, each attempt gets
+	// a new order id.
+	for i := 0; i < 2; i++ {
+		if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+	}
+	if n := f.email.sentCount(); n != 1 {
+		t.Errorf("sent %d confirmations, want 1", n)
+	}
+
+	f.cart.carts["user-1"] = f.cart.carts["user-1"][:1]
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if n := f.email.sentCount(); n != 2 {
+		t.Errorf("sent %d confirmations after the cart changed, want 2", n)
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if n := f.email.sentCount(); n != 3 {
+		t.Errorf("sent %d confirmations after the window, want 3", n)
+	}
+}
+
+func TestConfirmationDebouncerPrune(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := newConfirmationDebouncer(time.Minute)
+	d.claim("a", start)
+	d.claim("b", start.Add(30*time.Second))
+
+	d.prune(start.Add(time.Minute))
+	if _, ok := d.sent["a"]; ok {
+		t.Error("expired confirmation kept after pruning")
+	}
+	if _, ok := d.sent["b"]; !ok {
+		t.Error("confirmation within the window pruned")
+	}
+}
+
+func TestEmailRateLimiterReserve(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := newEmailRateLimiter(2, time.Minute)
+
+	steps := []struct {
+		email string
+		at    time.Duration
+		want  time.Duration
+	}{
+		{"a@example.com", 0, 0},
+		{"a@example.com", time.Second, 0},
+		{"b@example.com", 2 * time.Second, 0},
+		{"a@example.com", 2 * time.Second, 58 * time.Second},
+		{"a@example.com", 3 * time.Second, 58 * time.Second},
+		{"a@example.com", 2 * time.Minute, 0},
+	}
+	for i, s := range steps {
+		if got := l.reserve(s.email, start.Add(s.at)); got != s.want {
+			t.Errorf("step %d: reserve(%q) = %v, want %v", i, s.email, got, s.want)
+		}
+	}
+}
+
+func TestPlaceOrderDefersRateLimitedConfirmations(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	metrics := &recordingMetrics{}
+	cs.metrics = metrics
+	cs.emailLimiter = newEmailRateLimiter(2, 300*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+	}
+	if n := f.email.sentCount(); n != 2 {
+		t.Errorf("sent %d confirmations right away, want 2", n)
+	}
+	if n := metrics.count(deferredConfirmationsMetric, nil); n != 1 {
+		t.Errorf("%s = %d, want 1", deferredConfirmationsMetric, n)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for f.email.sentCount() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := f.email.sentCount(); n != 3 {
+		t.Errorf("sent %d confirmations after the window, want 3", n)
+	}
+}
+
+func TestDeferredConfirmationSpanLinksToOrder(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	tr := &recordingTracer{}
+	cs.tracer = tr
+	cs.emailLimiter = newEmailRateLimiter(1, 100*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(tr.finished("checkout.deferred_confirmation")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	spans := tr.finished("checkout.deferred_confirmation")
+	if len(spans) != 1 {
+		t.Fatalf("got %d deferred confirmation spans, want 1", len(spans))
+	}
+	s := spans[0]
+	if len(s.links) != 1 {
+		t.Fatalf("deferred confirmation span has %d links, want 1", len(s.links))
+	}
+	origin, ok := s.links[0].(*recordedSpan)
+	if !ok || origin.operation != "checkout.place_order" || origin.tags["order_id"] != s.tags["order_id"] {
+		t.Errorf("deferred confirmation of order %v linked to %+v, want the span placing it", s.tags["order_id"], s.links[0])
+	}
+}
+
+func TestSendOrderConfirmationTruncatesOversizedContent(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.maxConfirmationBytes = 1500
+
+	req := testOrderRequest()
+	req.ConfirmationAttachmentFormat = attachmentFormatCSV
+	gift := strings.Repeat("é", 500)
+	req.Metadata = map[string]string{giftMessageKey: gift}
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if resp.GetOrder().GetMetadata()[giftMessageKey] != gift {
+		t.Error("the order returned to the client should not be truncated")
+	}
+
+	if f.email.sentCount() != 1 {
+		t.Fatalf("sent %d confirmations, want 1", f.email.sentCount())
+	}
+	sent := f.email.sent[0]
+	if size := proto.Size(sent); size > cs.maxConfirmationBytes {
+		t.Errorf("confirmation is %d bytes, want at most %d", size, cs.maxConfirmationBytes)
+	}
+	msg := sent.GetGiftMessage()
+	if msg == "" || len(msg) >= len(gift) || !utf8.ValidString(msg) || !strings.HasPrefix(gift, msg) {
+		t.Errorf("gift message of %d bytes was not truncated cleanly to a prefix", len(msg))
+	}
+	if sent.GetOrder().GetMetadata()[giftMessageKey] != msg {
+		t.Error("order metadata sent should carry the truncated gift message")
+	}
+	rows, err := csv.NewReader(bytes.NewReader(sent.GetAttachment().GetContent())).ReadAll()
+	if err != nil || len(rows) == 0 {
+		t.Errorf("attachment is not valid CSV after truncation: %v", err)
+	}
+}
+
+func TestFitConfirmationDropsAttachmentRows(t *testing.T) {
+	content := "product_id,quantity,price,currency\nA,1,1.00,USD\nB,1,1.00,USD\n"
+	req := &pb.SendOrderConfirmationRequest{
+		Email:      "someone@example.com",
+		Attachment: &pb.EmailAttachment{Format: attachmentFormatCSV, Content: []byte(content)},
+	}
+	max := proto.Size(req) - 5
+	if !fitConfirmation(req, max) {
+		t.Fatal("fitConfirmation() = false, want the request to fit")
+	}
+	if got, want := string(req.GetAttachment().GetContent()), "product_id,quantity,price,currency\nA,1,1.00,USD\n"; got != want {
+		t.Errorf("attachment = %q, want %q", got, want)
+	}
+
+	if fitConfirmation(req, 10) {
+		t.Error("fitConfirmation() = true for a request that cannot fit")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// downstreamError annotates an error returned by a downstream call while
+// preserving its gRPC status code and details, so that e.g. a product which
+// is not found surfaces as NotFound rather than Internal. Errors without a
+// meaningful status are reported as Internal.
+func downstreamError(err error, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	code := codes.Internal
+	switch {
+	case err == context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	case err == context.Canceled:
+		code = codes.Canceled
+	default:
+		if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+			p := s.Proto()
+			p.Message = msg + ": " + p.Message
+			return status.ErrorProto(p)
+		}
+	}
+	return status.Errorf(code, "%s: %+v", msg, err)
+}
+
+// defaultUserErrorMessages replaces the messages of errors which only carry
+// internal details, such as the address of a failing service.
+var defaultUserErrorMessages = map[codes.Code]string{
+	codes.Unknown:  "an unexpected error occurred",
+	codes.Internal: "an unexpected error occurred",
+}
+
+// parseUserErrorMessages parses a comma-separated list of CODE=MESSAGE
+// pairs, e.g. "UNAVAILABLE=please retry later", on top of the defaults. An
+// empty message leaves the errors of that code untouched.
+func parseUserErrorMessages(v string) (map[codes.Code]string, error) {
+	out := make(map[codes.Code]string, len(defaultUserErrorMessages))
+	for c, msg := range defaultUserErrorMessages {
+		out[c] = msg
+	}
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid error message %q, expected CODE=MESSAGE", pair)
+		}
+		var c codes.Code
+		name := strings.ToUpper(strings.TrimSpace(kv[0]))
+		if err := c.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			return nil, fmt.Errorf("invalid status code %q", kv[0])
+		}
+		if c == codes.OK {
+			return nil, fmt.Errorf("cannot replace the message of status code %q", kv[0])
+		}
+		if msg := strings.TrimSpace(kv[1]); msg != "" {
+			out[c] = msg
+		} else {
+			delete(out, c)
+		}
+	}
+	return out, nil
+}
+
+// userErrorsInterceptor replaces the message of errors whose code is in
+// messages before they reach the client. The original error is logged.
+func userErrorsInterceptor(messages map[codes.Code]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		code := status.Code(err)
+		msg, ok := messages[code]
+		if !ok {
+			return resp, err
+		}
+		log.Warnf("%s failed: %v", info.FullMethod, err)
+		return resp, status.Error(code, msg)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestDownstreamError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"not found", status.Error(codes.NotFound, "no such product"), codes.NotFound},
+		{"unavailable", status.Error(codes.Unavailable, "connection refused"), codes.Unavailable},
+		{"wrapped twice", downstreamError(status.Error(codes.Unavailable, "down"), "inner"), codes.Unavailable},
+		{"unknown", status.Error(codes.Unknown, "boom"), codes.Internal},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"plain error", errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(downstreamError(tt.err, "outer")); got != tt.want {
+				t.Errorf("downstreamError(%v) code = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderSurfacesProductNotFound(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"] = []*pb.CartItem{{ProductId: "DISCONTINUED", Quantity: 1}}
+	cs := newTestCheckoutService(t, f)
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("PlaceOrder() code = %v, want NotFound (err: %v)", status.Code(err), err)
+	}
+}
+
+func TestPlaceOrderSurfacesShippingUnavailable(t *testing.T) {
+	f := newFakeDownstreams()
+	f.shipping.shipErr = status.Error(codes.Unavailable, "shipping is down")
+	cs := newTestCheckoutService(t, f)
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("PlaceOrder() code = %v, want Unavailable (err: %v)", status.Code(err), err)
+	}
+}
+
+func TestParseUserErrorMessages(t *testing.T) {
+	got, err := parseUserErrorMessages("unavailable=please retry later, INTERNAL=")
+	if err != nil {
+		t.Fatalf("parseUserErrorMessages() failed: %v", err)
+	}
+	if msg := got[codes.Unavailable]; msg != "please retry later" {
+		t.Errorf("UNAVAILABLE message = %q, want %q", msg, "please retry later")
+	}
+	if _, ok := got[codes.Internal]; ok {
+		t.Error("INTERNAL errors should be left untouched")
+	}
+	if msg := got[codes.Unknown]; msg != defaultUserErrorMessages[codes.Unknown] {
+		t.Errorf("UNKNOWN message = %q, want the default", msg)
+	}
+	for _, bad := range []string{"INTERNAL", "NOPE=oops", "OK=fine"} {
+		if _, err := parseUserErrorMessages(bad); err == nil {
+			t.Errorf("parseUserErrorMessages(%q) should fail", bad)
+		}
+	}
+}
+
+func TestUserErrorsInterceptorSanitizesMessages(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Out
+	log.Out = &buf
+	defer func() { log.Out = out }()
+
+	f := newFakeDownstreams()
+	f.payment.err = status.Error(codes.Internal, "gateway 10.3.2.1:8443 returned garbage")
+	cs := newTestCheckoutService(t, f)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(userErrorsInterceptor(defaultUserErrorMessages)))
+	pb.RegisterCheckoutServiceServer(srv, cs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewCheckoutServiceClient(conn)
+
+	_, err = client.PlaceOrder(context.Background(), testOrderRequest())
+	st := status.Convert(err)
+	if st.Code() != codes.Internal {
+		t.Fatalf("PlaceOrder() code = %v, want Internal", st.Code())
+	}
+	if st.Message() != defaultUserErrorMessages[codes.Internal] {
+		t.Errorf("client got message %q, want it sanitized", st.Message())
+	}
+	if !strings.Contains(buf.String(), "10.3.2.1:8443") {
+		t.Errorf("server log lost the error detail: %s", buf.String())
+	}
+
+	// Errors meant for the user go through unchanged.
+	req := testOrderRequest()
+	req.Email = "not an email"
+	_, err = client.PlaceOrder(context.Background(), req)
+	if st := status.Convert(err); st.Code() != codes.InvalidArgument || !strings.Contains(st.Message(), "email") {
+		t.Errorf("PlaceOrder() error = %v, want the validation error", err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+// orderExporter writes completed orders as proto JSON, one order per line,
+// for ingestion into a data warehouse.
+type orderExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newOrderExporter exports orders to stdout when path is "-", or appends them
+// to the file at path otherwise.
+func newOrderExporter(path string) (*orderExporter, error) {
+	w, err := openExportOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	return &orderExporter{w: w}, nil
+}
+
+// openExportOutput returns stdout when path is "-", or the file at path
+// opened for appending otherwise.
+func openExportOutput(path string) (io.Writer, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+func (e *orderExporter) export(order *pb.OrderResult) error {
+	b, err := protojson.Marshal(proto.MessageV2(order))
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+// orderAuditCSV appends a CSV line per completed order, with its id, the
+// hashed user id, the total, its currency and the time the order completed,
+// for lightweight analysis.
+type orderAuditCSV struct {
+	mu sync.Mutex
+	w  *csv.Writer
+}
+
+// newOrderAuditCSV writes the audit to stdout when path is "-", or appends
+// it to the file at path otherwise.
+func newOrderAuditCSV(path string) (*orderAuditCSV, error) {
+	w, err := openExportOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	return &orderAuditCSV{w: csv.NewWriter(w)}, nil
+}
+
+func (a *orderAuditCSV) record(orderID, userID string, total *pb.Money, at time.Time) error {
+	sum := sha256.Sum256([]byte(userID))
+	row := []string{
+		orderID,
+		hex.EncodeToString(sum[:]),
+		strconv.FormatFloat(moneyToFloat(total), 'f', money.Decimals(total.GetCurrencyCode()), 64),
+		total.GetCurrencyCode(),
+		at.UTC().Format(time.RFC3339),
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.w.Write(row); err != nil {
+		return err
+	}
+	a.w.Flush()
+	return a.w.Error()
+}
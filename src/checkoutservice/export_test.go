@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderExportsProtoJSON(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	var buf bytes.Buffer
+	cs.orderExporter = &orderExporter{w: &buf}
+
+	req := testOrderRequest()
+	req.Metadata = map[string]string{giftMessageKey: "Enjoy!"}
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d exported lines, want 1", len(lines))
+	}
+	if !strings.Contains(lines[0], `"shippingTrackingId"`) {
+		t.Errorf("export should use proto JSON field names, got %s", lines[0])
+	}
+	got := &pb.OrderResult{}
+	if err := protojson.Unmarshal([]byte(lines[0]), proto.MessageV2(got)); err != nil {
+		t.Fatalf("failed to parse exported order: %v", err)
+	}
+	if !proto.Equal(got, resp.GetOrder()) {
+		t.Errorf("exported order = %v, want %v", got, resp.GetOrder())
+	}
+}
+
+func TestPlaceOrderAuditsCSV(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	var buf bytes.Buffer
+	cs.orderAudit = &orderAuditCSV{w: csv.NewWriter(&buf)}
+	cs.clock = (&fakeClock{now: time.Date(2020, 6, 1, 12, 30, 0, 0, time.UTC)}).Now
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("audit is not valid CSV: %v", err)
+	}
+	sum := sha256.Sum256([]byte("user-1"))
+	want := []string{resp.GetOrder().GetOrderId(), hex.EncodeToString(sum[:]), "726.98", "USD", "2020-06-01T12:30:00Z"}
+	if len(rows) != 1 || strings.Join(rows[0], "|") != strings.Join(want, "|") {
+		t.Errorf("audit rows = %q, want [%q]", rows, want)
+	}
+}
+
+func TestOrderAuditCSVEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	a := &orderAuditCSV{w: csv.NewWriter(&buf)}
+	id := "order \"1\",\nsecond line"
+	if err := a.record(id, "user", &pb.Money{CurrencyCode: "JPY", Units: 1200}, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("audit is not valid CSV: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 5 || rows[0][0] != id || rows[0][2] != "1200" {
+		t.Errorf("audit rows = %q, want a single row for %q", rows, id)
+	}
+}
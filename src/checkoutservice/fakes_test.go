@@ -0,0 +1,604 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// startFakeServer serves the services registered by register on a random
+// local port for the duration of the test and returns its address.
+func startFakeServer(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// dialTestService connects cs to its configured downstream addresses for the
+// duration of the test.
+func dialTestService(t *testing.T, cs *checkoutService) {
+	t.Helper()
+	if err := cs.dialServices(context.Background()); err != nil {
+		t.Fatalf("dialServices() failed: %v", err)
+	}
+	t.Cleanup(cs.closeConns)
+}
+
+// fakeDownstreams bundles in-memory implementations of every service
+// checkout depends on.
+type fakeDownstreams struct {
+	cart     *fakeCartService
+	catalog  *fakeProductCatalogService
+	currency *fakeCurrencyService
+	shipping *fakeShippingService
+	payment  *fakePaymentService
+	email    *fakeEmailService
+	sms      *fakeSmsService
+	loyalty  *fakeLoyaltyService
+}
+
+func newFakeDownstreams() *fakeDownstreams {
+	return &fakeDownstreams{
+		cart: &fakeCartService{carts: map[string][]*pb.CartItem{
+			"user-1": {
+				{ProductId: "OLJCESPC7Z", Quantity: 1},
+				{ProductId: "66VCHSJNUP", Quantity: 2},
+			},
+		}},
+		catalog: &fakeProductCatalogService{products: map[string]*pb.Product{
+			"OLJCESPC7Z": {Id: "OLJCESPC7Z", Name: "Vintage Typewriter", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 19, Nanos: 990000000}},
+			"66VCHSJNUP": {Id: "66VCHSJNUP", Name: "Vintage Camera Lens", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 349}},
+		}},
+		currency: &fakeCurrencyService{rates: map[string]float64{"EUR": 0.5, "JPY": 100}},
+		shipping: &fakeShippingService{quote: &pb.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000}},
+		payment:  &fakePaymentService{},
+		email:    &fakeEmailService{},
+		sms:      &fakeSmsService{},
+		loyalty:  &fakeLoyaltyService{balances: map[string]int64{"user-1": 500}},
+	}
+}
+
+// newTestCheckoutService returns a checkout service connected to the given
+// fakes, all served from a single local server.
+func newTestCheckoutService(t *testing.T, f *fakeDownstreams) *checkoutService {
+	t.Helper()
+	addr := startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterCartServiceServer(s, f.cart)
+		pb.RegisterProductCatalogServiceServer(s, f.catalog)
+		pb.RegisterCurrencyServiceServer(s, f.currency)
+		pb.RegisterShippingServiceServer(s, f.shipping)
+		pb.RegisterPaymentServiceServer(s, f.payment)
+		pb.RegisterEmailServiceServer(s, f.email)
+		pb.RegisterSmsServiceServer(s, f.sms)
+		pb.RegisterLoyaltyServiceServer(s, f.loyalty)
+	})
+	cs := &checkoutService{
+		productCatalogSvcAddr: addr,
+		cartSvcAddr:           addr,
+		currencySvcAddr:       addr,
+		shippingSvcAddr:       addr,
+		emailSvcAddr:          addr,
+		paymentSvcAddr:        addr,
+		smsSvcAddr:            addr,
+		loyaltySvcAddr:        addr,
+		orders:                newOrderStore(),
+	}
+	dialTestService(t, cs)
+	return cs
+}
+
+// testOrderRequest returns a valid order request for "user-1".
+func testOrderRequest() *pb.PlaceOrderRequest {
+	return &pb.PlaceOrderRequest{
+		UserId:       "user-1",
+		UserCurrency: "USD",
+		Email:        "someone@example.com",
+		Address: &pb.Address{
+			StreetAddress: "1600 Amphitheatre Parkway",
+			City:          "Mountain View",
+			State:         "CA",
+			Country:       "United States",
+			ZipCode:       94043,
+		},
+		CreditCard: &pb.CreditCardInfo{
+			CreditCardNumber:          "4432801561520454",
+			CreditCardCvv:             672,
+			CreditCardExpirationYear:  2039,
+			CreditCardExpirationMonth: 1,
+		},
+	}
+}
+
+type fakeCartService struct {
+	mu       sync.Mutex
+	carts    map[string][]*pb.CartItem
+	getCalls int
+	emptied  []string
+
+	// emptyReads is the number of initial GetCart calls returning an empty
+	// cart, simulating a cart that is not yet consistent.
+	emptyReads int
+
+	// unavailable is the number of initial GetCart calls failing with
+	// Unavailable.
+	unavailable int
+
+	// orderIDs holds the order ID metadata of every GetCart call.
+	orderIDs []string
+
+	// budgets holds the remaining budget metadata of every GetCart call.
+	budgets []string
+}
+
+func (f *fakeCartService) AddItem(ctx context.Context, req *pb.AddItemRequest) (*pb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.carts[req.GetUserId()] = append(f.carts[req.GetUserId()], req.GetItem())
+	return &pb.Empty{}, nil
+}
+
+func (f *fakeCartService) GetCart(ctx context.Context, req *pb.GetCartRequest) (*pb.Cart, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.getCalls++
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		f.orderIDs = append(f.orderIDs, md.Get(orderIDMetadataKey)...)
+		f.budgets = append(f.budgets, md.Get(remainingBudgetMetadataKey)...)
+	}
+	if f.getCalls <= f.unavailable {
+		return nil, status.Error(codes.Unavailable, "cart store unavailable")
+	}
+	if f.getCalls <= f.emptyReads {
+		return &pb.Cart{UserId: req.GetUserId()}, nil
+	}
+	return &pb.Cart{UserId: req.GetUserId(), Items: f.carts[req.GetUserId()]}, nil
+}
+
+func (f *fakeCartService) EmptyCart(ctx context.Context, req *pb.EmptyCartRequest) (*pb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.emptied = append(f.emptied, req.GetUserId())
+	return &pb.Empty{}, nil
+}
+
+type fakeProductCatalogService struct {
+	mu                 sync.Mutex
+	products           map[string]*pb.Product
+	calls              int
+	unavailable        map[string]bool
+	restockDates       map[string]string
+	availabilityChecks int
+
+	// failures is the number of upcoming GetProduct calls failing with
+	// Unavailable, per product.
+	failures map[string]int
+
+	// delay slows down every GetProduct call; inFlight and maxInFlight
+	// track how many calls run concurrently.
+	delay       time.Duration
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fakeProductCatalogService) ListProducts(context.Context, *pb.Empty) (*pb.ListProductsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*pb.Product
+	for _, p := range f.products {
+		out = append(out, p)
+	}
+	return &pb.ListProductsResponse{Products: out}, nil
+}
+
+func (f *fakeProductCatalogService) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.Product, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	f.calls++
+	if f.failures[req.GetId()] > 0 {
+		f.failures[req.GetId()]--
+		return nil, status.Error(codes.Unavailable, "catalog is overloaded")
+	}
+	p, ok := f.products[req.GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no product with ID %s", req.GetId())
+	}
+	return p, nil
+}
+
+func (f *fakeProductCatalogService) CheckAvailability(ctx context.Context, req *pb.CheckAvailabilityRequest) (*pb.CheckAvailabilityResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.availabilityChecks++
+	resp := &pb.CheckAvailabilityResponse{RestockDates: f.restockDates}
+	for _, id := range req.GetProductIds() {
+		if f.unavailable[id] {
+			resp.UnavailableProductIds = append(resp.UnavailableProductIds, id)
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeProductCatalogService) SearchProducts(context.Context, *pb.SearchProductsRequest) (*pb.SearchProductsResponse, error) {
+	return &pb.SearchProductsResponse{}, nil
+}
+
+// fakeCurrencyService converts amounts using fixed per-currency rates
+// relative to USD.
+type fakeCurrencyService struct {
+	mu         sync.Mutex
+	calls      int
+	batchCalls int
+	rates      map[string]float64
+	err        error
+
+	// fromRates, when set, replaces rates for the currency converted from,
+	// making conversions asymmetric.
+	fromRates map[string]float64
+
+	// batchUnsupported makes ConvertBatch fail with Unimplemented.
+	batchUnsupported bool
+
+	// code, when set, is the currency of the converted amounts whatever
+	// the currency asked for.
+	code string
+
+	// delay slows down every Convert call.
+	delay time.Duration
+}
+
+func (f *fakeCurrencyService) Convert(ctx context.Context, req *pb.CurrencyConversionRequest) (*pb.Money, error) {
+	time.Sleep(f.delay)
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.convert(req.GetFrom(), req.GetToCode()), nil
+}
+
+func (f *fakeCurrencyService) ConvertBatch(ctx context.Context, req *pb.CurrencyConversionBatchRequest) (*pb.CurrencyConversionBatchResponse, error) {
+	f.mu.Lock()
+	f.batchCalls++
+	f.mu.Unlock()
+	if f.batchUnsupported {
+		return nil, status.Error(codes.Unimplemented, "unknown method ConvertBatch")
+	}
+	resp := new(pb.CurrencyConversionBatchResponse)
+	for _, from := range req.GetFrom() {
+		resp.To = append(resp.To, f.convert(from, req.GetToCode()))
+	}
+	return resp, nil
+}
+
+func (f *fakeCurrencyService) convert(from *pb.Money, toCode string) *pb.Money {
+	amount := float64(from.GetUnits()) + float64(from.GetNanos())/1e9
+	fromRate := f.rate(from.GetCurrencyCode())
+	if r, ok := f.fromRates[from.GetCurrencyCode()]; ok {
+		fromRate = r
+	}
+	amount = amount / fromRate * f.rate(toCode)
+	units := int64(amount)
+	if f.code != "" {
+		toCode = f.code
+	}
+	return &pb.Money{
+		CurrencyCode: toCode,
+		Units:        units,
+		Nanos:        int32((amount - float64(units)) * 1e9)}
+}
+
+func (f *fakeCurrencyService) GetSupportedCurrencies(context.Context, *pb.Empty) (*pb.GetSupportedCurrenciesResponse, error) {
+	return &pb.GetSupportedCurrenciesResponse{}, nil
+}
+
+func (f *fakeCurrencyService) rate(code string) float64 {
+	if r, ok := f.rates[code]; ok {
+		return r
+	}
+	return 1
+}
+
+func (f *fakeCurrencyService) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *fakeCurrencyService) batchCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batchCalls
+}
+
+type fakeShippingService struct {
+	mu         sync.Mutex
+	quote      *pb.Money
+	quoteCalls int
+	shipped    []*pb.ShipOrderRequest
+	shipErr    error
+
+	// quoteHook, if set, runs before every quote and can fail it.
+	quoteHook func(ctx context.Context) error
+}
+
+func (f *fakeShippingService) GetQuote(ctx context.Context, req *pb.GetQuoteRequest) (*pb.GetQuoteResponse, error) {
+	if f.quoteHook != nil {
+		if err := f.quoteHook(ctx); err != nil {
+			return nil, err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.quoteCalls++
+	return &pb.GetQuoteResponse{CostUsd: f.quote}, nil
+}
+
+func (f *fakeShippingService) ShipOrder(ctx context.Context, req *pb.ShipOrderRequest) (*pb.ShipOrderResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.shipErr != nil {
+		return nil, f.shipErr
+	}
+	f.shipped = append(f.shipped, req)
+	return &pb.ShipOrderResponse{TrackingId: fmt.Sprintf("TRACK-%d", len(f.shipped))}, nil
+}
+
+type fakePaymentService struct {
+	mu       sync.Mutex
+	calls    int
+	charges  []*pb.ChargeRequest
+	captured []string
+	voided   []string
+	err      error
+	delay    time.Duration
+
+	// voidedKeys holds the idempotency keys of the voided charges.
+	voidedKeys []string
+
+	// declined holds the numbers of the cards charges fail for.
+	declined map[string]bool
+
+	// failCaptures holds the transactions captures fail for.
+	failCaptures map[string]bool
+}
+
+func (f *fakePaymentService) Charge(ctx context.Context, req *pb.ChargeRequest) (*pb.ChargeResponse, error) {
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.declined[req.GetCreditCard().GetCreditCardNumber()] {
+		return nil, status.Error(codes.InvalidArgument, "card declined: insufficient funds")
+	}
+	f.charges = append(f.charges, req)
+	return &pb.ChargeResponse{TransactionId: fmt.Sprintf("tx-%d", len(f.charges))}, nil
+}
+
+func (f *fakePaymentService) Capture(ctx context.Context, req *pb.CaptureRequest) (*pb.CaptureResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failCaptures[req.GetTransactionId()] {
+		return nil, status.Error(codes.Unavailable, "capture failed")
+	}
+	f.captured = append(f.captured, req.GetTransactionId())
+	return &pb.CaptureResponse{}, nil
+}
+
+func (f *fakePaymentService) Void(ctx context.Context, req *pb.VoidRequest) (*pb.VoidResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.voided = append(f.voided, req.GetTransactionId())
+	f.voidedKeys = append(f.voidedKeys, req.GetIdempotencyKey())
+	return &pb.VoidResponse{}, nil
+}
+
+func (f *fakePaymentService) chargeCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.charges)
+}
+
+type fakeEmailService struct {
+	mu   sync.Mutex
+	sent []*pb.SendOrderConfirmationRequest
+	err  error
+}
+
+func (f *fakeEmailService) SendOrderConfirmation(ctx context.Context, req *pb.SendOrderConfirmationRequest) (*pb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.sent = append(f.sent, req)
+	return &pb.Empty{}, nil
+}
+
+func (f *fakeEmailService) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+type fakeSmsService struct {
+	mu   sync.Mutex
+	sent []*pb.SendSmsConfirmationRequest
+	err  error
+}
+
+func (f *fakeSmsService) SendOrderConfirmation(ctx context.Context, req *pb.SendSmsConfirmationRequest) (*pb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.sent = append(f.sent, req)
+	return &pb.Empty{}, nil
+}
+
+func (f *fakeSmsService) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+type fakeLoyaltyService struct {
+	mu       sync.Mutex
+	balances map[string]int64
+	redeemed []*pb.RedeemPointsRequest
+
+	// redeemErr makes redemptions fail.
+	redeemErr error
+}
+
+func (f *fakeLoyaltyService) GetPointsBalance(ctx context.Context, req *pb.GetPointsBalanceRequest) (*pb.PointsBalance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &pb.PointsBalance{Points: f.balances[req.GetUserId()]}, nil
+}
+
+func (f *fakeLoyaltyService) RedeemPoints(ctx context.Context, req *pb.RedeemPointsRequest) (*pb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.redeemErr != nil {
+		return nil, f.redeemErr
+	}
+	if req.GetPoints() > f.balances[req.GetUserId()] {
+		return nil, status.Errorf(codes.FailedPrecondition, "insufficient points")
+	}
+	f.balances[req.GetUserId()] -= req.GetPoints()
+	f.redeemed = append(f.redeemed, req)
+	return &pb.Empty{}, nil
+}
+
+type observation struct {
+	name   string
+	value  float64
+	labels map[string]string
+}
+
+// recordingMetrics keeps every reported metric in memory.
+type recordingMetrics struct {
+	mu           sync.Mutex
+	observations []observation
+	counters     []observation
+}
+
+func (m *recordingMetrics) ObserveHistogram(name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations = append(m.observations, observation{name, value, labels})
+}
+
+func (m *recordingMetrics) IncCounter(name string, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters = append(m.counters, observation{name, 1, labels})
+}
+
+// count returns the number of observations or increments of the named metric
+// having all the given labels.
+func (m *recordingMetrics) count(name string, labels map[string]string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := make([]observation, 0, len(m.observations)+len(m.counters))
+	all = append(append(all, m.observations...), m.counters...)
+	n := 0
+	for _, o := range all {
+		if o.name != name {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			if o.labels[k] != v {
+				match = false
+			}
+		}
+		if match {
+			n++
+		}
+	}
+	return n
+}
+
+// recordingTracer keeps every finished span in memory.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer    *recordingTracer
+	operation string
+	tags      map[string]interface{}
+	err       error
+	links     []Span
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, operation string) (Span, context.Context) {
+	return &recordedSpan{tracer: t, operation: operation, tags: make(map[string]interface{})}, ctx
+}
+
+func (s *recordedSpan) SetTag(key string, value interface{}) {
+	s.tags[key] = value
+}
+
+func (s *recordedSpan) Link(other Span) {
+	s.links = append(s.links, other)
+}
+
+func (s *recordedSpan) Finish(err error) {
+	s.err = err
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+// finished returns the finished spans of the given operation.
+func (t *recordingTracer) finished(operation string) []*recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []*recordedSpan
+	for _, s := range t.spans {
+		if s.operation == operation {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// FraudDecision is the outcome of scoring an order for fraud.
+type FraudDecision int
+
+const (
+	FraudAllow FraudDecision = iota
+	FraudReview
+	FraudDeny
+)
+
+func (d FraudDecision) String() string {
+	switch d {
+	case FraudAllow:
+		return "allow"
+	case FraudReview:
+		return "review"
+	case FraudDeny:
+		return "deny"
+	}
+	return "unknown"
+}
+
+// FraudCheck describes an order about to be charged.
+type FraudCheck struct {
+	OrderID string
+	UserID  string
+	Email   string
+	Total   *pb.Money
+	Address *pb.Address
+	IP      string
+}
+
+// FraudVerdict is returned by a FraudScorer.
+type FraudVerdict struct {
+	Score    float64
+	Decision FraudDecision
+}
+
+// FraudScorer scores orders before their payment is authorized.
+type FraudScorer interface {
+	Score(ctx context.Context, check FraudCheck) (FraudVerdict, error)
+}
+
+// allowAllScorer is the default FraudScorer, it accepts every order.
+type allowAllScorer struct{}
+
+func (allowAllScorer) Score(context.Context, FraudCheck) (FraudVerdict, error) {
+	return FraudVerdict{Decision: FraudAllow}, nil
+}
+
+// scoreOrder runs the configured fraud scorer. Orders which cannot be scored
+// are held for review rather than charged blindly.
+func (cs *checkoutService) scoreOrder(ctx context.Context, check FraudCheck) FraudVerdict {
+	scorer := cs.fraudScorer
+	if scorer == nil {
+		scorer = allowAllScorer{}
+	}
+	v, err := scorer.Score(ctx, check)
+	if err != nil {
+		log.Warnf("failed to score order %s for fraud, holding it for review: %+v", check.OrderID, err)
+		return FraudVerdict{Decision: FraudReview}
+	}
+	log.Debugf("fraud score of order %s: %.2f (%s)", check.OrderID, v.Score, v.Decision)
+	return v
+}
+
+// clientIP returns the address of the end user, as forwarded by the frontend,
+// falling back to the address of the immediate peer.
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-forwarded-for"); len(v) > 0 {
+			return strings.TrimSpace(strings.Split(v[0], ",")[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
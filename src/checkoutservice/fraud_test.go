@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+type fakeFraudScorer struct {
+	verdict FraudVerdict
+	err     error
+	checks  []FraudCheck
+}
+
+func (f *fakeFraudScorer) Score(ctx context.Context, check FraudCheck) (FraudVerdict, error) {
+	f.checks = append(f.checks, check)
+	return f.verdict, f.err
+}
+
+func TestPlaceOrderFraudDecisions(t *testing.T) {
+	tests := []struct {
+		name        string
+		scorer      *fakeFraudScorer
+		wantCode    codes.Code
+		wantStatus  pb.OrderStatus
+		wantCharged bool
+	}{
+		{"allow", &fakeFraudScorer{verdict: FraudVerdict{Score: 0.1, Decision: FraudAllow}}, codes.OK, pb.OrderStatus_ORDER_STATUS_COMPLETED, true},
+		{"review", &fakeFraudScorer{verdict: FraudVerdict{Score: 0.6, Decision: FraudReview}}, codes.OK, pb.OrderStatus_ORDER_STATUS_REVIEW, false},
+		{"deny", &fakeFraudScorer{verdict: FraudVerdict{Score: 0.9, Decision: FraudDeny}}, codes.PermissionDenied, 0, false},
+		{"scorer failure", &fakeFraudScorer{err: errors.New("scorer down")}, codes.OK, pb.OrderStatus_ORDER_STATUS_REVIEW, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.fraudScorer = tt.scorer
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-for", "203.0.113.7, 10.0.0.1"))
+			resp, err := cs.PlaceOrder(ctx, testOrderRequest())
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("PlaceOrder() code = %v, want %v (err: %v)", status.Code(err), tt.wantCode, err)
+			}
+			if charged := f.payment.chargeCount() > 0; charged != tt.wantCharged {
+				t.Errorf("card charged = %v, want %v", charged, tt.wantCharged)
+			}
+			if len(tt.scorer.checks) != 1 {
+				t.Fatalf("scorer was called %d times, want 1", len(tt.scorer.checks))
+			}
+			if c := tt.scorer.checks[0]; c.UserID != "user-1" || c.IP != "203.0.113.7" || c.Total.GetUnits() == 0 {
+				t.Errorf("unexpected fraud check context: %+v", c)
+			}
+			if err != nil {
+				return
+			}
+			order := resp.GetOrder()
+			if order.GetStatus() != tt.wantStatus {
+				t.Errorf("order status = %v, want %v", order.GetStatus(), tt.wantStatus)
+			}
+			if tt.wantStatus == pb.OrderStatus_ORDER_STATUS_REVIEW {
+				st, err := cs.GetOrderStatus(context.Background(), &pb.GetOrderStatusRequest{OrderId: order.GetOrderId()})
+				if err != nil || st.GetStatus() != pb.OrderStatus_ORDER_STATUS_REVIEW {
+					t.Errorf("GetOrderStatus() = %v, %v; want an order in review", st, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceOrderReviewReportsFullOrder(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"][1].GiftWrap = true
+	cs := newTestCheckoutService(t, f)
+	cs.fraudScorer = &fakeFraudScorer{verdict: FraudVerdict{Score: 0.6, Decision: FraudReview}}
+	cs.giftWrapFee = &pb.Money{CurrencyCode: "USD", Units: 2, Nanos: 500000000}
+	cs.maxDeliveryDays = 30
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 23, 0, 0, 0, time.UTC)}
+	cs.clock = clock.Now
+
+	req := testOrderRequest()
+	req.DeliveryDate = "2020-06-15"
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	order := resp.GetOrder()
+	if order.GetStatus() != pb.OrderStatus_ORDER_STATUS_REVIEW {
+		t.Fatalf("order status = %v, want in review", order.GetStatus())
+	}
+	if order.GetGiftWrapCost() == nil || order.GetDeliveryDate() != "2020-06-15" || order.GetGrandTotal() == nil {
+		t.Errorf("order in review = %v, want its gift wrap cost, delivery date and total", order)
+	}
+}
@@ -25,9 +25,111 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type OrderStatus int32
+
+const (
+	OrderStatus_ORDER_STATUS_UNSPECIFIED OrderStatus = 0
+	OrderStatus_ORDER_STATUS_PENDING     OrderStatus = 1
+	OrderStatus_ORDER_STATUS_COMPLETED   OrderStatus = 2
+	OrderStatus_ORDER_STATUS_FAILED      OrderStatus = 3
+	// The order was flagged by fraud scoring and awaits a manual review
+	// before being charged.
+	OrderStatus_ORDER_STATUS_REVIEW OrderStatus = 4
+	// The order stayed pending or in review for longer than the configured
+	// time to live and was abandoned.
+	OrderStatus_ORDER_STATUS_EXPIRED OrderStatus = 5
+)
+
+var OrderStatus_name = map[int32]string{
+	0: "ORDER_STATUS_UNSPECIFIED",
+	1: "ORDER_STATUS_PENDING",
+	2: "ORDER_STATUS_COMPLETED",
+	3: "ORDER_STATUS_FAILED",
+	4: "ORDER_STATUS_REVIEW",
+	5: "ORDER_STATUS_EXPIRED",
+}
+
+var OrderStatus_value = map[string]int32{
+	"ORDER_STATUS_UNSPECIFIED": 0,
+	"ORDER_STATUS_PENDING":     1,
+	"ORDER_STATUS_COMPLETED":   2,
+	"ORDER_STATUS_FAILED":      3,
+	"ORDER_STATUS_REVIEW":      4,
+	"ORDER_STATUS_EXPIRED":     5,
+}
+
+func (x OrderStatus) String() string {
+	return proto.EnumName(OrderStatus_name, int32(x))
+}
+
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{0}
+}
+
+type ItemDetail int32
+
+const (
+	// Detail the product of every item.
+	ItemDetail_ITEM_DETAIL_FULL ItemDetail = 0
+	// Only give the product id and cost of items.
+	ItemDetail_ITEM_DETAIL_SUMMARY ItemDetail = 1
+)
+
+var ItemDetail_name = map[int32]string{
+	0: "ITEM_DETAIL_FULL",
+	1: "ITEM_DETAIL_SUMMARY",
+}
+
+var ItemDetail_value = map[string]int32{
+	"ITEM_DETAIL_FULL":    0,
+	"ITEM_DETAIL_SUMMARY": 1,
+}
+
+func (x ItemDetail) String() string {
+	return proto.EnumName(ItemDetail_name, int32(x))
+}
+
+func (ItemDetail) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{1}
+}
+
+type ConfirmationChannel int32
+
+const (
+	// Confirm by email only.
+	ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL         ConfirmationChannel = 0
+	ConfirmationChannel_CONFIRMATION_CHANNEL_SMS           ConfirmationChannel = 1
+	ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL_AND_SMS ConfirmationChannel = 2
+)
+
+var ConfirmationChannel_name = map[int32]string{
+	0: "CONFIRMATION_CHANNEL_EMAIL",
+	1: "CONFIRMATION_CHANNEL_SMS",
+	2: "CONFIRMATION_CHANNEL_EMAIL_AND_SMS",
+}
+
+var ConfirmationChannel_value = map[string]int32{
+	"CONFIRMATION_CHANNEL_EMAIL":         0,
+	"CONFIRMATION_CHANNEL_SMS":           1,
+	"CONFIRMATION_CHANNEL_EMAIL_AND_SMS": 2,
+}
+
+func (x ConfirmationChannel) String() string {
+	return proto.EnumName(ConfirmationChannel_name, int32(x))
+}
+
+func (ConfirmationChannel) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{2}
+}
+
 type CartItem struct {
-	ProductId            string   `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
-	Quantity             int32    `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ProductId string `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity  int32  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Gift wrap every unit of the item, for a fee.
+	GiftWrap bool `protobuf:"varint,3,opt,name=gift_wrap,json=giftWrap,proto3" json:"gift_wrap,omitempty"`
+	// Optional note about the item, e.g. a purchase order number or
+	// handling instructions, when item notes are enabled.
+	Note                 string   `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -72,6 +174,20 @@ func (m *CartItem) GetQuantity() int32 {
 	return 0
 }
 
+func (m *CartItem) GetGiftWrap() bool {
+	if m != nil {
+		return m.GiftWrap
+	}
+	return false
+}
+
+func (m *CartItem) GetNote() string {
+	if m != nil {
+		return m.Note
+	}
+	return ""
+}
+
 type AddItemRequest struct {
 	UserId               string    `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Item                 *CartItem `protobuf:"bytes,2,opt,name=item,proto3" json:"item,omitempty"`
@@ -369,7 +485,10 @@ type Product struct {
 	PriceUsd    *Money `protobuf:"bytes,5,opt,name=price_usd,json=priceUsd,proto3" json:"price_usd,omitempty"`
 	// Categories such as "vintage" or "gardening" that can be used to look up
 	// other related products.
-	Categories           []string `protobuf:"bytes,6,rep,name=categories,proto3" json:"categories,omitempty"`
+	Categories []string `protobuf:"bytes,6,rep,name=categories,proto3" json:"categories,omitempty"`
+	// Whether the product, e.g. groceries or digital goods, is exempt from
+	// sales tax.
+	TaxExempt            bool     `protobuf:"varint,7,opt,name=tax_exempt,json=taxExempt,proto3" json:"tax_exempt,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -442,6 +561,13 @@ func (m *Product) GetCategories() []string {
 	return nil
 }
 
+func (m *Product) GetTaxExempt() bool {
+	if m != nil {
+		return m.TaxExempt
+	}
+	return false
+}
+
 type ListProductsResponse struct {
 	Products             []*Product `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
@@ -598,6 +724,94 @@ func (m *SearchProductsResponse) GetResults() []*Product {
 	return nil
 }
 
+type CheckAvailabilityRequest struct {
+	ProductIds           []string `protobuf:"bytes,1,rep,name=product_ids,json=productIds,proto3" json:"product_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckAvailabilityRequest) Reset()         { *m = CheckAvailabilityRequest{} }
+func (m *CheckAvailabilityRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckAvailabilityRequest) ProtoMessage()    {}
+func (*CheckAvailabilityRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{13}
+}
+
+func (m *CheckAvailabilityRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckAvailabilityRequest.Unmarshal(m, b)
+}
+func (m *CheckAvailabilityRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckAvailabilityRequest.Marshal(b, m, deterministic)
+}
+func (m *CheckAvailabilityRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckAvailabilityRequest.Merge(m, src)
+}
+func (m *CheckAvailabilityRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckAvailabilityRequest.Size(m)
+}
+func (m *CheckAvailabilityRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckAvailabilityRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckAvailabilityRequest proto.InternalMessageInfo
+
+func (m *CheckAvailabilityRequest) GetProductIds() []string {
+	if m != nil {
+		return m.ProductIds
+	}
+	return nil
+}
+
+type CheckAvailabilityResponse struct {
+	UnavailableProductIds []string `protobuf:"bytes,1,rep,name=unavailable_product_ids,json=unavailableProductIds,proto3" json:"unavailable_product_ids,omitempty"`
+	// Estimated date, as YYYY-MM-DD, the unavailable products which are
+	// being restocked are available again, by product id.
+	RestockDates         map[string]string `protobuf:"bytes,2,rep,name=restock_dates,json=restockDates,proto3" json:"restock_dates,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *CheckAvailabilityResponse) Reset()         { *m = CheckAvailabilityResponse{} }
+func (m *CheckAvailabilityResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckAvailabilityResponse) ProtoMessage()    {}
+func (*CheckAvailabilityResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{14}
+}
+
+func (m *CheckAvailabilityResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckAvailabilityResponse.Unmarshal(m, b)
+}
+func (m *CheckAvailabilityResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckAvailabilityResponse.Marshal(b, m, deterministic)
+}
+func (m *CheckAvailabilityResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckAvailabilityResponse.Merge(m, src)
+}
+func (m *CheckAvailabilityResponse) XXX_Size() int {
+	return xxx_messageInfo_CheckAvailabilityResponse.Size(m)
+}
+func (m *CheckAvailabilityResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckAvailabilityResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckAvailabilityResponse proto.InternalMessageInfo
+
+func (m *CheckAvailabilityResponse) GetUnavailableProductIds() []string {
+	if m != nil {
+		return m.UnavailableProductIds
+	}
+	return nil
+}
+
+func (m *CheckAvailabilityResponse) GetRestockDates() map[string]string {
+	if m != nil {
+		return m.RestockDates
+	}
+	return nil
+}
+
 type GetQuoteRequest struct {
 	Address              *Address    `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
 	Items                []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
@@ -610,7 +824,7 @@ func (m *GetQuoteRequest) Reset()         { *m = GetQuoteRequest{} }
 func (m *GetQuoteRequest) String() string { return proto.CompactTextString(m) }
 func (*GetQuoteRequest) ProtoMessage()    {}
 func (*GetQuoteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{13}
+	return fileDescriptor_ca53982754088a9d, []int{15}
 }
 
 func (m *GetQuoteRequest) XXX_Unmarshal(b []byte) error {
@@ -656,7 +870,7 @@ func (m *GetQuoteResponse) Reset()         { *m = GetQuoteResponse{} }
 func (m *GetQuoteResponse) String() string { return proto.CompactTextString(m) }
 func (*GetQuoteResponse) ProtoMessage()    {}
 func (*GetQuoteResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{14}
+	return fileDescriptor_ca53982754088a9d, []int{16}
 }
 
 func (m *GetQuoteResponse) XXX_Unmarshal(b []byte) error {
@@ -685,18 +899,21 @@ func (m *GetQuoteResponse) GetCostUsd() *Money {
 }
 
 type ShipOrderRequest struct {
-	Address              *Address    `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
-	Items                []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	Address *Address    `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Items   []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	// Requested delivery date, as YYYY-MM-DD, when the delivery is
+	// scheduled.
+	DeliveryDate         string   `protobuf:"bytes,3,opt,name=delivery_date,json=deliveryDate,proto3" json:"delivery_date,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ShipOrderRequest) Reset()         { *m = ShipOrderRequest{} }
 func (m *ShipOrderRequest) String() string { return proto.CompactTextString(m) }
 func (*ShipOrderRequest) ProtoMessage()    {}
 func (*ShipOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{15}
+	return fileDescriptor_ca53982754088a9d, []int{17}
 }
 
 func (m *ShipOrderRequest) XXX_Unmarshal(b []byte) error {
@@ -731,6 +948,13 @@ func (m *ShipOrderRequest) GetItems() []*CartItem {
 	return nil
 }
 
+func (m *ShipOrderRequest) GetDeliveryDate() string {
+	if m != nil {
+		return m.DeliveryDate
+	}
+	return ""
+}
+
 type ShipOrderResponse struct {
 	TrackingId           string   `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -742,7 +966,7 @@ func (m *ShipOrderResponse) Reset()         { *m = ShipOrderResponse{} }
 func (m *ShipOrderResponse) String() string { return proto.CompactTextString(m) }
 func (*ShipOrderResponse) ProtoMessage()    {}
 func (*ShipOrderResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{16}
+	return fileDescriptor_ca53982754088a9d, []int{18}
 }
 
 func (m *ShipOrderResponse) XXX_Unmarshal(b []byte) error {
@@ -785,7 +1009,7 @@ func (m *Address) Reset()         { *m = Address{} }
 func (m *Address) String() string { return proto.CompactTextString(m) }
 func (*Address) ProtoMessage()    {}
 func (*Address) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{17}
+	return fileDescriptor_ca53982754088a9d, []int{19}
 }
 
 func (m *Address) XXX_Unmarshal(b []byte) error {
@@ -864,7 +1088,7 @@ func (m *Money) Reset()         { *m = Money{} }
 func (m *Money) String() string { return proto.CompactTextString(m) }
 func (*Money) ProtoMessage()    {}
 func (*Money) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{18}
+	return fileDescriptor_ca53982754088a9d, []int{20}
 }
 
 func (m *Money) XXX_Unmarshal(b []byte) error {
@@ -918,7 +1142,7 @@ func (m *GetSupportedCurrenciesResponse) Reset()         { *m = GetSupportedCurr
 func (m *GetSupportedCurrenciesResponse) String() string { return proto.CompactTextString(m) }
 func (*GetSupportedCurrenciesResponse) ProtoMessage()    {}
 func (*GetSupportedCurrenciesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{19}
+	return fileDescriptor_ca53982754088a9d, []int{21}
 }
 
 func (m *GetSupportedCurrenciesResponse) XXX_Unmarshal(b []byte) error {
@@ -959,7 +1183,7 @@ func (m *CurrencyConversionRequest) Reset()         { *m = CurrencyConversionReq
 func (m *CurrencyConversionRequest) String() string { return proto.CompactTextString(m) }
 func (*CurrencyConversionRequest) ProtoMessage()    {}
 func (*CurrencyConversionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{20}
+	return fileDescriptor_ca53982754088a9d, []int{22}
 }
 
 func (m *CurrencyConversionRequest) XXX_Unmarshal(b []byte) error {
@@ -994,6 +1218,93 @@ func (m *CurrencyConversionRequest) GetToCode() string {
 	return ""
 }
 
+type CurrencyConversionBatchRequest struct {
+	From []*Money `protobuf:"bytes,1,rep,name=from,proto3" json:"from,omitempty"`
+	// The 3-letter currency code defined in ISO 4217.
+	ToCode               string   `protobuf:"bytes,2,opt,name=to_code,json=toCode,proto3" json:"to_code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CurrencyConversionBatchRequest) Reset()         { *m = CurrencyConversionBatchRequest{} }
+func (m *CurrencyConversionBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*CurrencyConversionBatchRequest) ProtoMessage()    {}
+func (*CurrencyConversionBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{23}
+}
+
+func (m *CurrencyConversionBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CurrencyConversionBatchRequest.Unmarshal(m, b)
+}
+func (m *CurrencyConversionBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CurrencyConversionBatchRequest.Marshal(b, m, deterministic)
+}
+func (m *CurrencyConversionBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CurrencyConversionBatchRequest.Merge(m, src)
+}
+func (m *CurrencyConversionBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_CurrencyConversionBatchRequest.Size(m)
+}
+func (m *CurrencyConversionBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CurrencyConversionBatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CurrencyConversionBatchRequest proto.InternalMessageInfo
+
+func (m *CurrencyConversionBatchRequest) GetFrom() []*Money {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *CurrencyConversionBatchRequest) GetToCode() string {
+	if m != nil {
+		return m.ToCode
+	}
+	return ""
+}
+
+type CurrencyConversionBatchResponse struct {
+	To                   []*Money `protobuf:"bytes,1,rep,name=to,proto3" json:"to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CurrencyConversionBatchResponse) Reset()         { *m = CurrencyConversionBatchResponse{} }
+func (m *CurrencyConversionBatchResponse) String() string { return proto.CompactTextString(m) }
+func (*CurrencyConversionBatchResponse) ProtoMessage()    {}
+func (*CurrencyConversionBatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{24}
+}
+
+func (m *CurrencyConversionBatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CurrencyConversionBatchResponse.Unmarshal(m, b)
+}
+func (m *CurrencyConversionBatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CurrencyConversionBatchResponse.Marshal(b, m, deterministic)
+}
+func (m *CurrencyConversionBatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CurrencyConversionBatchResponse.Merge(m, src)
+}
+func (m *CurrencyConversionBatchResponse) XXX_Size() int {
+	return xxx_messageInfo_CurrencyConversionBatchResponse.Size(m)
+}
+func (m *CurrencyConversionBatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CurrencyConversionBatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CurrencyConversionBatchResponse proto.InternalMessageInfo
+
+func (m *CurrencyConversionBatchResponse) GetTo() []*Money {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
 type CreditCardInfo struct {
 	CreditCardNumber          string   `protobuf:"bytes,1,opt,name=credit_card_number,json=creditCardNumber,proto3" json:"credit_card_number,omitempty"`
 	CreditCardCvv             int32    `protobuf:"varint,2,opt,name=credit_card_cvv,json=creditCardCvv,proto3" json:"credit_card_cvv,omitempty"`
@@ -1008,7 +1319,7 @@ func (m *CreditCardInfo) Reset()         { *m = CreditCardInfo{} }
 func (m *CreditCardInfo) String() string { return proto.CompactTextString(m) }
 func (*CreditCardInfo) ProtoMessage()    {}
 func (*CreditCardInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{21}
+	return fileDescriptor_ca53982754088a9d, []int{25}
 }
 
 func (m *CreditCardInfo) XXX_Unmarshal(b []byte) error {
@@ -1058,18 +1369,23 @@ func (m *CreditCardInfo) GetCreditCardExpirationMonth() int32 {
 }
 
 type ChargeRequest struct {
-	Amount               *Money          `protobuf:"bytes,1,opt,name=amount,proto3" json:"amount,omitempty"`
-	CreditCard           *CreditCardInfo `protobuf:"bytes,2,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+	Amount     *Money          `protobuf:"bytes,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	CreditCard *CreditCardInfo `protobuf:"bytes,2,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
+	// Stable key identifying the charge so that retried requests are not
+	// charged twice.
+	IdempotencyKey string `protobuf:"bytes,3,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Only place a hold on the amount, to be captured or voided later.
+	AuthorizeOnly        bool     `protobuf:"varint,4,opt,name=authorize_only,json=authorizeOnly,proto3" json:"authorize_only,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ChargeRequest) Reset()         { *m = ChargeRequest{} }
 func (m *ChargeRequest) String() string { return proto.CompactTextString(m) }
 func (*ChargeRequest) ProtoMessage()    {}
 func (*ChargeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{22}
+	return fileDescriptor_ca53982754088a9d, []int{26}
 }
 
 func (m *ChargeRequest) XXX_Unmarshal(b []byte) error {
@@ -1104,6 +1420,20 @@ func (m *ChargeRequest) GetCreditCard() *CreditCardInfo {
 	return nil
 }
 
+func (m *ChargeRequest) GetIdempotencyKey() string {
+	if m != nil {
+		return m.IdempotencyKey
+	}
+	return ""
+}
+
+func (m *ChargeRequest) GetAuthorizeOnly() bool {
+	if m != nil {
+		return m.AuthorizeOnly
+	}
+	return false
+}
+
 type ChargeResponse struct {
 	TransactionId        string   `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -1115,7 +1445,7 @@ func (m *ChargeResponse) Reset()         { *m = ChargeResponse{} }
 func (m *ChargeResponse) String() string { return proto.CompactTextString(m) }
 func (*ChargeResponse) ProtoMessage()    {}
 func (*ChargeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{23}
+	return fileDescriptor_ca53982754088a9d, []int{27}
 }
 
 func (m *ChargeResponse) XXX_Unmarshal(b []byte) error {
@@ -1143,187 +1473,960 @@ func (m *ChargeResponse) GetTransactionId() string {
 	return ""
 }
 
-type OrderItem struct {
-	Item                 *CartItem `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
-	Cost                 *Money    `protobuf:"bytes,2,opt,name=cost,proto3" json:"cost,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+type CaptureRequest struct {
+	TransactionId        string   `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	Amount               *Money   `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *OrderItem) Reset()         { *m = OrderItem{} }
-func (m *OrderItem) String() string { return proto.CompactTextString(m) }
-func (*OrderItem) ProtoMessage()    {}
-func (*OrderItem) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{24}
+func (m *CaptureRequest) Reset()         { *m = CaptureRequest{} }
+func (m *CaptureRequest) String() string { return proto.CompactTextString(m) }
+func (*CaptureRequest) ProtoMessage()    {}
+func (*CaptureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{28}
 }
 
-func (m *OrderItem) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_OrderItem.Unmarshal(m, b)
+func (m *CaptureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptureRequest.Unmarshal(m, b)
 }
-func (m *OrderItem) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_OrderItem.Marshal(b, m, deterministic)
+func (m *CaptureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptureRequest.Marshal(b, m, deterministic)
 }
-func (m *OrderItem) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_OrderItem.Merge(m, src)
+func (m *CaptureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptureRequest.Merge(m, src)
 }
-func (m *OrderItem) XXX_Size() int {
-	return xxx_messageInfo_OrderItem.Size(m)
+func (m *CaptureRequest) XXX_Size() int {
+	return xxx_messageInfo_CaptureRequest.Size(m)
 }
-func (m *OrderItem) XXX_DiscardUnknown() {
-	xxx_messageInfo_OrderItem.DiscardUnknown(m)
+func (m *CaptureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptureRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_OrderItem proto.InternalMessageInfo
+var xxx_messageInfo_CaptureRequest proto.InternalMessageInfo
 
-func (m *OrderItem) GetItem() *CartItem {
+func (m *CaptureRequest) GetTransactionId() string {
 	if m != nil {
-		return m.Item
+		return m.TransactionId
 	}
-	return nil
+	return ""
 }
 
-func (m *OrderItem) GetCost() *Money {
+func (m *CaptureRequest) GetAmount() *Money {
 	if m != nil {
-		return m.Cost
+		return m.Amount
 	}
 	return nil
 }
 
-type OrderResult struct {
-	OrderId              string       `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
-	ShippingTrackingId   string       `protobuf:"bytes,2,opt,name=shipping_tracking_id,json=shippingTrackingId,proto3" json:"shipping_tracking_id,omitempty"`
-	ShippingCost         *Money       `protobuf:"bytes,3,opt,name=shipping_cost,json=shippingCost,proto3" json:"shipping_cost,omitempty"`
-	ShippingAddress      *Address     `protobuf:"bytes,4,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
-	Items                []*OrderItem `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+type CaptureResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *OrderResult) Reset()         { *m = OrderResult{} }
-func (m *OrderResult) String() string { return proto.CompactTextString(m) }
-func (*OrderResult) ProtoMessage()    {}
-func (*OrderResult) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{25}
+func (m *CaptureResponse) Reset()         { *m = CaptureResponse{} }
+func (m *CaptureResponse) String() string { return proto.CompactTextString(m) }
+func (*CaptureResponse) ProtoMessage()    {}
+func (*CaptureResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{29}
 }
 
-func (m *OrderResult) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_OrderResult.Unmarshal(m, b)
+func (m *CaptureResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptureResponse.Unmarshal(m, b)
 }
-func (m *OrderResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_OrderResult.Marshal(b, m, deterministic)
+func (m *CaptureResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptureResponse.Marshal(b, m, deterministic)
 }
-func (m *OrderResult) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_OrderResult.Merge(m, src)
+func (m *CaptureResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptureResponse.Merge(m, src)
 }
-func (m *OrderResult) XXX_Size() int {
-	return xxx_messageInfo_OrderResult.Size(m)
+func (m *CaptureResponse) XXX_Size() int {
+	return xxx_messageInfo_CaptureResponse.Size(m)
 }
-func (m *OrderResult) XXX_DiscardUnknown() {
-	xxx_messageInfo_OrderResult.DiscardUnknown(m)
+func (m *CaptureResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptureResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_OrderResult proto.InternalMessageInfo
+var xxx_messageInfo_CaptureResponse proto.InternalMessageInfo
 
-func (m *OrderResult) GetOrderId() string {
-	if m != nil {
-		return m.OrderId
-	}
-	return ""
+type VoidRequest struct {
+	TransactionId string `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	// Idempotency key of the authorize_only charge to void, for when its
+	// transaction id is unknown, e.g. because the charge timed out.
+	IdempotencyKey       string   `protobuf:"bytes,2,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *OrderResult) GetShippingTrackingId() string {
-	if m != nil {
-		return m.ShippingTrackingId
+func (m *VoidRequest) Reset()         { *m = VoidRequest{} }
+func (m *VoidRequest) String() string { return proto.CompactTextString(m) }
+func (*VoidRequest) ProtoMessage()    {}
+func (*VoidRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{30}
+}
+
+func (m *VoidRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VoidRequest.Unmarshal(m, b)
+}
+func (m *VoidRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VoidRequest.Marshal(b, m, deterministic)
+}
+func (m *VoidRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VoidRequest.Merge(m, src)
+}
+func (m *VoidRequest) XXX_Size() int {
+	return xxx_messageInfo_VoidRequest.Size(m)
+}
+func (m *VoidRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VoidRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VoidRequest proto.InternalMessageInfo
+
+func (m *VoidRequest) GetTransactionId() string {
+	if m != nil {
+		return m.TransactionId
+	}
+	return ""
+}
+
+func (m *VoidRequest) GetIdempotencyKey() string {
+	if m != nil {
+		return m.IdempotencyKey
+	}
+	return ""
+}
+
+type VoidResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VoidResponse) Reset()         { *m = VoidResponse{} }
+func (m *VoidResponse) String() string { return proto.CompactTextString(m) }
+func (*VoidResponse) ProtoMessage()    {}
+func (*VoidResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{31}
+}
+
+func (m *VoidResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VoidResponse.Unmarshal(m, b)
+}
+func (m *VoidResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VoidResponse.Marshal(b, m, deterministic)
+}
+func (m *VoidResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VoidResponse.Merge(m, src)
+}
+func (m *VoidResponse) XXX_Size() int {
+	return xxx_messageInfo_VoidResponse.Size(m)
+}
+func (m *VoidResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_VoidResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VoidResponse proto.InternalMessageInfo
+
+type OrderItem struct {
+	Item *CartItem `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	Cost *Money    `protobuf:"bytes,2,opt,name=cost,proto3" json:"cost,omitempty"`
+	// Set when the item is out of stock and ships once restocked, around
+	// available_date (YYYY-MM-DD).
+	Backordered   bool   `protobuf:"varint,3,opt,name=backordered,proto3" json:"backordered,omitempty"`
+	AvailableDate string `protobuf:"bytes,4,opt,name=available_date,json=availableDate,proto3" json:"available_date,omitempty"`
+	// Product the item is, unless the order is summarized.
+	Product              *Product `protobuf:"bytes,5,opt,name=product,proto3" json:"product,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OrderItem) Reset()         { *m = OrderItem{} }
+func (m *OrderItem) String() string { return proto.CompactTextString(m) }
+func (*OrderItem) ProtoMessage()    {}
+func (*OrderItem) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{32}
+}
+
+func (m *OrderItem) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OrderItem.Unmarshal(m, b)
+}
+func (m *OrderItem) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OrderItem.Marshal(b, m, deterministic)
+}
+func (m *OrderItem) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OrderItem.Merge(m, src)
+}
+func (m *OrderItem) XXX_Size() int {
+	return xxx_messageInfo_OrderItem.Size(m)
+}
+func (m *OrderItem) XXX_DiscardUnknown() {
+	xxx_messageInfo_OrderItem.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OrderItem proto.InternalMessageInfo
+
+func (m *OrderItem) GetItem() *CartItem {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func (m *OrderItem) GetCost() *Money {
+	if m != nil {
+		return m.Cost
+	}
+	return nil
+}
+
+func (m *OrderItem) GetBackordered() bool {
+	if m != nil {
+		return m.Backordered
+	}
+	return false
+}
+
+func (m *OrderItem) GetAvailableDate() string {
+	if m != nil {
+		return m.AvailableDate
 	}
 	return ""
 }
 
-func (m *OrderResult) GetShippingCost() *Money {
-	if m != nil {
-		return m.ShippingCost
-	}
-	return nil
+func (m *OrderItem) GetProduct() *Product {
+	if m != nil {
+		return m.Product
+	}
+	return nil
+}
+
+type OrderResult struct {
+	OrderId            string       `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	ShippingTrackingId string       `protobuf:"bytes,2,opt,name=shipping_tracking_id,json=shippingTrackingId,proto3" json:"shipping_tracking_id,omitempty"`
+	ShippingCost       *Money       `protobuf:"bytes,3,opt,name=shipping_cost,json=shippingCost,proto3" json:"shipping_cost,omitempty"`
+	ShippingAddress    *Address     `protobuf:"bytes,4,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
+	Items              []*OrderItem `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
+	// Arbitrary client supplied metadata, e.g. a gift message.
+	Metadata map[string]string `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Status   OrderStatus       `protobuf:"varint,7,opt,name=status,proto3,enum=hipstershop.OrderStatus" json:"status,omitempty"`
+	// Every currency conversion used to price the order.
+	Conversions []*ConversionRecord `protobuf:"bytes,8,rep,name=conversions,proto3" json:"conversions,omitempty"`
+	// Tracking ids of every shipment when the order ships in several
+	// packages. shipping_tracking_id holds the first one.
+	ShippingTrackingIds []string `protobuf:"bytes,9,rep,name=shipping_tracking_ids,json=shippingTrackingIds,proto3" json:"shipping_tracking_ids,omitempty"`
+	// Cart items left out of the order because they were unavailable, when
+	// partial fulfillment is enabled.
+	UnavailableItems []*CartItem `protobuf:"bytes,10,rep,name=unavailable_items,json=unavailableItems,proto3" json:"unavailable_items,omitempty"`
+	// Increasing number of the order within its tenant. Numbers are not
+	// reused, so the sequence may have gaps.
+	OrderNumber int64 `protobuf:"varint,11,opt,name=order_number,json=orderNumber,proto3" json:"order_number,omitempty"`
+	// Shipping insurance fee, in the user currency, when the order is
+	// insured. It is included in shipping_cost.
+	InsuranceCost *Money `protobuf:"bytes,12,opt,name=insurance_cost,json=insuranceCost,proto3" json:"insurance_cost,omitempty"`
+	// Sales tax, in the user currency, on the items which are not exempt.
+	// It is included in the amount charged.
+	TaxCost *Money `protobuf:"bytes,13,opt,name=tax_cost,json=taxCost,proto3" json:"tax_cost,omitempty"`
+	// Sum of the promotions applied to the order, in the user currency. It
+	// is deducted from the amount charged.
+	Discount *Money `protobuf:"bytes,14,opt,name=discount,proto3" json:"discount,omitempty"`
+	// Promotions applied to the order, e.g. "coupon:WELCOME10" or
+	// "free_shipping".
+	Promotions []string `protobuf:"bytes,15,rep,name=promotions,proto3" json:"promotions,omitempty"`
+	// Requested delivery date, as YYYY-MM-DD, when the delivery is
+	// scheduled.
+	DeliveryDate string `protobuf:"bytes,16,opt,name=delivery_date,json=deliveryDate,proto3" json:"delivery_date,omitempty"`
+	// Gift wrapping fee, in the user currency, of the items to gift wrap.
+	// It is included in the amount charged.
+	GiftWrapCost *Money `protobuf:"bytes,17,opt,name=gift_wrap_cost,json=giftWrapCost,proto3" json:"gift_wrap_cost,omitempty"`
+	// Loyalty points redeemed, and the amount they are worth in the user
+	// currency. The amount is deducted from the amount charged.
+	PointsRedeemed int64  `protobuf:"varint,18,opt,name=points_redeemed,json=pointsRedeemed,proto3" json:"points_redeemed,omitempty"`
+	PointsDiscount *Money `protobuf:"bytes,19,opt,name=points_discount,json=pointsDiscount,proto3" json:"points_discount,omitempty"`
+	// Set when the cart was too large to price every product live, item
+	// prices then come from the product listing and should be reconciled.
+	ApproximatePricing bool `protobuf:"varint,20,opt,name=approximate_pricing,json=approximatePricing,proto3" json:"approximate_pricing,omitempty"`
+	// Total of the order as displayed to the user, rounded to the precision
+	// of the currency with the display rounding. It differs from the amount
+	// charged by at most one minor unit.
+	GrandTotal *Money `protobuf:"bytes,21,opt,name=grand_total,json=grandTotal,proto3" json:"grand_total,omitempty"`
+	// Promotions applied to the order with the discount of each, when
+	// enabled.
+	AppliedPromotions    []*AppliedPromotion `protobuf:"bytes,22,rep,name=applied_promotions,json=appliedPromotions,proto3" json:"applied_promotions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *OrderResult) Reset()         { *m = OrderResult{} }
+func (m *OrderResult) String() string { return proto.CompactTextString(m) }
+func (*OrderResult) ProtoMessage()    {}
+func (*OrderResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{33}
+}
+
+func (m *OrderResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OrderResult.Unmarshal(m, b)
+}
+func (m *OrderResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OrderResult.Marshal(b, m, deterministic)
+}
+func (m *OrderResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OrderResult.Merge(m, src)
+}
+func (m *OrderResult) XXX_Size() int {
+	return xxx_messageInfo_OrderResult.Size(m)
+}
+func (m *OrderResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_OrderResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OrderResult proto.InternalMessageInfo
+
+func (m *OrderResult) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+func (m *OrderResult) GetShippingTrackingId() string {
+	if m != nil {
+		return m.ShippingTrackingId
+	}
+	return ""
+}
+
+func (m *OrderResult) GetShippingCost() *Money {
+	if m != nil {
+		return m.ShippingCost
+	}
+	return nil
+}
+
+func (m *OrderResult) GetShippingAddress() *Address {
+	if m != nil {
+		return m.ShippingAddress
+	}
+	return nil
+}
+
+func (m *OrderResult) GetItems() []*OrderItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *OrderResult) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *OrderResult) GetStatus() OrderStatus {
+	if m != nil {
+		return m.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (m *OrderResult) GetConversions() []*ConversionRecord {
+	if m != nil {
+		return m.Conversions
+	}
+	return nil
+}
+
+func (m *OrderResult) GetShippingTrackingIds() []string {
+	if m != nil {
+		return m.ShippingTrackingIds
+	}
+	return nil
+}
+
+func (m *OrderResult) GetUnavailableItems() []*CartItem {
+	if m != nil {
+		return m.UnavailableItems
+	}
+	return nil
+}
+
+func (m *OrderResult) GetOrderNumber() int64 {
+	if m != nil {
+		return m.OrderNumber
+	}
+	return 0
+}
+
+func (m *OrderResult) GetInsuranceCost() *Money {
+	if m != nil {
+		return m.InsuranceCost
+	}
+	return nil
+}
+
+func (m *OrderResult) GetTaxCost() *Money {
+	if m != nil {
+		return m.TaxCost
+	}
+	return nil
+}
+
+func (m *OrderResult) GetDiscount() *Money {
+	if m != nil {
+		return m.Discount
+	}
+	return nil
+}
+
+func (m *OrderResult) GetPromotions() []string {
+	if m != nil {
+		return m.Promotions
+	}
+	return nil
+}
+
+func (m *OrderResult) GetDeliveryDate() string {
+	if m != nil {
+		return m.DeliveryDate
+	}
+	return ""
+}
+
+func (m *OrderResult) GetGiftWrapCost() *Money {
+	if m != nil {
+		return m.GiftWrapCost
+	}
+	return nil
+}
+
+func (m *OrderResult) GetPointsRedeemed() int64 {
+	if m != nil {
+		return m.PointsRedeemed
+	}
+	return 0
+}
+
+func (m *OrderResult) GetPointsDiscount() *Money {
+	if m != nil {
+		return m.PointsDiscount
+	}
+	return nil
+}
+
+func (m *OrderResult) GetApproximatePricing() bool {
+	if m != nil {
+		return m.ApproximatePricing
+	}
+	return false
+}
+
+func (m *OrderResult) GetGrandTotal() *Money {
+	if m != nil {
+		return m.GrandTotal
+	}
+	return nil
+}
+
+func (m *OrderResult) GetAppliedPromotions() []*AppliedPromotion {
+	if m != nil {
+		return m.AppliedPromotions
+	}
+	return nil
+}
+
+type AppliedPromotion struct {
+	// Kind of promotion, "coupon" or "free_shipping".
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Coupon code, for coupons.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	// Discount granted by the promotion, in the user currency.
+	Discount             *Money   `protobuf:"bytes,3,opt,name=discount,proto3" json:"discount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AppliedPromotion) Reset()         { *m = AppliedPromotion{} }
+func (m *AppliedPromotion) String() string { return proto.CompactTextString(m) }
+func (*AppliedPromotion) ProtoMessage()    {}
+func (*AppliedPromotion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{34}
+}
+
+func (m *AppliedPromotion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppliedPromotion.Unmarshal(m, b)
+}
+func (m *AppliedPromotion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppliedPromotion.Marshal(b, m, deterministic)
+}
+func (m *AppliedPromotion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppliedPromotion.Merge(m, src)
+}
+func (m *AppliedPromotion) XXX_Size() int {
+	return xxx_messageInfo_AppliedPromotion.Size(m)
+}
+func (m *AppliedPromotion) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppliedPromotion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppliedPromotion proto.InternalMessageInfo
+
+func (m *AppliedPromotion) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *AppliedPromotion) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *AppliedPromotion) GetDiscount() *Money {
+	if m != nil {
+		return m.Discount
+	}
+	return nil
+}
+
+type ConversionRecord struct {
+	// What was converted, e.g. "product:OLJCESPC7Z" or "shipping".
+	Description string `protobuf:"bytes,1,opt,name=description,proto3" json:"description,omitempty"`
+	From        *Money `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To          *Money `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	// Effective rate applied, i.e. to / from.
+	Rate                 float64  `protobuf:"fixed64,4,opt,name=rate,proto3" json:"rate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ConversionRecord) Reset()         { *m = ConversionRecord{} }
+func (m *ConversionRecord) String() string { return proto.CompactTextString(m) }
+func (*ConversionRecord) ProtoMessage()    {}
+func (*ConversionRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{35}
+}
+
+func (m *ConversionRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConversionRecord.Unmarshal(m, b)
+}
+func (m *ConversionRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConversionRecord.Marshal(b, m, deterministic)
+}
+func (m *ConversionRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConversionRecord.Merge(m, src)
+}
+func (m *ConversionRecord) XXX_Size() int {
+	return xxx_messageInfo_ConversionRecord.Size(m)
+}
+func (m *ConversionRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConversionRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ConversionRecord proto.InternalMessageInfo
+
+func (m *ConversionRecord) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *ConversionRecord) GetFrom() *Money {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *ConversionRecord) GetTo() *Money {
+	if m != nil {
+		return m.To
+	}
+	return nil
+}
+
+func (m *ConversionRecord) GetRate() float64 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+type SendOrderConfirmationRequest struct {
+	Email string       `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Order *OrderResult `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
+	// Optional pre-rendered attachment (e.g. an invoice) to include with the
+	// confirmation.
+	Attachment *EmailAttachment `protobuf:"bytes,3,opt,name=attachment,proto3" json:"attachment,omitempty"`
+	// Gift message to include in the confirmation, if any.
+	GiftMessage          string   `protobuf:"bytes,4,opt,name=gift_message,json=giftMessage,proto3" json:"gift_message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SendOrderConfirmationRequest) Reset()         { *m = SendOrderConfirmationRequest{} }
+func (m *SendOrderConfirmationRequest) String() string { return proto.CompactTextString(m) }
+func (*SendOrderConfirmationRequest) ProtoMessage()    {}
+func (*SendOrderConfirmationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{36}
+}
+
+func (m *SendOrderConfirmationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendOrderConfirmationRequest.Unmarshal(m, b)
+}
+func (m *SendOrderConfirmationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendOrderConfirmationRequest.Marshal(b, m, deterministic)
+}
+func (m *SendOrderConfirmationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendOrderConfirmationRequest.Merge(m, src)
+}
+func (m *SendOrderConfirmationRequest) XXX_Size() int {
+	return xxx_messageInfo_SendOrderConfirmationRequest.Size(m)
+}
+func (m *SendOrderConfirmationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendOrderConfirmationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendOrderConfirmationRequest proto.InternalMessageInfo
+
+func (m *SendOrderConfirmationRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *SendOrderConfirmationRequest) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+func (m *SendOrderConfirmationRequest) GetAttachment() *EmailAttachment {
+	if m != nil {
+		return m.Attachment
+	}
+	return nil
+}
+
+func (m *SendOrderConfirmationRequest) GetGiftMessage() string {
+	if m != nil {
+		return m.GiftMessage
+	}
+	return ""
+}
+
+type EmailAttachment struct {
+	// Format of the attachment, e.g. "csv".
+	Format               string   `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"`
+	Filename             string   `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	ContentType          string   `protobuf:"bytes,3,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Content              []byte   `protobuf:"bytes,4,opt,name=content,proto3" json:"content,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EmailAttachment) Reset()         { *m = EmailAttachment{} }
+func (m *EmailAttachment) String() string { return proto.CompactTextString(m) }
+func (*EmailAttachment) ProtoMessage()    {}
+func (*EmailAttachment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{37}
+}
+
+func (m *EmailAttachment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EmailAttachment.Unmarshal(m, b)
+}
+func (m *EmailAttachment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EmailAttachment.Marshal(b, m, deterministic)
+}
+func (m *EmailAttachment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EmailAttachment.Merge(m, src)
+}
+func (m *EmailAttachment) XXX_Size() int {
+	return xxx_messageInfo_EmailAttachment.Size(m)
+}
+func (m *EmailAttachment) XXX_DiscardUnknown() {
+	xxx_messageInfo_EmailAttachment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EmailAttachment proto.InternalMessageInfo
+
+func (m *EmailAttachment) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+func (m *EmailAttachment) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *EmailAttachment) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *EmailAttachment) GetContent() []byte {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+type SendSmsConfirmationRequest struct {
+	// Phone number in E.164 format, e.g. "+14155550123".
+	PhoneNumber          string       `protobuf:"bytes,1,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	Order                *OrderResult `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *SendSmsConfirmationRequest) Reset()         { *m = SendSmsConfirmationRequest{} }
+func (m *SendSmsConfirmationRequest) String() string { return proto.CompactTextString(m) }
+func (*SendSmsConfirmationRequest) ProtoMessage()    {}
+func (*SendSmsConfirmationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{38}
+}
+
+func (m *SendSmsConfirmationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendSmsConfirmationRequest.Unmarshal(m, b)
+}
+func (m *SendSmsConfirmationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendSmsConfirmationRequest.Marshal(b, m, deterministic)
+}
+func (m *SendSmsConfirmationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendSmsConfirmationRequest.Merge(m, src)
+}
+func (m *SendSmsConfirmationRequest) XXX_Size() int {
+	return xxx_messageInfo_SendSmsConfirmationRequest.Size(m)
+}
+func (m *SendSmsConfirmationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendSmsConfirmationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendSmsConfirmationRequest proto.InternalMessageInfo
+
+func (m *SendSmsConfirmationRequest) GetPhoneNumber() string {
+	if m != nil {
+		return m.PhoneNumber
+	}
+	return ""
+}
+
+func (m *SendSmsConfirmationRequest) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+type GetPointsBalanceRequest struct {
+	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetPointsBalanceRequest) Reset()         { *m = GetPointsBalanceRequest{} }
+func (m *GetPointsBalanceRequest) String() string { return proto.CompactTextString(m) }
+func (*GetPointsBalanceRequest) ProtoMessage()    {}
+func (*GetPointsBalanceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{39}
+}
+
+func (m *GetPointsBalanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetPointsBalanceRequest.Unmarshal(m, b)
+}
+func (m *GetPointsBalanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetPointsBalanceRequest.Marshal(b, m, deterministic)
+}
+func (m *GetPointsBalanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetPointsBalanceRequest.Merge(m, src)
+}
+func (m *GetPointsBalanceRequest) XXX_Size() int {
+	return xxx_messageInfo_GetPointsBalanceRequest.Size(m)
+}
+func (m *GetPointsBalanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetPointsBalanceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetPointsBalanceRequest proto.InternalMessageInfo
+
+func (m *GetPointsBalanceRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+type PointsBalance struct {
+	Points               int64    `protobuf:"varint,1,opt,name=points,proto3" json:"points,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PointsBalance) Reset()         { *m = PointsBalance{} }
+func (m *PointsBalance) String() string { return proto.CompactTextString(m) }
+func (*PointsBalance) ProtoMessage()    {}
+func (*PointsBalance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{40}
 }
 
-func (m *OrderResult) GetShippingAddress() *Address {
-	if m != nil {
-		return m.ShippingAddress
-	}
-	return nil
+func (m *PointsBalance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PointsBalance.Unmarshal(m, b)
+}
+func (m *PointsBalance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PointsBalance.Marshal(b, m, deterministic)
+}
+func (m *PointsBalance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PointsBalance.Merge(m, src)
+}
+func (m *PointsBalance) XXX_Size() int {
+	return xxx_messageInfo_PointsBalance.Size(m)
+}
+func (m *PointsBalance) XXX_DiscardUnknown() {
+	xxx_messageInfo_PointsBalance.DiscardUnknown(m)
 }
 
-func (m *OrderResult) GetItems() []*OrderItem {
+var xxx_messageInfo_PointsBalance proto.InternalMessageInfo
+
+func (m *PointsBalance) GetPoints() int64 {
 	if m != nil {
-		return m.Items
+		return m.Points
 	}
-	return nil
+	return 0
 }
 
-type SendOrderConfirmationRequest struct {
-	Email                string       `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
-	Order                *OrderResult `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+type RedeemPointsRequest struct {
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Points int64  `protobuf:"varint,2,opt,name=points,proto3" json:"points,omitempty"`
+	// Order the points are redeemed for. Redeeming points again for the same
+	// order has no effect.
+	OrderId              string   `protobuf:"bytes,3,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *SendOrderConfirmationRequest) Reset()         { *m = SendOrderConfirmationRequest{} }
-func (m *SendOrderConfirmationRequest) String() string { return proto.CompactTextString(m) }
-func (*SendOrderConfirmationRequest) ProtoMessage()    {}
-func (*SendOrderConfirmationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{26}
+func (m *RedeemPointsRequest) Reset()         { *m = RedeemPointsRequest{} }
+func (m *RedeemPointsRequest) String() string { return proto.CompactTextString(m) }
+func (*RedeemPointsRequest) ProtoMessage()    {}
+func (*RedeemPointsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{41}
 }
 
-func (m *SendOrderConfirmationRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SendOrderConfirmationRequest.Unmarshal(m, b)
+func (m *RedeemPointsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RedeemPointsRequest.Unmarshal(m, b)
 }
-func (m *SendOrderConfirmationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SendOrderConfirmationRequest.Marshal(b, m, deterministic)
+func (m *RedeemPointsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RedeemPointsRequest.Marshal(b, m, deterministic)
 }
-func (m *SendOrderConfirmationRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SendOrderConfirmationRequest.Merge(m, src)
+func (m *RedeemPointsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RedeemPointsRequest.Merge(m, src)
 }
-func (m *SendOrderConfirmationRequest) XXX_Size() int {
-	return xxx_messageInfo_SendOrderConfirmationRequest.Size(m)
+func (m *RedeemPointsRequest) XXX_Size() int {
+	return xxx_messageInfo_RedeemPointsRequest.Size(m)
 }
-func (m *SendOrderConfirmationRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_SendOrderConfirmationRequest.DiscardUnknown(m)
+func (m *RedeemPointsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RedeemPointsRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_SendOrderConfirmationRequest proto.InternalMessageInfo
+var xxx_messageInfo_RedeemPointsRequest proto.InternalMessageInfo
 
-func (m *SendOrderConfirmationRequest) GetEmail() string {
+func (m *RedeemPointsRequest) GetUserId() string {
 	if m != nil {
-		return m.Email
+		return m.UserId
 	}
 	return ""
 }
 
-func (m *SendOrderConfirmationRequest) GetOrder() *OrderResult {
+func (m *RedeemPointsRequest) GetPoints() int64 {
 	if m != nil {
-		return m.Order
+		return m.Points
 	}
-	return nil
+	return 0
+}
+
+func (m *RedeemPointsRequest) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
 }
 
 type PlaceOrderRequest struct {
-	UserId               string          `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	UserCurrency         string          `protobuf:"bytes,2,opt,name=user_currency,json=userCurrency,proto3" json:"user_currency,omitempty"`
-	Address              *Address        `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
-	Email                string          `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
-	CreditCard           *CreditCardInfo `protobuf:"bytes,6,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+	UserId       string          `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	UserCurrency string          `protobuf:"bytes,2,opt,name=user_currency,json=userCurrency,proto3" json:"user_currency,omitempty"`
+	Address      *Address        `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Email        string          `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	CreditCard   *CreditCardInfo `protobuf:"bytes,6,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
+	// Optional format of an attachment listing the order line items to send
+	// along with the confirmation email. Only "csv" is supported.
+	ConfirmationAttachmentFormat string `protobuf:"bytes,7,opt,name=confirmation_attachment_format,json=confirmationAttachmentFormat,proto3" json:"confirmation_attachment_format,omitempty"`
+	// Arbitrary metadata attached to the order, such as a gift message
+	// ("gift_message") or the source campaign.
+	Metadata map[string]string `protobuf:"bytes,8,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Complete the order without sending a confirmation email, e.g. for
+	// internal test orders.
+	SkipConfirmation bool `protobuf:"varint,9,opt,name=skip_confirmation,json=skipConfirmation,proto3" json:"skip_confirmation,omitempty"`
+	// Insure the shipment. The insurance fee is added to the shipping cost.
+	Insured bool `protobuf:"varint,10,opt,name=insured,proto3" json:"insured,omitempty"`
+	// Items added to the cart of a guest session, merged into the cart of
+	// user_id when the guest logs in at checkout.
+	GuestItems []*CartItem `protobuf:"bytes,11,rep,name=guest_items,json=guestItems,proto3" json:"guest_items,omitempty"`
+	// Phone number, in E.164 format, to send the confirmation to by SMS.
+	PhoneNumber string `protobuf:"bytes,12,opt,name=phone_number,json=phoneNumber,proto3" json:"phone_number,omitempty"`
+	// Channels the order confirmation is sent through.
+	ConfirmationChannel ConfirmationChannel `protobuf:"varint,13,opt,name=confirmation_channel,json=confirmationChannel,proto3,enum=hipstershop.ConfirmationChannel" json:"confirmation_channel,omitempty"`
+	// Optional coupon code to redeem with the order.
+	CouponCode string `protobuf:"bytes,14,opt,name=coupon_code,json=couponCode,proto3" json:"coupon_code,omitempty"`
+	// Optional delivery date, as YYYY-MM-DD, to schedule the delivery on.
+	// It must be in the future and within the allowed window.
+	DeliveryDate string `protobuf:"bytes,15,opt,name=delivery_date,json=deliveryDate,proto3" json:"delivery_date,omitempty"`
+	// Loyalty points to pay part of the order with. They must not exceed
+	// the points balance of the user.
+	PointsToRedeem int64 `protobuf:"varint,16,opt,name=points_to_redeem,json=pointsToRedeem,proto3" json:"points_to_redeem,omitempty"`
+	// Cards to split the payment across, in place of credit_card. Their
+	// amounts must add up to the total of the order.
+	PaymentSplits []*PaymentSplit `protobuf:"bytes,17,rep,name=payment_splits,json=paymentSplits,proto3" json:"payment_splits,omitempty"`
+	// How much of each product the items of the order response detail.
+	ItemDetail ItemDetail `protobuf:"varint,18,opt,name=item_detail,json=itemDetail,proto3,enum=hipstershop.ItemDetail" json:"item_detail,omitempty"`
+	// Notes to attach to the items of the cart, by product id. They replace
+	// the notes the cart items may have.
+	ItemNotes            map[string]string `protobuf:"bytes,19,rep,name=item_notes,json=itemNotes,proto3" json:"item_notes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *PlaceOrderRequest) Reset()         { *m = PlaceOrderRequest{} }
 func (m *PlaceOrderRequest) String() string { return proto.CompactTextString(m) }
 func (*PlaceOrderRequest) ProtoMessage()    {}
 func (*PlaceOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{27}
+	return fileDescriptor_ca53982754088a9d, []int{42}
 }
 
 func (m *PlaceOrderRequest) XXX_Unmarshal(b []byte) error {
@@ -1379,6 +2482,146 @@ func (m *PlaceOrderRequest) GetCreditCard() *CreditCardInfo {
 	return nil
 }
 
+func (m *PlaceOrderRequest) GetConfirmationAttachmentFormat() string {
+	if m != nil {
+		return m.ConfirmationAttachmentFormat
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *PlaceOrderRequest) GetSkipConfirmation() bool {
+	if m != nil {
+		return m.SkipConfirmation
+	}
+	return false
+}
+
+func (m *PlaceOrderRequest) GetInsured() bool {
+	if m != nil {
+		return m.Insured
+	}
+	return false
+}
+
+func (m *PlaceOrderRequest) GetGuestItems() []*CartItem {
+	if m != nil {
+		return m.GuestItems
+	}
+	return nil
+}
+
+func (m *PlaceOrderRequest) GetPhoneNumber() string {
+	if m != nil {
+		return m.PhoneNumber
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetConfirmationChannel() ConfirmationChannel {
+	if m != nil {
+		return m.ConfirmationChannel
+	}
+	return ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL
+}
+
+func (m *PlaceOrderRequest) GetCouponCode() string {
+	if m != nil {
+		return m.CouponCode
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetDeliveryDate() string {
+	if m != nil {
+		return m.DeliveryDate
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetPointsToRedeem() int64 {
+	if m != nil {
+		return m.PointsToRedeem
+	}
+	return 0
+}
+
+func (m *PlaceOrderRequest) GetPaymentSplits() []*PaymentSplit {
+	if m != nil {
+		return m.PaymentSplits
+	}
+	return nil
+}
+
+func (m *PlaceOrderRequest) GetItemDetail() ItemDetail {
+	if m != nil {
+		return m.ItemDetail
+	}
+	return ItemDetail_ITEM_DETAIL_FULL
+}
+
+func (m *PlaceOrderRequest) GetItemNotes() map[string]string {
+	if m != nil {
+		return m.ItemNotes
+	}
+	return nil
+}
+
+// PaymentSplit is the part of an order total paid with a card.
+type PaymentSplit struct {
+	CreditCard *CreditCardInfo `protobuf:"bytes,1,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
+	// Amount to charge the card, in the user currency.
+	Amount               *Money   `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PaymentSplit) Reset()         { *m = PaymentSplit{} }
+func (m *PaymentSplit) String() string { return proto.CompactTextString(m) }
+func (*PaymentSplit) ProtoMessage()    {}
+func (*PaymentSplit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{43}
+}
+
+func (m *PaymentSplit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PaymentSplit.Unmarshal(m, b)
+}
+func (m *PaymentSplit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PaymentSplit.Marshal(b, m, deterministic)
+}
+func (m *PaymentSplit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PaymentSplit.Merge(m, src)
+}
+func (m *PaymentSplit) XXX_Size() int {
+	return xxx_messageInfo_PaymentSplit.Size(m)
+}
+func (m *PaymentSplit) XXX_DiscardUnknown() {
+	xxx_messageInfo_PaymentSplit.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PaymentSplit proto.InternalMessageInfo
+
+func (m *PaymentSplit) GetCreditCard() *CreditCardInfo {
+	if m != nil {
+		return m.CreditCard
+	}
+	return nil
+}
+
+func (m *PaymentSplit) GetAmount() *Money {
+	if m != nil {
+		return m.Amount
+	}
+	return nil
+}
+
 type PlaceOrderResponse struct {
 	Order                *OrderResult `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
@@ -1390,7 +2633,7 @@ func (m *PlaceOrderResponse) Reset()         { *m = PlaceOrderResponse{} }
 func (m *PlaceOrderResponse) String() string { return proto.CompactTextString(m) }
 func (*PlaceOrderResponse) ProtoMessage()    {}
 func (*PlaceOrderResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{28}
+	return fileDescriptor_ca53982754088a9d, []int{44}
 }
 
 func (m *PlaceOrderResponse) XXX_Unmarshal(b []byte) error {
@@ -1418,6 +2661,217 @@ func (m *PlaceOrderResponse) GetOrder() *OrderResult {
 	return nil
 }
 
+type SimulateOrderResponse struct {
+	// The order as it would have been placed, without order id, number nor
+	// shipping tracking ids.
+	Order *OrderResult `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	// Amount that would have been charged.
+	Total                *Money   `protobuf:"bytes,2,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SimulateOrderResponse) Reset()         { *m = SimulateOrderResponse{} }
+func (m *SimulateOrderResponse) String() string { return proto.CompactTextString(m) }
+func (*SimulateOrderResponse) ProtoMessage()    {}
+func (*SimulateOrderResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{45}
+}
+
+func (m *SimulateOrderResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SimulateOrderResponse.Unmarshal(m, b)
+}
+func (m *SimulateOrderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SimulateOrderResponse.Marshal(b, m, deterministic)
+}
+func (m *SimulateOrderResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SimulateOrderResponse.Merge(m, src)
+}
+func (m *SimulateOrderResponse) XXX_Size() int {
+	return xxx_messageInfo_SimulateOrderResponse.Size(m)
+}
+func (m *SimulateOrderResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SimulateOrderResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SimulateOrderResponse proto.InternalMessageInfo
+
+func (m *SimulateOrderResponse) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+func (m *SimulateOrderResponse) GetTotal() *Money {
+	if m != nil {
+		return m.Total
+	}
+	return nil
+}
+
+type SubmitOrderResponse struct {
+	OrderId              string      `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status               OrderStatus `protobuf:"varint,2,opt,name=status,proto3,enum=hipstershop.OrderStatus" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *SubmitOrderResponse) Reset()         { *m = SubmitOrderResponse{} }
+func (m *SubmitOrderResponse) String() string { return proto.CompactTextString(m) }
+func (*SubmitOrderResponse) ProtoMessage()    {}
+func (*SubmitOrderResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{46}
+}
+
+func (m *SubmitOrderResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubmitOrderResponse.Unmarshal(m, b)
+}
+func (m *SubmitOrderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubmitOrderResponse.Marshal(b, m, deterministic)
+}
+func (m *SubmitOrderResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubmitOrderResponse.Merge(m, src)
+}
+func (m *SubmitOrderResponse) XXX_Size() int {
+	return xxx_messageInfo_SubmitOrderResponse.Size(m)
+}
+func (m *SubmitOrderResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubmitOrderResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubmitOrderResponse proto.InternalMessageInfo
+
+func (m *SubmitOrderResponse) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+func (m *SubmitOrderResponse) GetStatus() OrderStatus {
+	if m != nil {
+		return m.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+type GetOrderStatusRequest struct {
+	OrderId              string   `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetOrderStatusRequest) Reset()         { *m = GetOrderStatusRequest{} }
+func (m *GetOrderStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*GetOrderStatusRequest) ProtoMessage()    {}
+func (*GetOrderStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{47}
+}
+
+func (m *GetOrderStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetOrderStatusRequest.Unmarshal(m, b)
+}
+func (m *GetOrderStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetOrderStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *GetOrderStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetOrderStatusRequest.Merge(m, src)
+}
+func (m *GetOrderStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_GetOrderStatusRequest.Size(m)
+}
+func (m *GetOrderStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetOrderStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetOrderStatusRequest proto.InternalMessageInfo
+
+func (m *GetOrderStatusRequest) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+type GetOrderStatusResponse struct {
+	OrderId string      `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status  OrderStatus `protobuf:"varint,2,opt,name=status,proto3,enum=hipstershop.OrderStatus" json:"status,omitempty"`
+	// Set once the order is completed.
+	Order *OrderResult `protobuf:"bytes,3,opt,name=order,proto3" json:"order,omitempty"`
+	// Reason of the failure when the order failed.
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// Set, with only the order_id, when the request carried the ETag of the
+	// current state of the order in its if-none-match metadata.
+	NotModified          bool     `protobuf:"varint,5,opt,name=not_modified,json=notModified,proto3" json:"not_modified,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetOrderStatusResponse) Reset()         { *m = GetOrderStatusResponse{} }
+func (m *GetOrderStatusResponse) String() string { return proto.CompactTextString(m) }
+func (*GetOrderStatusResponse) ProtoMessage()    {}
+func (*GetOrderStatusResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{48}
+}
+
+func (m *GetOrderStatusResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetOrderStatusResponse.Unmarshal(m, b)
+}
+func (m *GetOrderStatusResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetOrderStatusResponse.Marshal(b, m, deterministic)
+}
+func (m *GetOrderStatusResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetOrderStatusResponse.Merge(m, src)
+}
+func (m *GetOrderStatusResponse) XXX_Size() int {
+	return xxx_messageInfo_GetOrderStatusResponse.Size(m)
+}
+func (m *GetOrderStatusResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetOrderStatusResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetOrderStatusResponse proto.InternalMessageInfo
+
+func (m *GetOrderStatusResponse) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+func (m *GetOrderStatusResponse) GetStatus() OrderStatus {
+	if m != nil {
+		return m.Status
+	}
+	return OrderStatus_ORDER_STATUS_UNSPECIFIED
+}
+
+func (m *GetOrderStatusResponse) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+func (m *GetOrderStatusResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *GetOrderStatusResponse) GetNotModified() bool {
+	if m != nil {
+		return m.NotModified
+	}
+	return false
+}
+
 type AdRequest struct {
 	// List of important key words from the current page describing the context.
 	ContextKeys          []string `protobuf:"bytes,1,rep,name=context_keys,json=contextKeys,proto3" json:"context_keys,omitempty"`
@@ -1430,7 +2884,7 @@ func (m *AdRequest) Reset()         { *m = AdRequest{} }
 func (m *AdRequest) String() string { return proto.CompactTextString(m) }
 func (*AdRequest) ProtoMessage()    {}
 func (*AdRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{29}
+	return fileDescriptor_ca53982754088a9d, []int{49}
 }
 
 func (m *AdRequest) XXX_Unmarshal(b []byte) error {
@@ -1469,7 +2923,7 @@ func (m *AdResponse) Reset()         { *m = AdResponse{} }
 func (m *AdResponse) String() string { return proto.CompactTextString(m) }
 func (*AdResponse) ProtoMessage()    {}
 func (*AdResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{30}
+	return fileDescriptor_ca53982754088a9d, []int{50}
 }
 
 func (m *AdResponse) XXX_Unmarshal(b []byte) error {
@@ -1511,7 +2965,7 @@ func (m *Ad) Reset()         { *m = Ad{} }
 func (m *Ad) String() string { return proto.CompactTextString(m) }
 func (*Ad) ProtoMessage()    {}
 func (*Ad) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{31}
+	return fileDescriptor_ca53982754088a9d, []int{51}
 }
 
 func (m *Ad) XXX_Unmarshal(b []byte) error {
@@ -1547,6 +3001,9 @@ func (m *Ad) GetText() string {
 }
 
 func init() {
+	proto.RegisterEnum("hipstershop.OrderStatus", OrderStatus_name, OrderStatus_value)
+	proto.RegisterEnum("hipstershop.ItemDetail", ItemDetail_name, ItemDetail_value)
+	proto.RegisterEnum("hipstershop.ConfirmationChannel", ConfirmationChannel_name, ConfirmationChannel_value)
 	proto.RegisterType((*CartItem)(nil), "hipstershop.CartItem")
 	proto.RegisterType((*AddItemRequest)(nil), "hipstershop.AddItemRequest")
 	proto.RegisterType((*EmptyCartRequest)(nil), "hipstershop.EmptyCartRequest")
@@ -1560,6 +3017,9 @@ func init() {
 	proto.RegisterType((*GetProductRequest)(nil), "hipstershop.GetProductRequest")
 	proto.RegisterType((*SearchProductsRequest)(nil), "hipstershop.SearchProductsRequest")
 	proto.RegisterType((*SearchProductsResponse)(nil), "hipstershop.SearchProductsResponse")
+	proto.RegisterType((*CheckAvailabilityRequest)(nil), "hipstershop.CheckAvailabilityRequest")
+	proto.RegisterType((*CheckAvailabilityResponse)(nil), "hipstershop.CheckAvailabilityResponse")
+	proto.RegisterMapType((map[string]string)(nil), "hipstershop.CheckAvailabilityResponse.RestockDatesEntry")
 	proto.RegisterType((*GetQuoteRequest)(nil), "hipstershop.GetQuoteRequest")
 	proto.RegisterType((*GetQuoteResponse)(nil), "hipstershop.GetQuoteResponse")
 	proto.RegisterType((*ShipOrderRequest)(nil), "hipstershop.ShipOrderRequest")
@@ -1568,14 +3028,35 @@ func init() {
 	proto.RegisterType((*Money)(nil), "hipstershop.Money")
 	proto.RegisterType((*GetSupportedCurrenciesResponse)(nil), "hipstershop.GetSupportedCurrenciesResponse")
 	proto.RegisterType((*CurrencyConversionRequest)(nil), "hipstershop.CurrencyConversionRequest")
+	proto.RegisterType((*CurrencyConversionBatchRequest)(nil), "hipstershop.CurrencyConversionBatchRequest")
+	proto.RegisterType((*CurrencyConversionBatchResponse)(nil), "hipstershop.CurrencyConversionBatchResponse")
 	proto.RegisterType((*CreditCardInfo)(nil), "hipstershop.CreditCardInfo")
 	proto.RegisterType((*ChargeRequest)(nil), "hipstershop.ChargeRequest")
 	proto.RegisterType((*ChargeResponse)(nil), "hipstershop.ChargeResponse")
+	proto.RegisterType((*CaptureRequest)(nil), "hipstershop.CaptureRequest")
+	proto.RegisterType((*CaptureResponse)(nil), "hipstershop.CaptureResponse")
+	proto.RegisterType((*VoidRequest)(nil), "hipstershop.VoidRequest")
+	proto.RegisterType((*VoidResponse)(nil), "hipstershop.VoidResponse")
 	proto.RegisterType((*OrderItem)(nil), "hipstershop.OrderItem")
 	proto.RegisterType((*OrderResult)(nil), "hipstershop.OrderResult")
+	proto.RegisterMapType((map[string]string)(nil), "hipstershop.OrderResult.MetadataEntry")
+	proto.RegisterType((*AppliedPromotion)(nil), "hipstershop.AppliedPromotion")
+	proto.RegisterType((*ConversionRecord)(nil), "hipstershop.ConversionRecord")
 	proto.RegisterType((*SendOrderConfirmationRequest)(nil), "hipstershop.SendOrderConfirmationRequest")
+	proto.RegisterType((*EmailAttachment)(nil), "hipstershop.EmailAttachment")
+	proto.RegisterType((*SendSmsConfirmationRequest)(nil), "hipstershop.SendSmsConfirmationRequest")
+	proto.RegisterType((*GetPointsBalanceRequest)(nil), "hipstershop.GetPointsBalanceRequest")
+	proto.RegisterType((*PointsBalance)(nil), "hipstershop.PointsBalance")
+	proto.RegisterType((*RedeemPointsRequest)(nil), "hipstershop.RedeemPointsRequest")
 	proto.RegisterType((*PlaceOrderRequest)(nil), "hipstershop.PlaceOrderRequest")
+	proto.RegisterMapType((map[string]string)(nil), "hipstershop.PlaceOrderRequest.ItemNotesEntry")
+	proto.RegisterMapType((map[string]string)(nil), "hipstershop.PlaceOrderRequest.MetadataEntry")
+	proto.RegisterType((*PaymentSplit)(nil), "hipstershop.PaymentSplit")
 	proto.RegisterType((*PlaceOrderResponse)(nil), "hipstershop.PlaceOrderResponse")
+	proto.RegisterType((*SimulateOrderResponse)(nil), "hipstershop.SimulateOrderResponse")
+	proto.RegisterType((*SubmitOrderResponse)(nil), "hipstershop.SubmitOrderResponse")
+	proto.RegisterType((*GetOrderStatusRequest)(nil), "hipstershop.GetOrderStatusRequest")
+	proto.RegisterType((*GetOrderStatusResponse)(nil), "hipstershop.GetOrderStatusResponse")
 	proto.RegisterType((*AdRequest)(nil), "hipstershop.AdRequest")
 	proto.RegisterType((*AdResponse)(nil), "hipstershop.AdResponse")
 	proto.RegisterType((*Ad)(nil), "hipstershop.Ad")
@@ -1790,6 +3271,8 @@ type ProductCatalogServiceClient interface {
 	ListProducts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListProductsResponse, error)
 	GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*Product, error)
 	SearchProducts(ctx context.Context, in *SearchProductsRequest, opts ...grpc.CallOption) (*SearchProductsResponse, error)
+	// Reports which of the given products cannot currently be ordered.
+	CheckAvailability(ctx context.Context, in *CheckAvailabilityRequest, opts ...grpc.CallOption) (*CheckAvailabilityResponse, error)
 }
 
 type productCatalogServiceClient struct {
@@ -1827,11 +3310,22 @@ func (c *productCatalogServiceClient) SearchProducts(ctx context.Context, in *Se
 	return out, nil
 }
 
+func (c *productCatalogServiceClient) CheckAvailability(ctx context.Context, in *CheckAvailabilityRequest, opts ...grpc.CallOption) (*CheckAvailabilityResponse, error) {
+	out := new(CheckAvailabilityResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.ProductCatalogService/CheckAvailability", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProductCatalogServiceServer is the server API for ProductCatalogService service.
 type ProductCatalogServiceServer interface {
 	ListProducts(context.Context, *Empty) (*ListProductsResponse, error)
 	GetProduct(context.Context, *GetProductRequest) (*Product, error)
 	SearchProducts(context.Context, *SearchProductsRequest) (*SearchProductsResponse, error)
+	// Reports which of the given products cannot currently be ordered.
+	CheckAvailability(context.Context, *CheckAvailabilityRequest) (*CheckAvailabilityResponse, error)
 }
 
 func RegisterProductCatalogServiceServer(s *grpc.Server, srv ProductCatalogServiceServer) {
@@ -1892,6 +3386,24 @@ func _ProductCatalogService_SearchProducts_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProductCatalogService_CheckAvailability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckAvailabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductCatalogServiceServer).CheckAvailability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.ProductCatalogService/CheckAvailability",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductCatalogServiceServer).CheckAvailability(ctx, req.(*CheckAvailabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ProductCatalogService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.ProductCatalogService",
 	HandlerType: (*ProductCatalogServiceServer)(nil),
@@ -1908,6 +3420,10 @@ var _ProductCatalogService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SearchProducts",
 			Handler:    _ProductCatalogService_SearchProducts_Handler,
 		},
+		{
+			MethodName: "CheckAvailability",
+			Handler:    _ProductCatalogService_CheckAvailability_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -2016,6 +3532,9 @@ var _ShippingService_serviceDesc = grpc.ServiceDesc{
 type CurrencyServiceClient interface {
 	GetSupportedCurrencies(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetSupportedCurrenciesResponse, error)
 	Convert(ctx context.Context, in *CurrencyConversionRequest, opts ...grpc.CallOption) (*Money, error)
+	// Converts several amounts to the same currency at once. Results are
+	// returned in the order of the request.
+	ConvertBatch(ctx context.Context, in *CurrencyConversionBatchRequest, opts ...grpc.CallOption) (*CurrencyConversionBatchResponse, error)
 }
 
 type currencyServiceClient struct {
@@ -2044,10 +3563,22 @@ func (c *currencyServiceClient) Convert(ctx context.Context, in *CurrencyConvers
 	return out, nil
 }
 
+func (c *currencyServiceClient) ConvertBatch(ctx context.Context, in *CurrencyConversionBatchRequest, opts ...grpc.CallOption) (*CurrencyConversionBatchResponse, error) {
+	out := new(CurrencyConversionBatchResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.CurrencyService/ConvertBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CurrencyServiceServer is the server API for CurrencyService service.
 type CurrencyServiceServer interface {
 	GetSupportedCurrencies(context.Context, *Empty) (*GetSupportedCurrenciesResponse, error)
 	Convert(context.Context, *CurrencyConversionRequest) (*Money, error)
+	// Converts several amounts to the same currency at once. Results are
+	// returned in the order of the request.
+	ConvertBatch(context.Context, *CurrencyConversionBatchRequest) (*CurrencyConversionBatchResponse, error)
 }
 
 func RegisterCurrencyServiceServer(s *grpc.Server, srv CurrencyServiceServer) {
@@ -2090,6 +3621,24 @@ func _CurrencyService_Convert_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CurrencyService_ConvertBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CurrencyConversionBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CurrencyServiceServer).ConvertBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CurrencyService/ConvertBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CurrencyServiceServer).ConvertBatch(ctx, req.(*CurrencyConversionBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CurrencyService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.CurrencyService",
 	HandlerType: (*CurrencyServiceServer)(nil),
@@ -2102,6 +3651,10 @@ var _CurrencyService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Convert",
 			Handler:    _CurrencyService_Convert_Handler,
 		},
+		{
+			MethodName: "ConvertBatch",
+			Handler:    _CurrencyService_ConvertBatch_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -2112,6 +3665,10 @@ var _CurrencyService_serviceDesc = grpc.ServiceDesc{
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type PaymentServiceClient interface {
 	Charge(ctx context.Context, in *ChargeRequest, opts ...grpc.CallOption) (*ChargeResponse, error)
+	// Captures the amount held by an authorize_only charge.
+	Capture(ctx context.Context, in *CaptureRequest, opts ...grpc.CallOption) (*CaptureResponse, error)
+	// Releases the amount held by an authorize_only charge.
+	Void(ctx context.Context, in *VoidRequest, opts ...grpc.CallOption) (*VoidResponse, error)
 }
 
 type paymentServiceClient struct {
@@ -2131,9 +3688,31 @@ func (c *paymentServiceClient) Charge(ctx context.Context, in *ChargeRequest, op
 	return out, nil
 }
 
+func (c *paymentServiceClient) Capture(ctx context.Context, in *CaptureRequest, opts ...grpc.CallOption) (*CaptureResponse, error) {
+	out := new(CaptureResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.PaymentService/Capture", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) Void(ctx context.Context, in *VoidRequest, opts ...grpc.CallOption) (*VoidResponse, error) {
+	out := new(VoidResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.PaymentService/Void", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PaymentServiceServer is the server API for PaymentService service.
 type PaymentServiceServer interface {
 	Charge(context.Context, *ChargeRequest) (*ChargeResponse, error)
+	// Captures the amount held by an authorize_only charge.
+	Capture(context.Context, *CaptureRequest) (*CaptureResponse, error)
+	// Releases the amount held by an authorize_only charge.
+	Void(context.Context, *VoidRequest) (*VoidResponse, error)
 }
 
 func RegisterPaymentServiceServer(s *grpc.Server, srv PaymentServiceServer) {
@@ -2158,6 +3737,42 @@ func _PaymentService_Charge_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PaymentService_Capture_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CaptureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).Capture(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.PaymentService/Capture",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).Capture(ctx, req.(*CaptureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_Void_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).Void(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.PaymentService/Void",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).Void(ctx, req.(*VoidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _PaymentService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.PaymentService",
 	HandlerType: (*PaymentServiceServer)(nil),
@@ -2166,6 +3781,14 @@ var _PaymentService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Charge",
 			Handler:    _PaymentService_Charge_Handler,
 		},
+		{
+			MethodName: "Capture",
+			Handler:    _PaymentService_Capture_Handler,
+		},
+		{
+			MethodName: "Void",
+			Handler:    _PaymentService_Void_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -2235,11 +3858,180 @@ var _EmailService_serviceDesc = grpc.ServiceDesc{
 	Metadata: "demo.proto",
 }
 
+// SmsServiceClient is the client API for SmsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SmsServiceClient interface {
+	SendOrderConfirmation(ctx context.Context, in *SendSmsConfirmationRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type smsServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSmsServiceClient(cc *grpc.ClientConn) SmsServiceClient {
+	return &smsServiceClient{cc}
+}
+
+func (c *smsServiceClient) SendOrderConfirmation(ctx context.Context, in *SendSmsConfirmationRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/hipstershop.SmsService/SendOrderConfirmation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SmsServiceServer is the server API for SmsService service.
+type SmsServiceServer interface {
+	SendOrderConfirmation(context.Context, *SendSmsConfirmationRequest) (*Empty, error)
+}
+
+func RegisterSmsServiceServer(s *grpc.Server, srv SmsServiceServer) {
+	s.RegisterService(&_SmsService_serviceDesc, srv)
+}
+
+func _SmsService_SendOrderConfirmation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendSmsConfirmationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmsServiceServer).SendOrderConfirmation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.SmsService/SendOrderConfirmation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmsServiceServer).SendOrderConfirmation(ctx, req.(*SendSmsConfirmationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SmsService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hipstershop.SmsService",
+	HandlerType: (*SmsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SendOrderConfirmation",
+			Handler:    _SmsService_SendOrderConfirmation_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "demo.proto",
+}
+
+// LoyaltyServiceClient is the client API for LoyaltyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type LoyaltyServiceClient interface {
+	GetPointsBalance(ctx context.Context, in *GetPointsBalanceRequest, opts ...grpc.CallOption) (*PointsBalance, error)
+	RedeemPoints(ctx context.Context, in *RedeemPointsRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type loyaltyServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewLoyaltyServiceClient(cc *grpc.ClientConn) LoyaltyServiceClient {
+	return &loyaltyServiceClient{cc}
+}
+
+func (c *loyaltyServiceClient) GetPointsBalance(ctx context.Context, in *GetPointsBalanceRequest, opts ...grpc.CallOption) (*PointsBalance, error) {
+	out := new(PointsBalance)
+	err := c.cc.Invoke(ctx, "/hipstershop.LoyaltyService/GetPointsBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loyaltyServiceClient) RedeemPoints(ctx context.Context, in *RedeemPointsRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/hipstershop.LoyaltyService/RedeemPoints", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LoyaltyServiceServer is the server API for LoyaltyService service.
+type LoyaltyServiceServer interface {
+	GetPointsBalance(context.Context, *GetPointsBalanceRequest) (*PointsBalance, error)
+	RedeemPoints(context.Context, *RedeemPointsRequest) (*Empty, error)
+}
+
+func RegisterLoyaltyServiceServer(s *grpc.Server, srv LoyaltyServiceServer) {
+	s.RegisterService(&_LoyaltyService_serviceDesc, srv)
+}
+
+func _LoyaltyService_GetPointsBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPointsBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoyaltyServiceServer).GetPointsBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.LoyaltyService/GetPointsBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoyaltyServiceServer).GetPointsBalance(ctx, req.(*GetPointsBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoyaltyService_RedeemPoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemPointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoyaltyServiceServer).RedeemPoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.LoyaltyService/RedeemPoints",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoyaltyServiceServer).RedeemPoints(ctx, req.(*RedeemPointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _LoyaltyService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hipstershop.LoyaltyService",
+	HandlerType: (*LoyaltyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPointsBalance",
+			Handler:    _LoyaltyService_GetPointsBalance_Handler,
+		},
+		{
+			MethodName: "RedeemPoints",
+			Handler:    _LoyaltyService_RedeemPoints_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "demo.proto",
+}
+
 // CheckoutServiceClient is the client API for CheckoutService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type CheckoutServiceClient interface {
 	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error)
+	// SubmitOrder validates and enqueues an order for asynchronous placement.
+	// Use GetOrderStatus to follow its progression.
+	SubmitOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error)
+	GetOrderStatus(ctx context.Context, in *GetOrderStatusRequest, opts ...grpc.CallOption) (*GetOrderStatusResponse, error)
+	// SimulateOrder prices an order like PlaceOrder, reading from the same
+	// downstream services, but neither charges, ships, sends a confirmation
+	// nor empties the cart. Meant for load testing.
+	SimulateOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*SimulateOrderResponse, error)
 }
 
 type checkoutServiceClient struct {
@@ -2259,9 +4051,44 @@ func (c *checkoutServiceClient) PlaceOrder(ctx context.Context, in *PlaceOrderRe
 	return out, nil
 }
 
+func (c *checkoutServiceClient) SubmitOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error) {
+	out := new(SubmitOrderResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.CheckoutService/SubmitOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkoutServiceClient) GetOrderStatus(ctx context.Context, in *GetOrderStatusRequest, opts ...grpc.CallOption) (*GetOrderStatusResponse, error) {
+	out := new(GetOrderStatusResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.CheckoutService/GetOrderStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkoutServiceClient) SimulateOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*SimulateOrderResponse, error) {
+	out := new(SimulateOrderResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.CheckoutService/SimulateOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CheckoutServiceServer is the server API for CheckoutService service.
 type CheckoutServiceServer interface {
 	PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error)
+	// SubmitOrder validates and enqueues an order for asynchronous placement.
+	// Use GetOrderStatus to follow its progression.
+	SubmitOrder(context.Context, *PlaceOrderRequest) (*SubmitOrderResponse, error)
+	GetOrderStatus(context.Context, *GetOrderStatusRequest) (*GetOrderStatusResponse, error)
+	// SimulateOrder prices an order like PlaceOrder, reading from the same
+	// downstream services, but neither charges, ships, sends a confirmation
+	// nor empties the cart. Meant for load testing.
+	SimulateOrder(context.Context, *PlaceOrderRequest) (*SimulateOrderResponse, error)
 }
 
 func RegisterCheckoutServiceServer(s *grpc.Server, srv CheckoutServiceServer) {
@@ -2286,6 +4113,60 @@ func _CheckoutService_PlaceOrder_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CheckoutService_SubmitOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckoutServiceServer).SubmitOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CheckoutService/SubmitOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckoutServiceServer).SubmitOrder(ctx, req.(*PlaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CheckoutService_GetOrderStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckoutServiceServer).GetOrderStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CheckoutService/GetOrderStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckoutServiceServer).GetOrderStatus(ctx, req.(*GetOrderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CheckoutService_SimulateOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckoutServiceServer).SimulateOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CheckoutService/SimulateOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckoutServiceServer).SimulateOrder(ctx, req.(*PlaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CheckoutService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.CheckoutService",
 	HandlerType: (*CheckoutServiceServer)(nil),
@@ -2294,6 +4175,18 @@ var _CheckoutService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PlaceOrder",
 			Handler:    _CheckoutService_PlaceOrder_Handler,
 		},
+		{
+			MethodName: "SubmitOrder",
+			Handler:    _CheckoutService_SubmitOrder_Handler,
+		},
+		{
+			MethodName: "GetOrderStatus",
+			Handler:    _CheckoutService_GetOrderStatus_Handler,
+		},
+		{
+			MethodName: "SimulateOrder",
+			Handler:    _CheckoutService_SimulateOrder_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -2366,99 +4259,201 @@ var _AdService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("demo.proto", fileDescriptor_ca53982754088a9d) }
 
 var fileDescriptor_ca53982754088a9d = []byte{
-	// 1500 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xef, 0x72, 0x13, 0xb7,
-	0x16, 0xcf, 0x26, 0xb1, 0x1d, 0x1f, 0xc7, 0x4e, 0xa2, 0x9b, 0x04, 0xb3, 0x81, 0x10, 0x94, 0x81,
-	0x0b, 0x17, 0x08, 0x4c, 0xee, 0x9d, 0xe1, 0x03, 0xdc, 0xd2, 0x8c, 0xc9, 0x18, 0x4f, 0xa1, 0xd0,
-	0x0d, 0xe9, 0xd0, 0xa1, 0x53, 0xcf, 0xb2, 0x12, 0xf1, 0x96, 0xec, 0x6a, 0x91, 0xb4, 0x19, 0xcc,
-	0xc7, 0xf6, 0x01, 0xfa, 0x1e, 0x7d, 0x81, 0xce, 0xf4, 0x11, 0xfa, 0xbd, 0xaf, 0xd0, 0xe7, 0xe8,
-	0x48, 0xbb, 0xda, 0x7f, 0xb1, 0x13, 0xf8, 0xd2, 0x6f, 0xab, 0xa3, 0x9f, 0xce, 0xf9, 0xe9, 0xe8,
-	0xfc, 0xb3, 0x01, 0x08, 0x0d, 0xd8, 0x4e, 0xc4, 0x99, 0x64, 0xa8, 0x35, 0xf2, 0x23, 0x21, 0x29,
-	0x17, 0x23, 0x16, 0xe1, 0x7d, 0x58, 0xe8, 0xb9, 0x5c, 0x0e, 0x24, 0x0d, 0xd0, 0x65, 0x80, 0x88,
-	0x33, 0x12, 0x7b, 0x72, 0xe8, 0x93, 0xae, 0xb5, 0x65, 0xdd, 0x68, 0x3a, 0xcd, 0x54, 0x32, 0x20,
-	0xc8, 0x86, 0x85, 0xf7, 0xb1, 0x1b, 0x4a, 0x5f, 0x8e, 0xbb, 0xb3, 0x5b, 0xd6, 0x8d, 0x9a, 0x93,
-	0xad, 0xf1, 0x4b, 0xe8, 0xec, 0x11, 0xa2, 0xb4, 0x38, 0xf4, 0x7d, 0x4c, 0x85, 0x44, 0x17, 0xa0,
-	0x11, 0x0b, 0xca, 0x73, 0x4d, 0x75, 0xb5, 0x1c, 0x10, 0x74, 0x13, 0xe6, 0x7d, 0x49, 0x03, 0xad,
-	0xa2, 0xb5, 0xbb, 0xb6, 0x53, 0x60, 0xb3, 0x63, 0xa8, 0x38, 0x1a, 0x82, 0x6f, 0xc1, 0xf2, 0x7e,
-	0x10, 0xc9, 0xb1, 0x12, 0x9f, 0xa7, 0x17, 0xdf, 0x84, 0x4e, 0x9f, 0xca, 0x4f, 0x82, 0x3e, 0x85,
-	0x79, 0x85, 0x9b, 0xce, 0xf1, 0x16, 0xd4, 0x14, 0x01, 0xd1, 0x9d, 0xdd, 0x9a, 0x9b, 0x4e, 0x32,
-	0xc1, 0xe0, 0x06, 0xd4, 0x34, 0x4b, 0xfc, 0x2d, 0xd8, 0x4f, 0x7d, 0x21, 0x1d, 0xea, 0xb1, 0x20,
-	0xa0, 0x21, 0x71, 0xa5, 0xcf, 0x42, 0x71, 0xae, 0x43, 0xae, 0x40, 0x2b, 0x77, 0x7b, 0x62, 0xb2,
-	0xe9, 0x40, 0xe6, 0x77, 0x81, 0xbf, 0x80, 0x8d, 0x89, 0x7a, 0x45, 0xc4, 0x42, 0x41, 0xab, 0xe7,
-	0xad, 0x53, 0xe7, 0x7f, 0xb7, 0xa0, 0xf1, 0x22, 0x59, 0xa2, 0x0e, 0xcc, 0x66, 0x04, 0x66, 0x7d,
-	0x82, 0x10, 0xcc, 0x87, 0x6e, 0x40, 0xf5, 0x6b, 0x34, 0x1d, 0xfd, 0x8d, 0xb6, 0xa0, 0x45, 0xa8,
-	0xf0, 0xb8, 0x1f, 0x29, 0x43, 0xdd, 0x39, 0xbd, 0x55, 0x14, 0xa1, 0x2e, 0x34, 0x22, 0xdf, 0x93,
-	0x31, 0xa7, 0xdd, 0x79, 0xbd, 0x6b, 0x96, 0xe8, 0x2e, 0x34, 0x23, 0xee, 0x7b, 0x74, 0x18, 0x0b,
-	0xd2, 0xad, 0xe9, 0x27, 0x46, 0x25, 0xef, 0x3d, 0x63, 0x21, 0x1d, 0x3b, 0x0b, 0x1a, 0x74, 0x28,
-	0x08, 0xda, 0x04, 0xf0, 0x5c, 0x49, 0x8f, 0x18, 0xf7, 0xa9, 0xe8, 0xd6, 0x13, 0xf2, 0xb9, 0x04,
-	0x3f, 0x81, 0x55, 0x75, 0xf9, 0x94, 0x7f, 0x7e, 0xeb, 0x7b, 0xb0, 0x90, 0x5e, 0x31, 0xb9, 0x72,
-	0x6b, 0x77, 0xb5, 0x64, 0x27, 0x3d, 0xe0, 0x64, 0x28, 0xbc, 0x0d, 0x2b, 0x7d, 0x6a, 0x14, 0x99,
-	0x57, 0xa9, 0xf8, 0x03, 0xdf, 0x81, 0xb5, 0x03, 0xea, 0x72, 0x6f, 0x94, 0x1b, 0x4c, 0x80, 0xab,
-	0x50, 0x7b, 0x1f, 0x53, 0x3e, 0x4e, 0xb1, 0xc9, 0x02, 0x3f, 0x81, 0xf5, 0x2a, 0x3c, 0xe5, 0xb7,
-	0x03, 0x0d, 0x4e, 0x45, 0x7c, 0x7c, 0x0e, 0x3d, 0x03, 0xc2, 0x21, 0x2c, 0xf5, 0xa9, 0xfc, 0x26,
-	0x66, 0x92, 0x1a, 0x93, 0x3b, 0xd0, 0x70, 0x09, 0xe1, 0x54, 0x08, 0x6d, 0xb4, 0xaa, 0x62, 0x2f,
-	0xd9, 0x73, 0x0c, 0xe8, 0xf3, 0xa2, 0x76, 0x0f, 0x96, 0x73, 0x7b, 0x29, 0xe7, 0x3b, 0xb0, 0xe0,
-	0x31, 0x21, 0xf5, 0xdb, 0x59, 0x53, 0xdf, 0xae, 0xa1, 0x30, 0x87, 0x82, 0x60, 0x06, 0xcb, 0x07,
-	0x23, 0x3f, 0x7a, 0xce, 0x09, 0xe5, 0xff, 0x08, 0xe7, 0xff, 0xc1, 0x4a, 0xc1, 0x60, 0x1e, 0xfe,
-	0x92, 0xbb, 0xde, 0x3b, 0x3f, 0x3c, 0xca, 0x73, 0x0b, 0x8c, 0x68, 0x40, 0xf0, 0x2f, 0x16, 0x34,
-	0x52, 0xbb, 0xe8, 0x1a, 0x74, 0x84, 0xe4, 0x94, 0xca, 0x61, 0x91, 0x65, 0xd3, 0x69, 0x27, 0x52,
-	0x03, 0x43, 0x30, 0xef, 0x99, 0x32, 0xd7, 0x74, 0xf4, 0xb7, 0x0a, 0x00, 0x21, 0x5d, 0x49, 0xd3,
-	0x7c, 0x48, 0x16, 0x2a, 0x13, 0x3c, 0x16, 0x87, 0x92, 0x8f, 0x4d, 0x26, 0xa4, 0x4b, 0x74, 0x11,
-	0x16, 0x3e, 0xfa, 0xd1, 0xd0, 0x63, 0x84, 0xea, 0x44, 0xa8, 0x39, 0x8d, 0x8f, 0x7e, 0xd4, 0x63,
-	0x84, 0xe2, 0x57, 0x50, 0xd3, 0xae, 0x44, 0xdb, 0xd0, 0xf6, 0x62, 0xce, 0x69, 0xe8, 0x8d, 0x13,
-	0x60, 0xc2, 0x66, 0xd1, 0x08, 0x15, 0x5a, 0x19, 0x8e, 0x43, 0x5f, 0x0a, 0xcd, 0x66, 0xce, 0x49,
-	0x16, 0x4a, 0x1a, 0xba, 0x21, 0x13, 0x9a, 0x4e, 0xcd, 0x49, 0x16, 0xb8, 0x0f, 0x9b, 0x7d, 0x2a,
-	0x0f, 0xe2, 0x28, 0x62, 0x5c, 0x52, 0xd2, 0x4b, 0xf4, 0xf8, 0x34, 0x8f, 0xcb, 0x6b, 0xd0, 0x29,
-	0x99, 0x34, 0x05, 0xa3, 0x5d, 0xb4, 0x29, 0xf0, 0xf7, 0x70, 0xb1, 0x97, 0x09, 0xc2, 0x13, 0xca,
-	0x85, 0xcf, 0x42, 0xf3, 0xc8, 0xd7, 0x61, 0xfe, 0x2d, 0x67, 0xc1, 0x19, 0x31, 0xa2, 0xf7, 0x55,
-	0xc9, 0x93, 0x2c, 0xb9, 0x58, 0xe2, 0xc9, 0xba, 0x64, 0xda, 0x01, 0x7f, 0x59, 0xd0, 0xe9, 0x71,
-	0x4a, 0x7c, 0x55, 0xaf, 0xc9, 0x20, 0x7c, 0xcb, 0xd0, 0x6d, 0x40, 0x9e, 0x96, 0x0c, 0x3d, 0x97,
-	0x93, 0x61, 0x18, 0x07, 0x6f, 0x28, 0x4f, 0xfd, 0xb1, 0xec, 0x65, 0xd8, 0xaf, 0xb5, 0x1c, 0x5d,
-	0x87, 0xa5, 0x22, 0xda, 0x3b, 0x39, 0x49, 0x5b, 0x52, 0x3b, 0x87, 0xf6, 0x4e, 0x4e, 0xd0, 0xff,
-	0x61, 0xa3, 0x88, 0xa3, 0x1f, 0x22, 0x9f, 0xeb, 0xf2, 0x39, 0x1c, 0x53, 0x97, 0xa7, 0xbe, 0xeb,
-	0xe6, 0x67, 0xf6, 0x33, 0xc0, 0x77, 0xd4, 0xe5, 0xe8, 0x11, 0x5c, 0x9a, 0x72, 0x3c, 0x60, 0xa1,
-	0x1c, 0xe9, 0x27, 0xaf, 0x39, 0x17, 0x27, 0x9d, 0x7f, 0xa6, 0x00, 0x78, 0x0c, 0xed, 0xde, 0xc8,
-	0xe5, 0x47, 0x59, 0x4e, 0xff, 0x07, 0xea, 0x6e, 0xa0, 0x22, 0xe4, 0x0c, 0xe7, 0xa5, 0x08, 0xf4,
-	0x10, 0x5a, 0x05, 0xeb, 0x69, 0xc3, 0xdc, 0x28, 0x67, 0x48, 0xc9, 0x89, 0x0e, 0xe4, 0x4c, 0xf0,
-	0x7d, 0xe8, 0x18, 0xd3, 0xf9, 0xd3, 0x4b, 0xee, 0x86, 0xc2, 0xf5, 0xf4, 0x15, 0xb2, 0x64, 0x69,
-	0x17, 0xa4, 0x03, 0x82, 0x7f, 0x80, 0xa6, 0xce, 0x30, 0x3d, 0x13, 0x98, 0x6e, 0x6d, 0x9d, 0xdb,
-	0xad, 0x55, 0x54, 0xa8, 0xca, 0x90, 0xf2, 0x9c, 0x18, 0x15, 0x6a, 0x1f, 0xff, 0x34, 0x0b, 0x2d,
-	0x93, 0xc2, 0xf1, 0xb1, 0x54, 0x89, 0xc2, 0xd4, 0x32, 0x27, 0xd4, 0xd0, 0xeb, 0x01, 0x41, 0xf7,
-	0x60, 0x55, 0x8c, 0xfc, 0x28, 0x52, 0xb9, 0x5d, 0x4c, 0xf2, 0x24, 0x9a, 0x90, 0xd9, 0x7b, 0x99,
-	0x25, 0x3b, 0xba, 0x0f, 0xed, 0xec, 0x84, 0x66, 0x33, 0x37, 0x95, 0xcd, 0xa2, 0x01, 0xf6, 0x98,
-	0x90, 0xe8, 0x11, 0x2c, 0x67, 0x07, 0x4d, 0x6d, 0x98, 0x3f, 0xa3, 0x82, 0x2d, 0x19, 0xb4, 0xa9,
-	0x19, 0xb7, 0x4d, 0x25, 0xab, 0xe9, 0x4a, 0xb6, 0x5e, 0x3a, 0x95, 0x39, 0xd4, 0x94, 0x32, 0x02,
-	0x97, 0x0e, 0x68, 0x48, 0xb4, 0xbc, 0xc7, 0xc2, 0xb7, 0x3e, 0x0f, 0x74, 0xd8, 0x14, 0xda, 0x0d,
-	0x0d, 0x5c, 0xff, 0xd8, 0xb4, 0x1b, 0xbd, 0x40, 0x3b, 0x50, 0xd3, 0xae, 0x49, 0x7d, 0xdc, 0x3d,
-	0x6d, 0x23, 0xf1, 0xa9, 0x93, 0xc0, 0xf0, 0x9f, 0x16, 0xac, 0xbc, 0x38, 0x76, 0x3d, 0x5a, 0xaa,
-	0xd1, 0x53, 0x27, 0x91, 0x6d, 0x68, 0xeb, 0x0d, 0x53, 0x0a, 0x52, 0x3f, 0x2f, 0x2a, 0xa1, 0xa9,
-	0x06, 0xc5, 0x0a, 0x3f, 0xf7, 0x29, 0x15, 0x3e, 0xbb, 0x49, 0xad, 0x78, 0x93, 0x4a, 0x6c, 0xd7,
-	0x3f, 0x2f, 0xb6, 0x1f, 0x03, 0x2a, 0x5e, 0x2b, 0x6b, 0xb9, 0xa9, 0x77, 0xac, 0x4f, 0xf3, 0xce,
-	0x0e, 0x34, 0xf7, 0x88, 0x71, 0xca, 0x55, 0x58, 0xf4, 0x58, 0x28, 0xe9, 0x07, 0x39, 0x7c, 0x47,
-	0xc7, 0xa6, 0x2a, 0xb6, 0x52, 0xd9, 0x57, 0x74, 0x2c, 0xf0, 0x5d, 0x00, 0x85, 0x4f, 0xad, 0x5d,
-	0x85, 0x39, 0x97, 0x98, 0xe6, 0xbe, 0x54, 0xf1, 0x81, 0xa3, 0xf6, 0xf0, 0x03, 0x98, 0xdd, 0x23,
-	0x4a, 0xb3, 0x62, 0xce, 0xa9, 0x27, 0x87, 0x31, 0x37, 0x2f, 0xda, 0x32, 0xb2, 0x43, 0x7e, 0xac,
-	0xfa, 0x8d, 0xb2, 0x62, 0xfa, 0x8d, 0xfa, 0xde, 0xfd, 0xc3, 0x82, 0x96, 0xca, 0xb0, 0x03, 0xca,
-	0x4f, 0x7c, 0x8f, 0xa2, 0x87, 0xba, 0x8b, 0xe9, 0xa4, 0xdc, 0xa8, 0x7a, 0xbc, 0x30, 0x78, 0xdb,
-	0xe5, 0x50, 0x4f, 0x26, 0xd3, 0x19, 0xf4, 0x00, 0x1a, 0xe9, 0x74, 0x5c, 0x39, 0x5d, 0x9e, 0x99,
-	0xed, 0x95, 0x53, 0x19, 0x8e, 0x67, 0xd0, 0x97, 0xd0, 0xcc, 0xe6, 0x70, 0x74, 0xf9, 0xb4, 0xfe,
-	0xa2, 0x82, 0x89, 0xe6, 0x77, 0x7f, 0xb6, 0x60, 0xad, 0x3c, 0xbf, 0x9a, 0x6b, 0xfd, 0x08, 0xff,
-	0x9a, 0x30, 0xdc, 0xa2, 0x7f, 0x97, 0xd4, 0x4c, 0x1f, 0xab, 0xed, 0x1b, 0xe7, 0x03, 0x93, 0x07,
-	0x53, 0x2c, 0x66, 0x61, 0x2d, 0x1d, 0xbc, 0x7a, 0xae, 0x74, 0x8f, 0xd9, 0x91, 0x61, 0xd1, 0x87,
-	0xc5, 0xe2, 0x94, 0x89, 0x26, 0xdc, 0xc2, 0xbe, 0x7a, 0xca, 0x52, 0x75, 0xe8, 0xc3, 0x33, 0xe8,
-	0x31, 0x40, 0x3e, 0x64, 0xa2, 0xcd, 0xaa, 0xab, 0xcb, 0xd3, 0xa7, 0x3d, 0x71, 0x26, 0xc4, 0x33,
-	0xe8, 0x35, 0x74, 0xca, 0x63, 0x25, 0xc2, 0x25, 0xe4, 0xc4, 0x11, 0xd5, 0xde, 0x3e, 0x13, 0x93,
-	0x79, 0xe1, 0x57, 0x0b, 0x96, 0x0e, 0xd2, 0xe2, 0x65, 0xee, 0x3f, 0x80, 0x05, 0x33, 0x0d, 0xa2,
-	0x4b, 0x55, 0xd2, 0xc5, 0xa1, 0xd4, 0xbe, 0x3c, 0x65, 0x37, 0xf3, 0xc0, 0x53, 0x68, 0x66, 0x43,
-	0x5a, 0x25, 0x58, 0xaa, 0xd3, 0xa2, 0xbd, 0x39, 0x6d, 0x3b, 0x23, 0xfb, 0x9b, 0x05, 0x4b, 0xa6,
-	0xf4, 0x18, 0xb2, 0xaf, 0x61, 0x7d, 0xf2, 0x90, 0x33, 0xf1, 0xd9, 0x6e, 0x55, 0x09, 0x9f, 0x31,
-	0x1d, 0xe1, 0x19, 0xd4, 0x87, 0x46, 0x32, 0xf0, 0x48, 0x74, 0xbd, 0x9c, 0x0b, 0xd3, 0xc6, 0x21,
-	0x7b, 0x42, 0x73, 0xc1, 0x33, 0xbb, 0x87, 0xd0, 0x79, 0xe1, 0x8e, 0x03, 0x1a, 0x66, 0x19, 0xdc,
-	0x83, 0x7a, 0xd2, 0x91, 0x91, 0x5d, 0xd6, 0x5c, 0x9c, 0x10, 0xec, 0x8d, 0x89, 0x7b, 0x99, 0x43,
-	0x46, 0xb0, 0xb8, 0xaf, 0x2a, 0xa8, 0x51, 0xfa, 0x4a, 0xfd, 0x60, 0x99, 0xd0, 0x48, 0xd0, 0xcd,
-	0x4a, 0x34, 0x4c, 0x6f, 0x36, 0x53, 0x72, 0xf6, 0x0d, 0x2c, 0xf5, 0x46, 0xd4, 0x7b, 0xc7, 0xe2,
-	0xec, 0x06, 0xcf, 0x01, 0xf2, 0xba, 0x5b, 0x89, 0xee, 0x53, 0x7d, 0xc6, 0xbe, 0x32, 0x75, 0x3f,
-	0xbb, 0xcd, 0x13, 0x55, 0x82, 0x8d, 0xf6, 0x07, 0x50, 0xef, 0xab, 0x19, 0x5c, 0xa0, 0xf5, 0x6a,
-	0x39, 0x4d, 0x35, 0x5e, 0x38, 0x25, 0x37, 0x9a, 0xde, 0xd4, 0xf5, 0x9f, 0x1b, 0xff, 0xfd, 0x3b,
-	0x00, 0x00, 0xff, 0xff, 0xb2, 0xa0, 0x6e, 0x6c, 0xea, 0x10, 0x00, 0x00,
+	// 3135 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x3a, 0x5d, 0x73, 0xdb, 0xc6,
+	0xb5, 0x02, 0x25, 0x8a, 0xe4, 0xe1, 0x87, 0xa8, 0xd5, 0x87, 0x61, 0x5a, 0x76, 0x6c, 0xf8, 0xc6,
+	0x76, 0xfc, 0xa1, 0x64, 0x94, 0x3b, 0x89, 0xef, 0xb5, 0x73, 0x1d, 0x86, 0xa4, 0x65, 0x4e, 0xf4,
+	0x15, 0x50, 0x72, 0x92, 0xc9, 0xcd, 0x60, 0x60, 0x60, 0x25, 0x22, 0x22, 0xb1, 0x30, 0xb0, 0x54,
+	0xc5, 0xf4, 0xad, 0xfd, 0x01, 0x9d, 0xce, 0xf4, 0x27, 0xf4, 0xa9, 0x33, 0x9d, 0xe9, 0x8f, 0x68,
+	0x1f, 0xda, 0x87, 0xbe, 0x76, 0x3a, 0xd3, 0xe7, 0xce, 0xe4, 0xb9, 0x7f, 0xa0, 0xb3, 0x0b, 0x2c,
+	0x08, 0x80, 0x80, 0x28, 0x35, 0xd3, 0xbe, 0x61, 0xcf, 0x9e, 0x3d, 0xe7, 0xec, 0xd9, 0xb3, 0xe7,
+	0x63, 0x0f, 0x00, 0x4c, 0x3c, 0x24, 0x9b, 0x8e, 0x4b, 0x28, 0x41, 0xe5, 0xbe, 0xe5, 0x78, 0x14,
+	0xbb, 0x5e, 0x9f, 0x38, 0xca, 0x19, 0x14, 0x5b, 0xba, 0x4b, 0xbb, 0x14, 0x0f, 0xd1, 0x4d, 0x00,
+	0xc7, 0x25, 0xe6, 0xc8, 0xa0, 0x9a, 0x65, 0xca, 0xd2, 0x6d, 0xe9, 0x41, 0x49, 0x2d, 0x05, 0x90,
+	0xae, 0x89, 0x1a, 0x50, 0x7c, 0x3b, 0xd2, 0x6d, 0x6a, 0xd1, 0xb1, 0x9c, 0xbb, 0x2d, 0x3d, 0xc8,
+	0xab, 0xe1, 0x18, 0xdd, 0x80, 0xd2, 0x89, 0x75, 0x4c, 0xb5, 0x9f, 0xb8, 0xba, 0x23, 0xcf, 0xdf,
+	0x96, 0x1e, 0x14, 0xd5, 0x22, 0x03, 0x7c, 0xe9, 0xea, 0x0e, 0x42, 0xb0, 0x60, 0x13, 0x8a, 0xe5,
+	0x05, 0x4e, 0x91, 0x7f, 0x2b, 0x87, 0x50, 0x6b, 0x9a, 0x26, 0x63, 0xab, 0xe2, 0xb7, 0x23, 0xec,
+	0x51, 0x74, 0x0d, 0x0a, 0x23, 0x0f, 0xbb, 0x13, 0xd6, 0x8b, 0x6c, 0xd8, 0x35, 0xd1, 0x7b, 0xb0,
+	0x60, 0x51, 0x3c, 0xe4, 0x3c, 0xcb, 0x5b, 0x6b, 0x9b, 0x11, 0xf1, 0x37, 0x85, 0xec, 0x2a, 0x47,
+	0x51, 0x1e, 0x41, 0xbd, 0x33, 0x74, 0xe8, 0x98, 0x81, 0x67, 0xd1, 0x55, 0xde, 0x83, 0xda, 0x36,
+	0xa6, 0x97, 0x42, 0xdd, 0x81, 0x05, 0x86, 0x97, 0x2d, 0xe3, 0x23, 0xc8, 0x33, 0x01, 0x3c, 0x39,
+	0x77, 0x7b, 0x3e, 0x5b, 0x48, 0x1f, 0x47, 0x29, 0x40, 0x9e, 0x4b, 0xa9, 0xbc, 0x86, 0xc6, 0x8e,
+	0xe5, 0x51, 0x15, 0x1b, 0x64, 0x38, 0xc4, 0xb6, 0xa9, 0x53, 0x8b, 0xd8, 0xde, 0x4c, 0x85, 0xbc,
+	0x03, 0xe5, 0xc9, 0x39, 0xf9, 0x2c, 0x4b, 0x2a, 0x84, 0x07, 0xe5, 0x29, 0xff, 0x07, 0x37, 0x52,
+	0xe9, 0x7a, 0x0e, 0xb1, 0x3d, 0x9c, 0x5c, 0x2f, 0x4d, 0xad, 0xff, 0xab, 0x04, 0x85, 0x03, 0x7f,
+	0x88, 0x6a, 0x90, 0x0b, 0x05, 0xc8, 0x59, 0x26, 0x3f, 0x4c, 0x7d, 0x88, 0xe5, 0x5c, 0x70, 0x98,
+	0xfa, 0x10, 0xa3, 0xdb, 0x50, 0x36, 0xb1, 0x67, 0xb8, 0x96, 0xc3, 0x18, 0xf1, 0xf3, 0x2f, 0xa9,
+	0x51, 0x10, 0x92, 0xa1, 0xe0, 0x58, 0x06, 0x1d, 0xb9, 0xc2, 0x0a, 0xc4, 0x10, 0xbd, 0x0f, 0x25,
+	0xc7, 0xb5, 0x0c, 0xac, 0x8d, 0x3c, 0x53, 0xce, 0xf3, 0x23, 0x46, 0x31, 0xed, 0xed, 0x12, 0x1b,
+	0x8f, 0xd5, 0x22, 0x47, 0x3a, 0xf2, 0x4c, 0x74, 0x0b, 0xc0, 0xd0, 0x29, 0x3e, 0x21, 0xae, 0x85,
+	0x3d, 0x79, 0xd1, 0x17, 0x7e, 0x02, 0x61, 0x56, 0x4c, 0xf5, 0x73, 0x0d, 0x9f, 0xe3, 0xa1, 0x43,
+	0xe5, 0x02, 0xb7, 0xc5, 0x12, 0xd5, 0xcf, 0x3b, 0x1c, 0xa0, 0xbc, 0x82, 0x55, 0xa6, 0x9b, 0x60,
+	0x7b, 0x13, 0xa5, 0x7c, 0x00, 0xc5, 0x40, 0x03, 0xbe, 0x46, 0xca, 0x5b, 0xab, 0x31, 0x31, 0x82,
+	0x05, 0x6a, 0x88, 0xa5, 0xdc, 0x85, 0xe5, 0x6d, 0x2c, 0x08, 0x89, 0x43, 0x4b, 0xa8, 0x4b, 0x79,
+	0x02, 0x6b, 0x3d, 0xac, 0xbb, 0x46, 0x7f, 0xc2, 0xd0, 0x47, 0x5c, 0x85, 0xfc, 0xdb, 0x11, 0x76,
+	0xc7, 0x01, 0xae, 0x3f, 0x50, 0x5e, 0xc1, 0x7a, 0x12, 0x3d, 0x90, 0x6f, 0x13, 0x0a, 0x2e, 0xf6,
+	0x46, 0x83, 0x19, 0xe2, 0x09, 0x24, 0xe5, 0x19, 0xc8, 0xad, 0x3e, 0x36, 0x4e, 0x9b, 0x67, 0xba,
+	0x35, 0xd0, 0xdf, 0x58, 0x03, 0x8b, 0x8e, 0x05, 0xef, 0x99, 0x06, 0xf0, 0x0f, 0x09, 0xae, 0xa7,
+	0xac, 0x0e, 0x44, 0xf9, 0x08, 0xae, 0x8d, 0x6c, 0xdd, 0x9f, 0x19, 0x60, 0x6d, 0x9a, 0xd4, 0x5a,
+	0x64, 0xfa, 0x20, 0xa4, 0x8a, 0xbe, 0x85, 0xaa, 0x8b, 0x3d, 0x4a, 0x8c, 0x53, 0xcd, 0xd4, 0x29,
+	0x16, 0x97, 0xe5, 0x69, 0xfc, 0xb2, 0x64, 0xb1, 0xdd, 0x54, 0xfd, 0xb5, 0x6d, 0xb6, 0xb4, 0x63,
+	0x53, 0x77, 0xac, 0x56, 0xdc, 0x08, 0xa8, 0xf1, 0x02, 0x96, 0xa7, 0x50, 0x50, 0x1d, 0xe6, 0x4f,
+	0xb1, 0x50, 0x32, 0xfb, 0x64, 0x8a, 0x3f, 0xd3, 0x07, 0x23, 0x61, 0xc1, 0xfe, 0xe0, 0x7f, 0x73,
+	0x4f, 0x25, 0xc5, 0x86, 0xa5, 0x6d, 0x4c, 0xbf, 0x18, 0x11, 0x8a, 0x85, 0xa6, 0x36, 0xa1, 0xa0,
+	0x9b, 0xa6, 0x8b, 0x3d, 0x8f, 0x93, 0x48, 0x6a, 0xbd, 0xe9, 0xcf, 0xa9, 0x02, 0xe9, 0x6a, 0x7e,
+	0xa0, 0x09, 0xf5, 0x09, 0xbf, 0x40, 0xb7, 0x4f, 0xa0, 0x68, 0x10, 0x8f, 0xf2, 0xdb, 0x20, 0x65,
+	0xde, 0x86, 0x02, 0xc3, 0x39, 0xf2, 0x4c, 0xe5, 0x57, 0x12, 0xd4, 0x7b, 0x7d, 0xcb, 0xd9, 0x77,
+	0x4d, 0xec, 0xfe, 0x27, 0x84, 0x46, 0x77, 0xa1, 0x6a, 0xe2, 0x81, 0x75, 0x86, 0xdd, 0x31, 0x3f,
+	0xc5, 0xe0, 0xb6, 0x57, 0x04, 0x90, 0xe9, 0x5e, 0xf9, 0x6f, 0x58, 0x8e, 0x48, 0x35, 0x71, 0x3b,
+	0xd4, 0xd5, 0x8d, 0x53, 0xcb, 0x3e, 0x99, 0xf8, 0x34, 0x10, 0xa0, 0xae, 0xa9, 0xfc, 0x42, 0x82,
+	0x42, 0x20, 0x1c, 0x7a, 0x17, 0x6a, 0x1e, 0x75, 0x31, 0xa6, 0x5a, 0x74, 0x2b, 0x25, 0xb5, 0xea,
+	0x43, 0x05, 0x1a, 0x82, 0x05, 0x43, 0xc4, 0xa3, 0x92, 0xca, 0xbf, 0xd9, 0x01, 0x7b, 0x74, 0x22,
+	0x99, 0x3f, 0x60, 0x1e, 0xc8, 0x20, 0x23, 0x66, 0x13, 0xc2, 0x03, 0x05, 0x43, 0x74, 0x1d, 0x8a,
+	0xdf, 0x5b, 0x8e, 0x66, 0x10, 0x13, 0x73, 0x07, 0x94, 0x57, 0x0b, 0xdf, 0x5b, 0x4e, 0x8b, 0x98,
+	0x58, 0xf9, 0x0a, 0xf2, 0x5c, 0xe1, 0x6c, 0xd7, 0xc6, 0xc8, 0x75, 0xb1, 0x6d, 0x8c, 0x7d, 0x44,
+	0x5f, 0x9a, 0x8a, 0x00, 0x32, 0x6c, 0xc6, 0x78, 0x64, 0x5b, 0xd4, 0xe3, 0xd2, 0xcc, 0xab, 0xfe,
+	0x80, 0x41, 0x6d, 0xdd, 0x26, 0x1e, 0x17, 0x27, 0xaf, 0xfa, 0x03, 0x65, 0x1b, 0x6e, 0x6d, 0x63,
+	0xda, 0x1b, 0x39, 0x0e, 0x71, 0x29, 0x36, 0x5b, 0x3e, 0x1d, 0x0b, 0x4f, 0x2e, 0xfc, 0xbb, 0x50,
+	0x8b, 0xb1, 0x14, 0x97, 0xab, 0x1a, 0xe5, 0xe9, 0x29, 0xff, 0x0f, 0xd7, 0x5b, 0x21, 0xc0, 0x3e,
+	0xc3, 0xae, 0x67, 0x11, 0x5b, 0x58, 0xc2, 0x3d, 0x58, 0x38, 0x76, 0xc9, 0xf0, 0x02, 0x4b, 0xe2,
+	0xf3, 0x2c, 0xd4, 0x50, 0xe2, 0x6f, 0xcc, 0xd7, 0xe4, 0x22, 0x25, 0x5c, 0x01, 0x3a, 0xdc, 0x9a,
+	0xa6, 0xfe, 0x99, 0x4e, 0x8d, 0xfe, 0x34, 0x8b, 0xf9, 0x7f, 0x8d, 0x45, 0x07, 0xde, 0xc9, 0x64,
+	0x11, 0xa8, 0x42, 0x81, 0x1c, 0x25, 0x17, 0x70, 0xc8, 0x51, 0xa2, 0xfc, 0x5d, 0x82, 0x5a, 0xcb,
+	0xc5, 0xa6, 0xc5, 0x22, 0xba, 0xd9, 0xb5, 0x8f, 0x09, 0x7a, 0x0c, 0xc8, 0xe0, 0x10, 0xcd, 0xd0,
+	0x5d, 0x53, 0xb3, 0x47, 0xc3, 0x37, 0xd8, 0x0d, 0x4e, 0xae, 0x6e, 0x84, 0xb8, 0x7b, 0x1c, 0x8e,
+	0xee, 0xc1, 0x52, 0x14, 0xdb, 0x38, 0x3b, 0x0b, 0xb2, 0x9c, 0xea, 0x04, 0xb5, 0x75, 0x76, 0x86,
+	0x3e, 0x81, 0x1b, 0x51, 0x3c, 0x7c, 0xee, 0x58, 0x2e, 0x0f, 0xb0, 0xda, 0x18, 0xeb, 0x6e, 0x70,
+	0xca, 0xf2, 0x64, 0x4d, 0x27, 0x44, 0xf8, 0x1a, 0xeb, 0x2e, 0x7a, 0x01, 0x1b, 0x19, 0xcb, 0x87,
+	0xc4, 0xa6, 0x7d, 0x6e, 0x9c, 0x79, 0xf5, 0x7a, 0xda, 0xfa, 0x5d, 0x86, 0xa0, 0xfc, 0x51, 0x82,
+	0x6a, 0xab, 0xaf, 0xbb, 0x27, 0xa1, 0x93, 0x7a, 0x08, 0x8b, 0xfa, 0x90, 0x19, 0xf3, 0x05, 0xe7,
+	0x1c, 0x60, 0xa0, 0xe7, 0x50, 0x8e, 0xb0, 0x0f, 0x72, 0xaa, 0x1b, 0xf1, 0x1b, 0x1f, 0xd3, 0xa2,
+	0x0a, 0x13, 0x51, 0xd0, 0x7d, 0x58, 0xb2, 0x4c, 0x3c, 0x74, 0x08, 0xe5, 0x66, 0xc9, 0x3c, 0xab,
+	0x7f, 0xc9, 0x6a, 0x11, 0xf0, 0xe7, 0x78, 0xcc, 0x8c, 0x57, 0x1f, 0xd1, 0x3e, 0x71, 0xad, 0xef,
+	0xb1, 0x46, 0xec, 0x81, 0x7f, 0xe9, 0x8a, 0x6a, 0x35, 0x84, 0xee, 0xdb, 0x83, 0xb1, 0xf2, 0x31,
+	0xd4, 0xc4, 0x56, 0x26, 0x56, 0x4f, 0x5d, 0xdd, 0xf6, 0x74, 0x83, 0xeb, 0x24, 0xf4, 0x13, 0xd5,
+	0x08, 0xb4, 0x6b, 0x2a, 0x06, 0xd4, 0x5a, 0xba, 0xc3, 0x12, 0x08, 0xa1, 0x84, 0xcb, 0x2d, 0x8c,
+	0xe8, 0x2a, 0x37, 0x4b, 0x57, 0xca, 0x32, 0x2c, 0x85, 0x4c, 0x7c, 0xf1, 0x94, 0x6f, 0xa1, 0xfc,
+	0x9a, 0x58, 0xe6, 0x15, 0x99, 0xa6, 0xa8, 0x2d, 0x97, 0xa6, 0x36, 0xa5, 0x06, 0x15, 0x9f, 0x7c,
+	0xc0, 0xee, 0x2f, 0x12, 0x94, 0xb8, 0x13, 0xe5, 0xf9, 0xb9, 0x48, 0x84, 0xa5, 0x99, 0x89, 0x30,
+	0xbb, 0x95, 0x2c, 0x44, 0x5c, 0xb0, 0x49, 0x3e, 0xcf, 0x32, 0xb7, 0x37, 0xba, 0x71, 0x4a, 0x18,
+	0x0f, 0x6c, 0x06, 0x99, 0x7b, 0x14, 0xc4, 0x4f, 0x32, 0x0c, 0xf5, 0xdc, 0xe1, 0xfb, 0xee, 0xb3,
+	0x1a, 0x42, 0x99, 0xc7, 0x67, 0x31, 0x27, 0xc8, 0x03, 0xe4, 0x7c, 0x4a, 0xcc, 0x09, 0xd3, 0x93,
+	0x00, 0x49, 0xf9, 0x7d, 0x09, 0xca, 0x22, 0x3c, 0x8c, 0x06, 0x94, 0x39, 0x61, 0xce, 0x71, 0xa2,
+	0xc3, 0x02, 0x1f, 0x77, 0x4d, 0xf4, 0x01, 0xac, 0x7a, 0x7d, 0xcb, 0x71, 0x58, 0xdc, 0x88, 0x06,
+	0x10, 0x5f, 0x85, 0x48, 0xcc, 0x1d, 0x86, 0x81, 0x04, 0x7d, 0x0c, 0xd5, 0x70, 0x05, 0x57, 0xc3,
+	0x7c, 0xa6, 0x1a, 0x2a, 0x02, 0xb1, 0xc5, 0xd4, 0xf1, 0x02, 0xea, 0xe1, 0x42, 0x11, 0x77, 0x16,
+	0x2e, 0x08, 0xa1, 0x4b, 0x02, 0x3b, 0x00, 0xa0, 0xc7, 0x22, 0x94, 0xe6, 0xb9, 0xb3, 0x5a, 0x8f,
+	0xad, 0x0a, 0x4f, 0x52, 0xc4, 0xd2, 0xcf, 0xa0, 0x38, 0xc4, 0x54, 0x37, 0x75, 0xaa, 0xf3, 0x44,
+	0xb6, 0xbc, 0x75, 0x6f, 0x7a, 0x81, 0xaf, 0xa0, 0xcd, 0xdd, 0x00, 0xd1, 0xcf, 0x7c, 0xc2, 0x75,
+	0xe8, 0x03, 0x58, 0x64, 0x01, 0x6e, 0xe4, 0xf1, 0x54, 0xb7, 0xb6, 0x25, 0x4f, 0x53, 0xe8, 0xf1,
+	0x79, 0x35, 0xc0, 0x43, 0x2f, 0xa0, 0x6c, 0x84, 0x8e, 0xd6, 0x93, 0x8b, 0x9c, 0xf1, 0xcd, 0xb8,
+	0x35, 0x45, 0x22, 0x89, 0x41, 0x5c, 0x53, 0x8d, 0xae, 0x40, 0x5b, 0xb0, 0x96, 0x76, 0x20, 0x9e,
+	0x5c, 0xe2, 0x01, 0x6a, 0x65, 0xfa, 0x44, 0xd8, 0x56, 0x97, 0xa3, 0x39, 0xa3, 0xaf, 0x24, 0xb8,
+	0x28, 0xdf, 0xa8, 0x47, 0xf0, 0xbb, 0x5c, 0x5d, 0x77, 0xa0, 0xe2, 0xdb, 0x48, 0xe0, 0xc9, 0xcb,
+	0x3c, 0xcc, 0x96, 0x39, 0x2c, 0x70, 0xe2, 0xff, 0x03, 0x35, 0xcb, 0xf6, 0x46, 0xae, 0x6e, 0x1b,
+	0xd8, 0x3f, 0xfa, 0x4a, 0xe6, 0xd1, 0x57, 0x43, 0x4c, 0x7e, 0xf6, 0x4f, 0xa0, 0xc8, 0xea, 0x06,
+	0xbe, 0xa8, 0x9a, 0x9d, 0x79, 0x51, 0xfd, 0x9c, 0xa3, 0x6f, 0x42, 0xd1, 0xb4, 0x3c, 0x9e, 0x43,
+	0xc8, 0xb5, 0xec, 0xb2, 0x45, 0xe0, 0xb0, 0xb2, 0xc5, 0x71, 0xc9, 0x90, 0xf0, 0x52, 0x4c, 0x5e,
+	0x0a, 0x53, 0xee, 0x00, 0x32, 0x9d, 0x57, 0xd5, 0xa7, 0xf3, 0x2a, 0xf4, 0x14, 0x6a, 0x61, 0x99,
+	0xed, 0x4b, 0xba, 0x9c, 0x6d, 0xd9, 0xa2, 0xfe, 0xe6, 0xe2, 0xde, 0x87, 0x25, 0x87, 0x58, 0x36,
+	0xf5, 0x34, 0x17, 0x9b, 0x18, 0x0f, 0xb1, 0x29, 0x23, 0xae, 0xbe, 0x9a, 0x0f, 0x56, 0x03, 0x28,
+	0x7a, 0x16, 0x22, 0x86, 0xdb, 0x5b, 0xc9, 0xe4, 0x11, 0x2c, 0x6e, 0x8b, 0x4d, 0xbe, 0x0f, 0x2b,
+	0xba, 0xe3, 0xb8, 0xe4, 0xdc, 0x1a, 0xea, 0x94, 0x55, 0x06, 0x96, 0x61, 0xd9, 0x27, 0xf2, 0x2a,
+	0x77, 0x2b, 0x28, 0x32, 0x75, 0xe0, 0xcf, 0xa0, 0x0f, 0xa1, 0x7c, 0xe2, 0xea, 0xb6, 0xa9, 0x51,
+	0x42, 0xf5, 0x81, 0xbc, 0x96, 0xc9, 0x09, 0x38, 0xda, 0x21, 0xc3, 0x42, 0x3b, 0xc0, 0x48, 0x0d,
+	0x2c, 0x6c, 0x6a, 0x11, 0x95, 0xae, 0xa7, 0xd8, 0x71, 0xd3, 0x47, 0x3b, 0x10, 0x58, 0xea, 0xb2,
+	0x9e, 0x80, 0x78, 0x8d, 0x67, 0x50, 0x8d, 0xdd, 0xad, 0x2b, 0x95, 0x0c, 0xdf, 0x41, 0x3d, 0xc9,
+	0x83, 0xe5, 0xa4, 0x74, 0xec, 0x88, 0x14, 0x91, 0x7f, 0x33, 0x58, 0x24, 0xf5, 0xe1, 0xdf, 0x31,
+	0x0b, 0x9a, 0x9f, 0x6d, 0x41, 0x3c, 0xd7, 0x4f, 0x5e, 0xcc, 0x64, 0xe9, 0x2d, 0x4d, 0x97, 0xde,
+	0x22, 0x41, 0xcb, 0xcd, 0xc8, 0x01, 0xfd, 0x24, 0x2b, 0x5b, 0x90, 0x1c, 0x25, 0x6c, 0x1b, 0xae,
+	0x08, 0x01, 0x92, 0xca, 0xbf, 0x95, 0x3f, 0x48, 0xb0, 0xd1, 0xc3, 0xb6, 0xc9, 0x5d, 0x4d, 0x8b,
+	0xd8, 0xc7, 0x96, 0x3b, 0xe4, 0xe9, 0x4a, 0xa4, 0xd2, 0xc5, 0x43, 0xdd, 0x1a, 0x88, 0x4a, 0x97,
+	0x0f, 0xd0, 0x26, 0xe4, 0xf9, 0xc5, 0x0d, 0xe4, 0x92, 0xb3, 0x1c, 0x9f, 0xea, 0xa3, 0xa1, 0xe7,
+	0x00, 0x3a, 0xa5, 0xba, 0xd1, 0x1f, 0xe2, 0x50, 0x5f, 0x1b, 0xb1, 0x45, 0x1d, 0x46, 0xb7, 0x19,
+	0xe2, 0xa8, 0x11, 0x7c, 0xe6, 0x3a, 0xf8, 0xc5, 0x19, 0x62, 0xcf, 0xd3, 0x4f, 0x44, 0x0c, 0x2b,
+	0x33, 0xd8, 0xae, 0x0f, 0x52, 0x7e, 0x26, 0xc1, 0x52, 0x82, 0x04, 0x5a, 0x87, 0xc5, 0x63, 0xc2,
+	0xb6, 0x23, 0x5e, 0x60, 0xfc, 0x11, 0x7b, 0x0a, 0x3b, 0xb6, 0x06, 0x38, 0xf2, 0x10, 0x12, 0x8e,
+	0x19, 0x2b, 0x83, 0xd8, 0x14, 0xdb, 0x54, 0xe3, 0x66, 0x10, 0xbc, 0x86, 0x04, 0xb0, 0xc3, 0xb1,
+	0x13, 0xd4, 0x22, 0x7c, 0xc8, 0x05, 0xa9, 0xa8, 0x62, 0xa8, 0x10, 0x68, 0x30, 0x5d, 0xf6, 0x86,
+	0x5e, 0x9a, 0x26, 0xef, 0x40, 0xc5, 0xe9, 0x13, 0x1b, 0xc7, 0x53, 0xd9, 0x32, 0x87, 0x05, 0x0e,
+	0xf0, 0x8a, 0x6a, 0x55, 0xb6, 0xe0, 0x1a, 0x7b, 0xc4, 0xe0, 0xd7, 0xf8, 0x33, 0x7d, 0xc0, 0xbc,
+	0xe1, 0xcc, 0xd7, 0xb0, 0xfb, 0x50, 0x8d, 0x2d, 0x60, 0x6a, 0xf2, 0x1d, 0x01, 0x47, 0x9c, 0x57,
+	0x83, 0x91, 0xa2, 0xc3, 0x8a, 0xef, 0x57, 0x0e, 0x02, 0x1f, 0x73, 0x31, 0xe1, 0x08, 0x9d, 0x5c,
+	0x94, 0x4e, 0x2c, 0x39, 0x98, 0x8f, 0x25, 0x07, 0xca, 0x2f, 0x8b, 0xb0, 0x7c, 0x30, 0xd0, 0x0d,
+	0x1c, 0xab, 0x80, 0x33, 0x39, 0xdc, 0x85, 0x2a, 0x9f, 0x10, 0x35, 0x54, 0x70, 0x7a, 0x15, 0x06,
+	0x14, 0x55, 0x48, 0xb4, 0x7e, 0x9e, 0xbf, 0x4c, 0xfd, 0x1c, 0x1a, 0x78, 0x3e, 0x6a, 0xe0, 0x89,
+	0x4c, 0x7b, 0xf1, 0x6a, 0x99, 0x76, 0x1b, 0x6e, 0x19, 0x11, 0x0b, 0xd0, 0x26, 0xb6, 0xac, 0x05,
+	0x16, 0x59, 0xe0, 0xcc, 0x36, 0xa2, 0x58, 0x13, 0xcb, 0x7d, 0xe9, 0xdb, 0xe9, 0xab, 0x48, 0x82,
+	0xe1, 0xc7, 0xf9, 0xc7, 0xf1, 0xb4, 0x2c, 0xa9, 0xb9, 0xcc, 0x34, 0xe3, 0x11, 0x2c, 0x7b, 0xa7,
+	0xbc, 0x4a, 0x9e, 0xb0, 0x93, 0x4b, 0xdc, 0xaf, 0xd7, 0xd9, 0x44, 0xd4, 0x5c, 0x99, 0x7d, 0xf3,
+	0xd8, 0x8a, 0x4d, 0x19, 0x38, 0x8a, 0x18, 0xa2, 0x8f, 0xa0, 0x7c, 0xc2, 0xf8, 0x04, 0x09, 0x40,
+	0xf9, 0xa2, 0x04, 0x00, 0x38, 0x66, 0x18, 0xfa, 0x63, 0x96, 0x5f, 0x99, 0xb6, 0xfc, 0x1e, 0xac,
+	0xc6, 0x34, 0x66, 0xf4, 0x75, 0xdb, 0xc6, 0x03, 0x1e, 0xcb, 0x6b, 0x5b, 0xb7, 0x93, 0xf9, 0x4d,
+	0x88, 0xd8, 0xf2, 0xf1, 0xd4, 0x15, 0x63, 0x1a, 0xc8, 0xde, 0x2c, 0x0c, 0x32, 0x72, 0x18, 0x39,
+	0xe6, 0xbe, 0x6b, 0x9c, 0x2d, 0xf8, 0x20, 0x5e, 0xf3, 0x4f, 0x85, 0xed, 0xa5, 0x94, 0xb0, 0xfd,
+	0x00, 0xea, 0x41, 0x4c, 0xa5, 0x24, 0x88, 0xbf, 0x72, 0x3d, 0x1a, 0x7d, 0x0f, 0x89, 0x7f, 0x4f,
+	0xd0, 0xa7, 0x50, 0x73, 0xf4, 0x31, 0x3f, 0x66, 0xcf, 0x19, 0xb0, 0xb7, 0x84, 0x65, 0xae, 0xa2,
+	0xeb, 0xf1, 0x63, 0xf3, 0x51, 0x7a, 0x0c, 0x43, 0xad, 0x3a, 0x91, 0x91, 0x87, 0x9e, 0x42, 0x99,
+	0xe9, 0x56, 0x33, 0x31, 0x65, 0x26, 0x89, 0xf8, 0xee, 0xaf, 0xc5, 0x96, 0x33, 0x95, 0xb6, 0xf9,
+	0xb4, 0x0a, 0x56, 0xf8, 0x8d, 0x76, 0x80, 0x8f, 0x34, 0x9b, 0x50, 0xec, 0xc9, 0x2b, 0x9c, 0xef,
+	0x93, 0x19, 0xe6, 0xc2, 0x48, 0xed, 0x91, 0xf0, 0x41, 0xae, 0x64, 0x89, 0xf1, 0x8f, 0x0a, 0xab,
+	0x8d, 0xe7, 0x50, 0x8b, 0x53, 0xbe, 0x52, 0x50, 0x3e, 0x87, 0x4a, 0x54, 0x43, 0xc9, 0x9b, 0x28,
+	0x5d, 0xed, 0x26, 0x5e, 0xa5, 0x62, 0x6c, 0x03, 0x8a, 0xea, 0x28, 0x7c, 0xba, 0x0d, 0x7c, 0xb2,
+	0x74, 0x39, 0x9f, 0xfc, 0x16, 0xd6, 0x7a, 0xd6, 0x70, 0x34, 0xd0, 0xe9, 0x8f, 0x23, 0x84, 0x1e,
+	0x40, 0xde, 0xcf, 0xab, 0xb2, 0x25, 0xf7, 0x11, 0x94, 0x37, 0xb0, 0xd2, 0x1b, 0xbd, 0x19, 0x5a,
+	0x34, 0xce, 0xf0, 0xc2, 0xaa, 0x4c, 0xd4, 0x1d, 0xb9, 0xcb, 0xd5, 0x1d, 0xca, 0x16, 0xac, 0x6d,
+	0x63, 0x1a, 0x9d, 0x09, 0xbc, 0x75, 0x36, 0x17, 0xe5, 0xcf, 0x12, 0xac, 0x27, 0x17, 0xfd, 0x1b,
+	0x64, 0x9b, 0x68, 0x76, 0xfe, 0x72, 0x9a, 0x65, 0x2e, 0xdf, 0x75, 0x89, 0x1b, 0x24, 0x12, 0xfe,
+	0x80, 0x79, 0x29, 0x9b, 0x50, 0x6d, 0x48, 0x4c, 0xeb, 0xd8, 0xc2, 0x7e, 0x3b, 0xa3, 0xa8, 0x96,
+	0x6d, 0x42, 0x77, 0x03, 0x90, 0xb2, 0x09, 0xa5, 0xa6, 0x19, 0x89, 0xe7, 0x3c, 0xf0, 0x9f, 0x53,
+	0xf6, 0x26, 0x20, 0x1e, 0xf8, 0xca, 0x01, 0xec, 0x73, 0x3c, 0xf6, 0x94, 0xf7, 0x01, 0x9a, 0xe1,
+	0x7b, 0x00, 0xba, 0x03, 0xf3, 0xba, 0x29, 0x1a, 0x00, 0x4b, 0x89, 0xa8, 0xa4, 0xb2, 0x39, 0xe5,
+	0x19, 0xe4, 0x9a, 0x26, 0xa3, 0xcc, 0x2c, 0xd8, 0xc5, 0x06, 0xd5, 0x46, 0xae, 0x48, 0xbd, 0xca,
+	0x02, 0x76, 0xe4, 0x0e, 0x78, 0x9a, 0x8a, 0xcf, 0xa9, 0x48, 0x49, 0xd9, 0xf7, 0xc3, 0xdf, 0x4a,
+	0x50, 0x8e, 0xa8, 0x07, 0x6d, 0x80, 0xbc, 0xaf, 0xb6, 0x3b, 0xaa, 0xd6, 0x3b, 0x6c, 0x1e, 0x1e,
+	0xf5, 0xb4, 0xa3, 0xbd, 0xde, 0x41, 0xa7, 0xd5, 0x7d, 0xd9, 0xed, 0xb4, 0xeb, 0x73, 0x48, 0x86,
+	0xd5, 0xd8, 0xec, 0x41, 0x67, 0xaf, 0xdd, 0xdd, 0xdb, 0xae, 0x4b, 0xa8, 0x01, 0xeb, 0xb1, 0x99,
+	0xd6, 0xfe, 0xee, 0xc1, 0x4e, 0xe7, 0xb0, 0xd3, 0xae, 0xe7, 0xd0, 0x35, 0x58, 0x89, 0xcd, 0xbd,
+	0x6c, 0x76, 0x77, 0x3a, 0xed, 0xfa, 0xfc, 0xd4, 0x84, 0xda, 0x79, 0xdd, 0xed, 0x7c, 0x59, 0x5f,
+	0x98, 0xe2, 0xd3, 0xf9, 0xea, 0xa0, 0xab, 0x76, 0xda, 0xf5, 0xfc, 0xc3, 0x67, 0x00, 0x13, 0x67,
+	0x86, 0x56, 0xa1, 0xde, 0x3d, 0xec, 0xec, 0x6a, 0xed, 0xce, 0x61, 0xb3, 0xbb, 0xa3, 0xbd, 0x3c,
+	0xda, 0xd9, 0xa9, 0xcf, 0x31, 0xb2, 0x51, 0x68, 0xef, 0x68, 0x77, 0xb7, 0xa9, 0x7e, 0x5d, 0x97,
+	0x1e, 0xfe, 0x14, 0x56, 0x52, 0xe2, 0x00, 0xba, 0x05, 0x8d, 0xd6, 0xfe, 0xde, 0xcb, 0xae, 0xba,
+	0xdb, 0x3c, 0xec, 0xee, 0xef, 0x69, 0xad, 0x57, 0xcd, 0xbd, 0xbd, 0xce, 0x8e, 0xd6, 0xd9, 0x6d,
+	0x76, 0x19, 0xbd, 0x0d, 0x90, 0x53, 0xe7, 0x7b, 0xbb, 0xbd, 0xba, 0x84, 0xee, 0x81, 0x92, 0xbd,
+	0x5a, 0x6b, 0xee, 0xb5, 0x39, 0x5e, 0x6e, 0xeb, 0x4f, 0x12, 0x94, 0x59, 0xa4, 0xeb, 0x61, 0xf7,
+	0xcc, 0x32, 0x30, 0x7a, 0xce, 0x9f, 0xbe, 0xd9, 0x66, 0xd0, 0x8d, 0x64, 0xb6, 0x11, 0xe9, 0x92,
+	0x36, 0x50, 0x22, 0xe5, 0x65, 0x6d, 0xc4, 0x39, 0xf4, 0x0c, 0x0a, 0x41, 0x2b, 0x33, 0xb1, 0x3a,
+	0xde, 0xe0, 0x6c, 0x2c, 0x4f, 0x45, 0x5a, 0x65, 0x0e, 0x7d, 0x0a, 0xa5, 0xb0, 0x69, 0x8a, 0x6e,
+	0x4e, 0xd3, 0x8f, 0x12, 0x48, 0x65, 0xbf, 0xf5, 0x73, 0x09, 0xd6, 0xe2, 0xcd, 0x46, 0xb1, 0xad,
+	0xef, 0x60, 0x25, 0xa5, 0x13, 0x89, 0xee, 0xc7, 0xc8, 0x64, 0xf7, 0x40, 0x1b, 0x0f, 0x66, 0x23,
+	0x06, 0x4f, 0x65, 0x73, 0x5b, 0x3f, 0xe4, 0x60, 0x2d, 0x78, 0x67, 0x6a, 0xe9, 0x54, 0x1f, 0x90,
+	0x13, 0x21, 0xc5, 0x36, 0x54, 0xa2, 0x3d, 0x3f, 0x94, 0xb2, 0x8b, 0xc6, 0x9d, 0x29, 0x4e, 0xc9,
+	0x16, 0x9c, 0x32, 0x87, 0xda, 0x00, 0x93, 0x96, 0x1f, 0xba, 0x95, 0x54, 0x75, 0xbc, 0x17, 0xd8,
+	0x48, 0x7d, 0x02, 0x53, 0xe6, 0xd0, 0x37, 0x50, 0x8b, 0x37, 0xf9, 0x90, 0x12, 0xc3, 0x4c, 0x6d,
+	0x18, 0x36, 0xee, 0x5e, 0x88, 0x13, 0x8a, 0x68, 0xc2, 0xf2, 0x54, 0x0b, 0x0d, 0xbd, 0x3b, 0xab,
+	0xc5, 0xe6, 0xb3, 0xb8, 0x77, 0xb9, 0x4e, 0x9c, 0x32, 0xb7, 0xf5, 0x1b, 0x09, 0x96, 0x7a, 0xc1,
+	0x33, 0x8f, 0xd0, 0x72, 0x17, 0x8a, 0xa2, 0x9d, 0x85, 0x36, 0x92, 0xaa, 0x89, 0x76, 0xd5, 0x1a,
+	0x37, 0x33, 0x66, 0xc3, 0x4d, 0xec, 0x40, 0x29, 0xec, 0x1f, 0x25, 0x4c, 0x32, 0xd9, 0xed, 0x6a,
+	0xdc, 0xca, 0x9a, 0x0e, 0x85, 0xfd, 0x75, 0x0e, 0x96, 0x44, 0x72, 0x2f, 0x84, 0xfd, 0x06, 0xd6,
+	0xd3, 0xfb, 0x2f, 0xa9, 0xc6, 0xf1, 0x28, 0x29, 0xf0, 0x05, 0x8d, 0x1b, 0x65, 0x0e, 0x6d, 0x43,
+	0xc1, 0x2f, 0xd4, 0x29, 0x4a, 0xa8, 0x34, 0xab, 0x53, 0xd3, 0x48, 0x89, 0xcd, 0xca, 0x1c, 0x3a,
+	0x85, 0x4a, 0x40, 0x88, 0x37, 0x44, 0xd0, 0xa3, 0x19, 0xd4, 0xa2, 0x9d, 0x99, 0xc6, 0xe3, 0xcb,
+	0x21, 0x87, 0x6a, 0xfa, 0x9b, 0x04, 0x35, 0x91, 0x37, 0x05, 0x5a, 0x6a, 0xc1, 0xa2, 0xff, 0x3e,
+	0x8f, 0x1a, 0x09, 0xd3, 0x88, 0xf4, 0x1f, 0x1a, 0x37, 0x52, 0xe7, 0x42, 0x6d, 0xbc, 0x84, 0x42,
+	0xf0, 0x8c, 0x9e, 0x70, 0x4e, 0xf1, 0x17, 0xfc, 0xc6, 0x46, 0xfa, 0x64, 0x48, 0xe7, 0x13, 0x58,
+	0x60, 0x8f, 0xe3, 0x28, 0x1e, 0x9c, 0x23, 0xcf, 0xf1, 0x8d, 0xeb, 0x29, 0x33, 0xe1, 0xf6, 0xfa,
+	0x50, 0xe1, 0xe5, 0xbd, 0xd8, 0xdb, 0x57, 0xb0, 0x96, 0xfa, 0x6c, 0x81, 0xde, 0x4b, 0x5c, 0xb4,
+	0xec, 0xa7, 0x8d, 0x0c, 0x77, 0x68, 0x02, 0xf4, 0x86, 0x9e, 0xe0, 0xf3, 0x3a, 0x8b, 0xcf, 0xfd,
+	0x29, 0x3e, 0xe9, 0x65, 0x7f, 0x06, 0x97, 0xdf, 0x49, 0x50, 0xdb, 0x21, 0x63, 0x7d, 0x40, 0xc7,
+	0x13, 0x56, 0xf5, 0x64, 0x31, 0x8f, 0xfe, 0x6b, 0xca, 0x49, 0xa5, 0xd4, 0xfa, 0x8d, 0xf8, 0xf1,
+	0xc6, 0x50, 0xf8, 0x09, 0x56, 0xa2, 0x75, 0x3c, 0x8a, 0x17, 0x53, 0x29, 0x25, 0x7e, 0x86, 0xc8,
+	0x3f, 0xb0, 0x8b, 0xc8, 0xbc, 0x0a, 0x19, 0x85, 0x26, 0xb6, 0x0f, 0x30, 0x49, 0x99, 0x13, 0x2e,
+	0x75, 0xaa, 0xde, 0x68, 0xbc, 0x93, 0x39, 0x1f, 0x9a, 0xc9, 0x17, 0x50, 0x8e, 0xa4, 0xb2, 0x33,
+	0x29, 0xc6, 0xf7, 0x92, 0x92, 0x04, 0xfb, 0x0e, 0x3b, 0x9e, 0x84, 0x26, 0x1c, 0x76, 0x6a, 0x5a,
+	0xdb, 0xb8, 0x7b, 0x21, 0x4e, 0x48, 0xfc, 0x08, 0xaa, 0xb1, 0x6c, 0x7f, 0xa6, 0xc4, 0x89, 0x60,
+	0x91, 0x56, 0x29, 0x28, 0x73, 0x5b, 0xaf, 0x58, 0xa2, 0x29, 0x94, 0xfc, 0x0c, 0x16, 0xb7, 0x59,
+	0xd3, 0xdc, 0x43, 0xeb, 0xc9, 0xa4, 0x31, 0x20, 0x7a, 0x6d, 0x0a, 0x2e, 0x28, 0xbd, 0x59, 0xe4,
+	0xbf, 0x8d, 0x7d, 0xf8, 0xcf, 0x01, 0x00, 0xcc, 0x23, 0x6b, 0x58, 0x44, 0x26, 0x00, 0x00,
 }
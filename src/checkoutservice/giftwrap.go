@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// wrapGifts records in prep the gift wrapping fee, in the user currency, of
+// the items to gift wrap. The fee is charged for every unit.
+func (cs *checkoutService) wrapGifts(ctx context.Context, prep *orderPrep, userCurrency string) error {
+	var units int64
+	for _, it := range prep.orderItems {
+		if it.GetItem().GetGiftWrap() {
+			units += int64(it.GetItem().GetQuantity())
+		}
+	}
+	if units == 0 {
+		return nil
+	}
+	if cs.giftWrapFee == nil {
+		return status.Errorf(codes.FailedPrecondition, "gift wrapping is not available")
+	}
+	feeUSD := floatToMoney(moneyToFloat(cs.giftWrapFee)*float64(units), usdCurrency)
+	fee, err := cs.convertCurrency(withCallResource(ctx, "currency.convert.gift_wrap"), feeUSD, userCurrency)
+	if err != nil {
+		return downstreamError(err, "failed to convert gift wrapping fee to currency")
+	}
+	prep.conversions = append(prep.conversions, newConversionRecord("gift_wrap", feeUSD, fee))
+	prep.giftWrapCost = fee
+	return nil
+}
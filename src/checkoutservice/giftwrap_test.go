@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderGiftWrap(t *testing.T) {
+	placeOrder := func(giftWrap bool) (*pb.OrderResult, *pb.Money) {
+		f := newFakeDownstreams()
+		f.cart.carts["user-1"][1].GiftWrap = giftWrap
+		cs := newTestCheckoutService(t, f)
+		cs.giftWrapFee = &pb.Money{CurrencyCode: "USD", Units: 2, Nanos: 500000000}
+
+		req := testOrderRequest()
+		req.UserCurrency = "EUR"
+		resp, err := cs.PlaceOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+		return resp.GetOrder(), f.payment.charges[0].GetAmount()
+	}
+
+	order, charged := placeOrder(true)
+	// 2 units of 66VCHSJNUP at 2.50 USD, converted at 0.5 EUR per USD.
+	want := &pb.Money{CurrencyCode: "EUR", Units: 2, Nanos: 500000000}
+	if got := order.GetGiftWrapCost(); !proto.Equal(got, want) {
+		t.Errorf("gift wrap cost = %v, want %v", got, want)
+	}
+	_, unwrapped := placeOrder(false)
+	if diff := moneyToFloat(charged) - moneyToFloat(unwrapped); math.Abs(diff-2.5) > 1e-9 {
+		t.Errorf("gift wrapping added %v EUR to the charge, want 2.5", diff)
+	}
+}
+
+func TestPlaceOrderGiftWrapUnavailable(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"][0].GiftWrap = true
+	cs := newTestCheckoutService(t, f)
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("PlaceOrder() error = %v, want FailedPrecondition", err)
+	}
+	if n := f.payment.chargeCount(); n != 0 {
+		t.Errorf("card was charged %d times", n)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// readinessService is the health check service name reporting whether
+	// the dependencies of checkout are available. The default, empty,
+	// service only reports that the process is alive.
+	readinessService = "readiness"
+
+	readinessProbeTimeout       = 500 * time.Millisecond
+	defaultOptionalDependencies = "emailservice,smsservice,loyaltyservice"
+)
+
+// parseDependencySet parses a comma-separated list of downstream names.
+func parseDependencySet(v string) map[string]bool {
+	out := make(map[string]bool)
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+// checkReadiness health checks every downstream service. The service is ready
+// as long as all required dependencies are serving; optional ones being down
+// is only logged.
+func (cs *checkoutService) checkReadiness(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessProbeTimeout)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		ready = true
+	)
+	for _, d := range cs.downstreams() {
+		wg.Add(1)
+		go func(d downstream) {
+			defer wg.Done()
+			resp, err := healthpb.NewHealthClient(*d.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			if err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING {
+				return
+			}
+			if cs.optionalDependencies[d.name] {
+				log.Warnf("optional dependency %s is not serving: %v", d.name, err)
+				return
+			}
+			log.Warnf("required dependency %s is not serving: %v", d.name, err)
+			mu.Lock()
+			ready = false
+			mu.Unlock()
+		}(d)
+	}
+	wg.Wait()
+	if !ready {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
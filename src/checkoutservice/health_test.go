@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReadinessWithOptionalDependencyDown(t *testing.T) {
+	up := startFakeServer(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := lis.Addr().String()
+	lis.Close()
+
+	tests := []struct {
+		name     string
+		optional string
+		want     healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{"email optional", "emailservice", healthpb.HealthCheckResponse_SERVING},
+		{"email required", "", healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &checkoutService{
+				productCatalogSvcAddr: up,
+				cartSvcAddr:           up,
+				currencySvcAddr:       up,
+				shippingSvcAddr:       up,
+				emailSvcAddr:          down,
+				paymentSvcAddr:        up,
+				optionalDependencies:  parseDependencySet(tt.optional),
+			}
+			dialTestService(t, cs)
+
+			resp, err := cs.Check(context.Background(), &healthpb.HealthCheckRequest{Service: readinessService})
+			if err != nil {
+				t.Fatalf("Check() failed: %v", err)
+			}
+			if resp.GetStatus() != tt.want {
+				t.Errorf("readiness = %v, want %v", resp.GetStatus(), tt.want)
+			}
+
+			// Liveness does not depend on downstream services.
+			resp, err = cs.Check(context.Background(), &healthpb.HealthCheckRequest{})
+			if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("liveness = %v, %v; want SERVING", resp.GetStatus(), err)
+			}
+		})
+	}
+}
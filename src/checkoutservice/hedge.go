@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const hedgedRequestsMetric = "checkout_hedged_requests_total"
+
+// hedgedCall is one attempt of a hedged request.
+type hedgedCall func(ctx context.Context) (interface{}, error)
+
+// hedge runs primary and, if it has not returned after delay, backup as well.
+// The first successful response wins and the other call is cancelled. If
+// every started call fails, the last error is returned. A primary call
+// failing before delay is not hedged.
+func hedge(ctx context.Context, delay time.Duration, primary, backup hedgedCall, onHedge func()) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		v   interface{}
+		err error
+	}
+	results := make(chan result, 2)
+	launch := func(call hedgedCall) {
+		go func() {
+			v, err := call(ctx)
+			results <- result{v, err}
+		}()
+	}
+
+	launch(primary)
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedgeC := timer.C
+	for {
+		select {
+		case <-hedgeC:
+			hedgeC = nil
+			onHedge()
+			launch(backup)
+			pending++
+		case r := <-results:
+			pending--
+			if r.err == nil || pending == 0 {
+				return r.v, r.err
+			}
+		}
+	}
+}
+
+// hedging reports whether reads over the hedge connection conn should be
+// hedged. Hedging is disabled for a service without a hedge address.
+func (cs *checkoutService) hedging(conn *grpc.ClientConn) bool {
+	return cs.hedgeDelay > 0 && conn != nil
+}
+
+func (cs *checkoutService) countHedge(service string) func() {
+	return func() {
+		cs.stats().IncCounter(hedgedRequestsMetric, map[string]string{"service": service})
+	}
+}
+
+// getProduct fetches a product, hedged on a second connection to the
+// catalog when hedging is enabled.
+func (cs *checkoutService) getProduct(ctx context.Context, id string) (*pb.Product, error) {
+	req := &pb.GetProductRequest{Id: id}
+	if !cs.hedging(cs.productCatalogHedgeConn) {
+		return cs.clients().catalog().GetProduct(ctx, req)
+	}
+	v, err := hedge(ctx, cs.hedgeDelay,
+		func(ctx context.Context) (interface{}, error) {
+			return cs.clients().catalog().GetProduct(ctx, req)
+		},
+		func(ctx context.Context) (interface{}, error) {
+			return pb.NewProductCatalogServiceClient(cs.productCatalogHedgeConn).GetProduct(ctx, req)
+		},
+		cs.countHedge("productcatalogservice"))
+	if err != nil {
+		return nil, err
+	}
+	return v.(*pb.Product), nil
+}
+
+// convertMoney sends a conversion request, hedged on a second connection to
+// the currency service when hedging is enabled. Requests routed to a region
+// are hedged on the connection to that region.
+func (cs *checkoutService) convertMoney(ctx context.Context, req *pb.CurrencyConversionRequest) (*pb.Money, error) {
+	if !cs.hedging(cs.currencyHedgeConn) {
+		return cs.currencyClient(ctx).Convert(ctx, req)
+	}
+	v, err := hedge(ctx, cs.hedgeDelay,
+		func(ctx context.Context) (interface{}, error) {
+			return cs.currencyClient(ctx).Convert(ctx, req)
+		},
+		func(ctx context.Context) (interface{}, error) {
+			if c, ok := cs.regionalCurrencyClient(ctx); ok {
+				return c.Convert(ctx, req)
+			}
+			return pb.NewCurrencyServiceClient(cs.currencyHedgeConn).Convert(ctx, req)
+		},
+		cs.countHedge("currencyservice"))
+	if err != nil {
+		return nil, err
+	}
+	return v.(*pb.Money), nil
+}
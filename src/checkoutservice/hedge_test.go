@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestHedgeFastPrimaryIsNotHedged(t *testing.T) {
+	hedged := false
+	v, err := hedge(context.Background(), time.Second,
+		func(context.Context) (interface{}, error) { return "primary", nil },
+		func(context.Context) (interface{}, error) { return "backup", nil },
+		func() { hedged = true })
+	if err != nil || v != "primary" {
+		t.Errorf("hedge() = %v, %v; want primary", v, err)
+	}
+	if hedged {
+		t.Error("a fast primary call should not be hedged")
+	}
+}
+
+func TestHedgeFailedBackupWaitsForPrimary(t *testing.T) {
+	v, err := hedge(context.Background(), time.Millisecond,
+		func(context.Context) (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			return "primary", nil
+		},
+		func(context.Context) (interface{}, error) { return nil, errors.New("unavailable") },
+		func() {})
+	if err != nil || v != "primary" {
+		t.Errorf("hedge() = %v, %v; want primary", v, err)
+	}
+}
+
+func TestGetProductHedgedOnFasterReplica(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.delay = time.Second
+	cs := newTestCheckoutService(t, f)
+	m := &recordingMetrics{}
+	cs.metrics = m
+
+	fast := &fakeProductCatalogService{products: f.catalog.products}
+	addr := startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterProductCatalogServiceServer(s, fast)
+	})
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	cs.hedgeDelay = 20 * time.Millisecond
+	cs.productCatalogHedgeConn = conn
+	cs.currencyHedgeConn = cs.currencySvcConn
+
+	start := time.Now()
+	p, err := cs.getProduct(context.Background(), "OLJCESPC7Z")
+	if err != nil {
+		t.Fatalf("getProduct() failed: %v", err)
+	}
+	if p.GetId() != "OLJCESPC7Z" {
+		t.Errorf("getProduct() = %v, want OLJCESPC7Z", p)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("getProduct() took %v, want the faster replica to win", elapsed)
+	}
+	if n := m.count(hedgedRequestsMetric, map[string]string{"service": "productcatalogservice"}); n != 1 {
+		t.Errorf("counted %d hedged requests, want 1", n)
+	}
+}
+
+func TestConvertMoneyHedgedInRegion(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	m := &recordingMetrics{}
+	cs.metrics = m
+
+	dial := func(svc *fakeCurrencyService) *grpc.ClientConn {
+		addr := startFakeServer(t, func(s *grpc.Server) {
+			pb.RegisterCurrencyServiceServer(s, svc)
+		})
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	eu := &fakeCurrencyService{rates: map[string]float64{"EUR": 0.5}, delay: 50 * time.Millisecond}
+	backup := &fakeCurrencyService{rates: map[string]float64{"EUR": 0.5}}
+	cs.currencyRegions = map[string]*regionalConn{"eu": {conn: dial(eu)}}
+	cs.hedgeDelay = time.Millisecond
+	cs.currencyHedgeConn = dial(backup)
+
+	ctx := withRegion(context.Background(), "eu")
+	if _, err := cs.convertMoney(ctx, &pb.CurrencyConversionRequest{
+		From: &pb.Money{CurrencyCode: "USD", Units: 10}, ToCode: "EUR"}); err != nil {
+		t.Fatalf("convertMoney() failed: %v", err)
+	}
+	if n := m.count(hedgedRequestsMetric, map[string]string{"service": "currencyservice"}); n != 1 {
+		t.Errorf("counted %d hedged requests, want 1", n)
+	}
+	if backup.callCount() != 0 || f.currency.callCount() != 0 {
+		t.Errorf("got %d hedge and %d global conversions, want all of them in the EU", backup.callCount(), f.currency.callCount())
+	}
+}
+
+func TestDownstreamsWithoutHedgeAddr(t *testing.T) {
+	cs := &checkoutService{hedgeDelay: time.Millisecond, productCatalogHedgeAddr: "catalog-hedge:3550"}
+	var hedged []string
+	for _, d := range cs.downstreams() {
+		if d.conn == &cs.productCatalogHedgeConn || d.conn == &cs.currencyHedgeConn {
+			hedged = append(hedged, d.name)
+		}
+	}
+	if len(hedged) != 1 || hedged[0] != "productcatalogservice" {
+		t.Errorf("hedge connections for %v, want only productcatalogservice", hedged)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// parseItemDetail parses an item detail level, "full" or "summary".
+func parseItemDetail(v string) (pb.ItemDetail, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "full":
+		return pb.ItemDetail_ITEM_DETAIL_FULL, nil
+	case "summary":
+		return pb.ItemDetail_ITEM_DETAIL_SUMMARY, nil
+	}
+	return 0, fmt.Errorf("unsupported item detail level %q, expected full or summary", v)
+}
+
+// itemDetail returns the detail level of the items of an order response,
+// the one requested unless it exceeds the configured maximum.
+func (cs *checkoutService) itemDetail(requested pb.ItemDetail) pb.ItemDetail {
+	if cs.maxItemDetail == pb.ItemDetail_ITEM_DETAIL_SUMMARY {
+		return pb.ItemDetail_ITEM_DETAIL_SUMMARY
+	}
+	return requested
+}
+
+// withItemDetail returns order with its items detailed at level. A
+// summarized order is a copy, order itself may be kept with its products.
+func withItemDetail(order *pb.OrderResult, level pb.ItemDetail) *pb.OrderResult {
+	if level != pb.ItemDetail_ITEM_DETAIL_SUMMARY {
+		return order
+	}
+	summary := proto.Clone(order).(*pb.OrderResult)
+	for _, it := range summary.GetItems() {
+		it.Product = nil
+	}
+	return summary
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderItemDetail(t *testing.T) {
+	tests := []struct {
+		name        string
+		requested   pb.ItemDetail
+		max         pb.ItemDetail
+		wantProduct bool
+	}{
+		{"full by default", pb.ItemDetail_ITEM_DETAIL_FULL, pb.ItemDetail_ITEM_DETAIL_FULL, true},
+		{"summary requested", pb.ItemDetail_ITEM_DETAIL_SUMMARY, pb.ItemDetail_ITEM_DETAIL_FULL, false},
+		{"summary at most", pb.ItemDetail_ITEM_DETAIL_FULL, pb.ItemDetail_ITEM_DETAIL_SUMMARY, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.catalog.products["OLJCESPC7Z"].Description = "This typewriter looks good in your living room."
+			cs := newTestCheckoutService(t, f)
+			cs.maxItemDetail = tt.max
+			req := testOrderRequest()
+			req.ItemDetail = tt.requested
+
+			resp, err := cs.PlaceOrder(context.Background(), req)
+			if err != nil {
+				t.Fatalf("PlaceOrder() failed: %v", err)
+			}
+			items := resp.GetOrder().GetItems()
+			if len(items) != 2 {
+				t.Fatalf("got %d items, want 2", len(items))
+			}
+			it := items[0]
+			if it.GetItem().GetProductId() != "OLJCESPC7Z" || it.GetCost().GetUnits() != 19 {
+				t.Errorf("item = %v, want the id and cost of OLJCESPC7Z", it)
+			}
+			if got := it.GetProduct() != nil; got != tt.wantProduct {
+				t.Errorf("item has product %v, want product: %v", it.GetProduct(), tt.wantProduct)
+			}
+			if tt.wantProduct && it.GetProduct().GetDescription() == "" {
+				t.Errorf("product %v has no description", it.GetProduct())
+			}
+		})
+	}
+}
+
+func TestGetOrderStatusItemDetail(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.startOrderWorkers(1, 10)
+	defer cs.stopOrderWorkers(time.Now().Add(5 * time.Second))
+
+	req := testOrderRequest()
+	req.ItemDetail = pb.ItemDetail_ITEM_DETAIL_SUMMARY
+	resp, err := cs.SubmitOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	got := waitForOrderStatus(t, cs, resp.GetOrderId())
+	if got.GetStatus() != pb.OrderStatus_ORDER_STATUS_COMPLETED {
+		t.Fatalf("order status = %v (%s), want COMPLETED", got.GetStatus(), got.GetError())
+	}
+	items := got.GetOrder().GetItems()
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	for _, it := range items {
+		if it.GetProduct() != nil || it.GetItem().GetProductId() == "" {
+			t.Errorf("item = %v, want a summarized item", it)
+		}
+	}
+}
+
+func TestParseItemDetail(t *testing.T) {
+	for v, want := range map[string]pb.ItemDetail{
+		"full":      pb.ItemDetail_ITEM_DETAIL_FULL,
+		" Summary ": pb.ItemDetail_ITEM_DETAIL_SUMMARY,
+	} {
+		if got, err := parseItemDetail(v); err != nil || got != want {
+			t.Errorf("parseItemDetail(%q) = %v, %v, want %v", v, got, err, want)
+		}
+	}
+	if _, err := parseItemDetail("ids"); err == nil {
+		t.Error("parseItemDetail(\"ids\") should fail")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const defaultMaxItemNoteLength = 256
+
+// validateItemNotes checks the notes of the guest items and the item notes
+// of req against the maximum note length. Notes are refused altogether
+// unless enabled.
+func (cs *checkoutService) validateItemNotes(v *violations, req *pb.PlaceOrderRequest) {
+	check := func(field, note string) {
+		switch {
+		case note == "":
+		case cs.maxItemNoteLength <= 0:
+			v.add(field, "item notes are not available")
+		case len(note) > cs.maxItemNoteLength:
+			v.add(field, "note exceeds %d bytes", cs.maxItemNoteLength)
+		}
+	}
+	for i, it := range req.GetGuestItems() {
+		check(fmt.Sprintf("guest_items[%d].note", i), it.GetNote())
+	}
+	ids := make([]string, 0, len(req.GetItemNotes()))
+	for id := range req.GetItemNotes() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		check(fmt.Sprintf("item_notes[%q]", id), req.GetItemNotes()[id])
+	}
+}
+
+// applyItemNotes returns items with the notes of their product, which must
+// all be in items. Annotated items are copies, items is left untouched.
+func applyItemNotes(items []*pb.CartItem, notes map[string]string) ([]*pb.CartItem, error) {
+	if len(notes) == 0 {
+		return items, nil
+	}
+	out := make([]*pb.CartItem, len(items))
+	applied := make(map[string]bool, len(notes))
+	for i, it := range items {
+		if note, ok := notes[it.GetProductId()]; ok {
+			it = proto.Clone(it).(*pb.CartItem)
+			it.Note = note
+			applied[it.GetProductId()] = true
+		}
+		out[i] = it
+	}
+	for id := range notes {
+		if !applied[id] {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot attach a note to product %q, it is not in the cart", id)
+		}
+	}
+	return out, nil
+}
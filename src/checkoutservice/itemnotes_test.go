@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderKeepsItemNotes(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.maxItemNoteLength = defaultMaxItemNoteLength
+	cs.mergeGuestCart = true
+
+	req := testOrderRequest()
+	req.ItemNotes = map[string]string{"OLJCESPC7Z": "PO 4500012345"}
+	req.GuestItems = []*pb.CartItem{{ProductId: "9SIQT8TOJO", Quantity: 1, Note: "Leave at the loading dock"}}
+	f.catalog.products["9SIQT8TOJO"] = &pb.Product{Id: "9SIQT8TOJO", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 5}}
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+
+	want := map[string]string{"OLJCESPC7Z": "PO 4500012345", "66VCHSJNUP": "", "9SIQT8TOJO": "Leave at the loading dock"}
+	check := func(where string, items []*pb.CartItem) {
+		t.Helper()
+		if len(items) != len(want) {
+			t.Fatalf("%s has %d items, want %d", where, len(items), len(want))
+		}
+		for _, it := range items {
+			if it.GetNote() != want[it.GetProductId()] {
+				t.Errorf("%s note of %s = %q, want %q", where, it.GetProductId(), it.GetNote(), want[it.GetProductId()])
+			}
+		}
+	}
+	cartItems := func(items []*pb.OrderItem) []*pb.CartItem {
+		var out []*pb.CartItem
+		for _, it := range items {
+			out = append(out, it.GetItem())
+		}
+		return out
+	}
+	check("order", cartItems(resp.GetOrder().GetItems()))
+	check("shipment", f.shipping.shipped[0].GetItems())
+	check("confirmation", cartItems(f.email.sent[0].GetOrder().GetItems()))
+	if note := f.cart.carts["user-1"][0].GetNote(); note != "" {
+		t.Errorf("note %q was attached to the stored cart item", note)
+	}
+}
+
+func TestPlaceOrderRejectsInvalidItemNotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxLen  int
+		notes   map[string]string
+		wantErr codes.Code
+	}{
+		{"disabled", 0, map[string]string{"OLJCESPC7Z": "PO 1"}, codes.InvalidArgument},
+		{"too long", 8, map[string]string{"OLJCESPC7Z": strings.Repeat("x", 9)}, codes.InvalidArgument},
+		{"not in cart", 8, map[string]string{"2ZYFJ3GM2N": "PO 1"}, codes.InvalidArgument},
+		{"valid", 8, map[string]string{"OLJCESPC7Z": strings.Repeat("x", 8)}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.maxItemNoteLength = tt.maxLen
+			req := testOrderRequest()
+			req.ItemNotes = tt.notes
+
+			_, err := cs.PlaceOrder(context.Background(), req)
+			if status.Code(err) != tt.wantErr {
+				t.Errorf("PlaceOrder() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
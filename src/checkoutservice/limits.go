@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	maxMetadataEntries     = 16
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 1024
+
+	defaultMaxOrdersPerUser = 1
+
+	// orderItemFootprint approximates the memory, in bytes, taken by each
+	// line of an order while it is placed: the cart item, the order item and
+	// its cost, and the conversion record with its two amounts.
+	orderItemFootprint = 512
+)
+
+// validateMetadata enforces the count and size limits of the metadata
+// attached to an order.
+func validateMetadata(v *violations, md map[string]string) {
+	if len(md) > maxMetadataEntries {
+		v.add("metadata", "has %d entries, at most %d are allowed", len(md), maxMetadataEntries)
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		field := fmt.Sprintf("metadata[%q]", k)
+		if k == "" || len(k) > maxMetadataKeyLength {
+			v.add(field, "key must be between 1 and %d bytes", maxMetadataKeyLength)
+		}
+		if len(md[k]) > maxMetadataValueLength {
+			v.add(field, "value exceeds %d bytes", maxMetadataValueLength)
+		}
+	}
+}
+
+// metadataField describes the values accepted for a metadata key.
+type metadataField struct {
+	required bool
+	// kind is "string", "int", "bool" or "enum".
+	kind    string
+	allowed map[string]bool
+}
+
+// metadataSchema constrains the metadata of orders, by key. Keys missing
+// from the schema are accepted as long as they fit the size limits.
+type metadataSchema map[string]metadataField
+
+// parseMetadataSchema parses a comma-separated list of KEY:TYPE[:required]
+// entries, where TYPE is string, int, bool or a |-separated list of allowed
+// values, e.g. "channel:web|mobile:required,priority:int".
+func parseMetadataSchema(v string) (metadataSchema, error) {
+	out := make(metadataSchema)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid metadata field %q, expected KEY:TYPE[:required]", entry)
+		}
+		f := metadataField{kind: parts[1]}
+		if len(parts) == 3 {
+			if parts[2] != "required" {
+				return nil, fmt.Errorf("invalid metadata field %q, expected KEY:TYPE[:required]", entry)
+			}
+			f.required = true
+		}
+		switch f.kind {
+		case "string", "int", "bool":
+		default:
+			f.kind = "enum"
+			f.allowed = make(map[string]bool)
+			for _, value := range strings.Split(parts[1], "|") {
+				f.allowed[value] = true
+			}
+		}
+		out[parts[0]] = f
+	}
+	return out, nil
+}
+
+// validate reports the metadata entries which do not conform to the schema
+// and the required keys which are missing.
+func (s metadataSchema) validate(v *violations, md map[string]string) {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f := s[k]
+		field := fmt.Sprintf("metadata[%q]", k)
+		value, ok := md[k]
+		if !ok {
+			if f.required {
+				v.add(field, "is required")
+			}
+			continue
+		}
+		switch f.kind {
+		case "int":
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				v.add(field, "%q is not an integer", value)
+			}
+		case "bool":
+			if _, err := strconv.ParseBool(value); err != nil {
+				v.add(field, "%q is not a boolean", value)
+			}
+		case "enum":
+			if !f.allowed[value] {
+				v.add(field, "%q is not an allowed value", value)
+			}
+		}
+	}
+}
+
+// checkDistinctProducts rejects carts containing more distinct products than
+// allowed by maxDistinctProducts. A limit of zero disables the check.
+func (cs *checkoutService) checkDistinctProducts(items []*pb.CartItem) error {
+	if cs.maxDistinctProducts <= 0 {
+		return nil
+	}
+	distinct := make(map[string]struct{}, len(items))
+	for _, it := range items {
+		distinct[it.GetProductId()] = struct{}{}
+	}
+	if len(distinct) > cs.maxDistinctProducts {
+		return status.Errorf(codes.InvalidArgument, "order contains %d distinct products, at most %d are allowed", len(distinct), cs.maxDistinctProducts)
+	}
+	return nil
+}
+
+// checkItemsMemory rejects carts whose order items would take, by estimate,
+// more than maxItemsBytes of memory, before they are allocated. A budget of
+// zero disables the check.
+func (cs *checkoutService) checkItemsMemory(items []*pb.CartItem) error {
+	if cs.maxItemsBytes <= 0 {
+		return nil
+	}
+	if n := len(items) * orderItemFootprint; n > cs.maxItemsBytes {
+		return status.Errorf(codes.ResourceExhausted, "order of %d items would take about %d bytes, at most %d are allowed", len(items), n, cs.maxItemsBytes)
+	}
+	return nil
+}
+
+// userOrderLimiter bounds the number of orders placed concurrently by a
+// single user, e.g. when a checkout button is clicked twice.
+type userOrderLimiter struct {
+	mu       sync.Mutex
+	max      int
+	inFlight map[string]int
+}
+
+func newUserOrderLimiter(max int) *userOrderLimiter {
+	return &userOrderLimiter{max: max, inFlight: make(map[string]int)}
+}
+
+// acquire reserves an order slot for userID, reporting false if the user
+// already has the maximum number of orders in flight.
+func (l *userOrderLimiter) acquire(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[userID] >= l.max {
+		return false
+	}
+	l.inFlight[userID]++
+	return true
+}
+
+func (l *userOrderLimiter) release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[userID]--; l.inFlight[userID] <= 0 {
+		delete(l.inFlight, userID)
+	}
+}
+
+// rpcBudget bounds the number of downstream calls of an order.
+type rpcBudget struct {
+	max  int
+	left int64
+}
+
+type rpcBudgetKey struct{}
+
+// withRPCBudget caps to n the downstream calls made with the returned
+// context, retries included, whatever the size of the cart.
+func withRPCBudget(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, rpcBudgetKey{}, &rpcBudget{max: n, left: int64(n)})
+}
+
+// withoutRPCBudget lifts the budget of ctx, e.g. once the order is charged
+// and failing it would leave the payment behind.
+func withoutRPCBudget(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rpcBudgetKey{}, (*rpcBudget)(nil))
+}
+
+// unbudgetedMethods settle or release payments, they are never refused so
+// that a spent budget does not leave a hold on the card.
+var unbudgetedMethods = map[string]bool{
+	"/hipstershop.PaymentService/Capture": true,
+	"/hipstershop.PaymentService/Void":    true,
+}
+
+// rpcBudgetInterceptor fails the calls made once the budget of their
+// context is spent with ResourceExhausted, without issuing them.
+func rpcBudgetInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if unbudgetedMethods[method] {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if b, ok := ctx.Value(rpcBudgetKey{}).(*rpcBudget); ok && b != nil && atomic.AddInt64(&b.left, -1) < 0 {
+		return status.Errorf(codes.ResourceExhausted, "order exceeds the maximum of %d downstream calls, not calling %s", b.max, method)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
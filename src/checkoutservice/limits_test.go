@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderDistinctProductLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		wantCode codes.Code
+	}{
+		{"unlimited", 0, codes.OK},
+		{"at limit", 2, codes.OK},
+		{"over limit", 1, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			// The same product listed twice only counts once.
+			f.cart.carts["user-1"] = append(f.cart.carts["user-1"], &pb.CartItem{ProductId: "OLJCESPC7Z", Quantity: 1})
+			cs := newTestCheckoutService(t, f)
+			cs.maxDistinctProducts = tt.limit
+
+			_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("PlaceOrder() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK && f.payment.chargeCount() != 0 {
+				t.Error("card should not be charged when the order is rejected")
+			}
+		})
+	}
+}
+
+func TestPlaceOrderItemsMemoryBudget(t *testing.T) {
+	f := newFakeDownstreams()
+	var items []*pb.CartItem
+	for i := 0; i < 10000; i++ {
+		items = append(items, &pb.CartItem{ProductId: "OLJCESPC7Z", Quantity: 1})
+	}
+	f.cart.carts["user-1"] = items
+	cs := newTestCheckoutService(t, f)
+	cs.maxItemsBytes = 1 << 20
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("PlaceOrder() = %v, want ResourceExhausted", err)
+	}
+	if f.catalog.calls != 0 {
+		t.Errorf("catalog served %d products, want the order rejected before pricing", f.catalog.calls)
+	}
+
+	cs.maxItemsBytes = 10 << 20
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Errorf("PlaceOrder() within the budget failed: %v", err)
+	}
+}
+
+func TestPlaceOrderMetadataPassthrough(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.Metadata = map[string]string{
+		giftMessageKey:    "Happy birthday!",
+		"source_campaign": "spring-sale",
+	}
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if got := resp.GetOrder().GetMetadata(); got["source_campaign"] != "spring-sale" || got[giftMessageKey] != "Happy birthday!" {
+		t.Errorf("order metadata = %v, want the request metadata", got)
+	}
+	if n := f.email.sentCount(); n != 1 {
+		t.Fatalf("expected one confirmation, got %d", n)
+	}
+	if got := f.email.sent[0].GetGiftMessage(); got != "Happy birthday!" {
+		t.Errorf("gift message sent to email service = %q, want %q", got, "Happy birthday!")
+	}
+}
+
+func TestPlaceOrderRejectsOversizedMetadata(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxMetadataEntries; i++ {
+		tooMany[fmt.Sprintf("key-%d", i)] = "v"
+	}
+	tests := []struct {
+		name string
+		md   map[string]string
+	}{
+		{"too many entries", tooMany},
+		{"value too long", map[string]string{giftMessageKey: strings.Repeat("x", maxMetadataValueLength+1)}},
+		{"key too long", map[string]string{strings.Repeat("k", maxMetadataKeyLength+1): "v"}},
+		{"empty key", map[string]string{"": "v"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+
+			req := testOrderRequest()
+			req.Metadata = tt.md
+			_, err := cs.PlaceOrder(context.Background(), req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("PlaceOrder() code = %v, want InvalidArgument", status.Code(err))
+			}
+			if f.cart.getCalls != 0 {
+				t.Error("invalid metadata should be rejected before fetching the cart")
+			}
+		})
+	}
+}
+
+func TestPlaceOrderMetadataSchema(t *testing.T) {
+	schema, err := parseMetadataSchema("channel:web|mobile:required,priority:int,gift:bool")
+	if err != nil {
+		t.Fatalf("parseMetadataSchema() failed: %v", err)
+	}
+	tests := []struct {
+		name     string
+		md       map[string]string
+		wantCode codes.Code
+	}{
+		{"conformant", map[string]string{"channel": "web", "priority": "2", "gift": "true", "other": "anything"}, codes.OK},
+		{"required only", map[string]string{"channel": "mobile"}, codes.OK},
+		{"missing required key", map[string]string{"priority": "2"}, codes.InvalidArgument},
+		{"value not allowed", map[string]string{"channel": "fax"}, codes.InvalidArgument},
+		{"not an integer", map[string]string{"channel": "web", "priority": "high"}, codes.InvalidArgument},
+		{"not a boolean", map[string]string{"channel": "web", "gift": "maybe"}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.metadataSchema = schema
+
+			req := testOrderRequest()
+			req.Metadata = tt.md
+			_, err := cs.PlaceOrder(context.Background(), req)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("PlaceOrder() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestParseMetadataSchemaRejectsInvalidEntries(t *testing.T) {
+	for _, v := range []string{"channel", ":int", "priority:int:optional", "a:b:c:d"} {
+		if _, err := parseMetadataSchema(v); err == nil {
+			t.Errorf("parseMetadataSchema(%q) succeeded, want an error", v)
+		}
+	}
+}
+
+func TestPlaceOrderConcurrentOrdersPerUser(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.delay = 200 * time.Millisecond
+	cs := newTestCheckoutService(t, f)
+	cs.userOrders = newUserOrderLimiter(1)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			errs <- err
+		}()
+	}
+	var ok, aborted int
+	for i := 0; i < 2; i++ {
+		switch err := <-errs; status.Code(err) {
+		case codes.OK:
+			ok++
+		case codes.Aborted:
+			aborted++
+		default:
+			t.Errorf("PlaceOrder() = %v, want OK or Aborted", err)
+		}
+	}
+	if ok != 1 || aborted != 1 {
+		t.Errorf("got %d placed and %d aborted orders, want one of each", ok, aborted)
+	}
+
+	// The slot is released once the first order completes.
+	f.catalog.delay = 0
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Errorf("PlaceOrder() after the first completed = %v, want success", err)
+	}
+}
+
+func TestPlaceOrderMaxRPCs(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		wantCode codes.Code
+	}{
+		{"unlimited", 0, codes.OK},
+		{"large enough", 100, codes.OK},
+		{"exceeded", 20, codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			var items []*pb.CartItem
+			for i := 0; i < 30; i++ {
+				id := fmt.Sprintf("P%02d", i)
+				f.catalog.products[id] = &pb.Product{Id: id, PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 1}}
+				items = append(items, &pb.CartItem{ProductId: id, Quantity: 1})
+			}
+			f.cart.carts["user-1"] = items
+			cs := newTestCheckoutService(t, f)
+			cs.maxRPCsPerOrder = tt.limit
+
+			_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("PlaceOrder() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK && f.payment.chargeCount() != 0 {
+				t.Error("card should not be charged when the order is rejected")
+			}
+		})
+	}
+}
+
+func TestPlaceOrderRPCBudgetEndsAtCharge(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	tr := &recordingTracer{}
+	cs.tracer = tr
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	// Calls up to the charge included.
+	var budget int
+	for _, s := range tr.finished("checkout.downstream_call") {
+		budget++
+		if s.tags["rpc"] == "/hipstershop.PaymentService/Charge" {
+			break
+		}
+	}
+
+	f = newFakeDownstreams()
+	cs = newTestCheckoutService(t, f)
+	cs.maxRPCsPerOrder = budget
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() with a budget of %d calls = %v, want the calls after the charge not counted", budget, err)
+	}
+	if len(f.shipping.shipped) != 1 || f.email.sentCount() != 1 {
+		t.Errorf("shipped %d times and sent %d confirmations, want 1 each", len(f.shipping.shipped), f.email.sentCount())
+	}
+}
+
+func TestRPCBudgetNeverRefusesVoidsAndCaptures(t *testing.T) {
+	ctx := withRPCBudget(context.Background(), 0)
+	invoked := func(method string) bool {
+		var called bool
+		rpcBudgetInterceptor(ctx, method, nil, nil, nil, func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+			called = true
+			return nil
+		})
+		return called
+	}
+	if invoked("/hipstershop.PaymentService/Charge") {
+		t.Error("Charge was invoked with a spent budget")
+	}
+	for _, m := range []string{"/hipstershop.PaymentService/Void", "/hipstershop.PaymentService/Capture"} {
+		if !invoked(m) {
+			t.Errorf("%s was refused with a spent budget", m)
+		}
+	}
+}
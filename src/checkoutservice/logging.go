@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields lists the proto fields whose values must never be logged.
+var sensitiveFields = map[protoreflect.FullName]bool{
+	"hipstershop.CreditCardInfo.credit_card_number":           true,
+	"hipstershop.CreditCardInfo.credit_card_cvv":              true,
+	"hipstershop.CreditCardInfo.credit_card_expiration_year":  true,
+	"hipstershop.CreditCardInfo.credit_card_expiration_month": true,
+}
+
+// loggingUnaryInterceptor logs the method, duration and redacted payloads of
+// every unary call at debug level.
+func loggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	if log.IsLevelEnabled(logrus.DebugLevel) {
+		log.WithFields(logrus.Fields{
+			"method":   info.FullMethod,
+			"duration": time.Since(start).String(),
+			"code":     status.Code(err).String(),
+			"request":  redactedJSON(req),
+			"response": redactedJSON(resp),
+		}).Debug("handled request")
+	}
+	return resp, err
+}
+
+// redactedJSON renders v as proto JSON with all sensitive fields masked. The
+// original message is left untouched.
+func redactedJSON(v interface{}) string {
+	m, ok := v.(proto.Message)
+	if !ok || m == nil {
+		return ""
+	}
+	clone := proto.MessageV2(proto.Clone(m))
+	redact(clone.ProtoReflect())
+	b, err := protojson.Marshal(clone)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// redact walks the message using its descriptor and masks sensitive fields,
+// recursing into nested messages, lists and maps.
+func redact(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if sensitiveFields[fd.FullName()] {
+			if fd.Kind() == protoreflect.StringKind {
+				m.Set(fd, protoreflect.ValueOfString(redactedValue))
+			} else {
+				m.Clear(fd)
+			}
+			return true
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				redact(l.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redact(mv.Message())
+				return true
+			})
+		case fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			redact(v.Message())
+		}
+		return true
+	})
+}
+
+// sensitiveConfigFields lists fragments of the names of the fields whose
+// values must never be logged.
+var sensitiveConfigFields = []string{"secret", "token", "password", "credential", "apikey", "dsn"}
+
+// redactedConfig renders the configuration of the service like %+v does,
+// with the values of the sensitive fields masked.
+func (cs *checkoutService) redactedConfig() string {
+	return redactConfig(cs)
+}
+
+// redactConfig renders the struct, or pointer to struct, v like %+v does,
+// masking the non-zero values of the fields deemed sensitive by their name.
+func redactConfig(v interface{}) string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; i < rv.NumField(); i++ {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		name, field := rv.Type().Field(i).Name, rv.Field(i)
+		if isSensitiveField(name) && !field.IsZero() {
+			fmt.Fprintf(&b, "%s:%s", name, redactedValue)
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%+v", name, field)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveConfigFields {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
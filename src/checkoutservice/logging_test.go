@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestLoggingInterceptorRedactsCard(t *testing.T) {
+	var buf bytes.Buffer
+	out, lvl := log.Out, log.GetLevel()
+	log.Out = &buf
+	log.SetLevel(logrus.DebugLevel)
+	defer func() {
+		log.Out = out
+		log.SetLevel(lvl)
+	}()
+
+	req := &pb.PlaceOrderRequest{
+		UserId: "user-42",
+		CreditCard: &pb.CreditCardInfo{
+			CreditCardNumber: "4432801561520454",
+			CreditCardCvv:    672,
+		},
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.PlaceOrderResponse{}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/hipstershop.CheckoutService/PlaceOrder"}
+	if _, err := loggingUnaryInterceptor(context.Background(), req, info, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	if !strings.Contains(got, "user-42") {
+		t.Errorf("log should contain the user id, got %s", got)
+	}
+	if strings.Contains(got, "4432801561520454") || strings.Contains(got, "creditCardCvv") {
+		t.Errorf("log leaks card details: %s", got)
+	}
+	if !strings.Contains(got, redactedValue) {
+		t.Errorf("log should contain a masked card number, got %s", got)
+	}
+	if req.CreditCard.CreditCardNumber != "4432801561520454" {
+		t.Error("redaction must not modify the original request")
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	cfg := struct {
+		cartSvcAddr   string
+		webhookSecret string
+		apiToken      []byte
+		dbPassword    string
+		retries       int
+	}{
+		cartSvcAddr:   "cartservice:7070",
+		webhookSecret: "s3cr3t",
+		apiToken:      []byte("t0k3n"),
+		retries:       2,
+	}
+
+	got := redactConfig(&cfg)
+	for _, leaked := range []string{"s3cr3t", "t0k3n", "116 48"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("redactConfig() = %q, leaks %q", got, leaked)
+		}
+	}
+	for _, want := range []string{"cartSvcAddr:cartservice:7070", "webhookSecret:" + redactedValue, "apiToken:" + redactedValue, "dbPassword: ", "retries:2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("redactConfig() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestRedactedConfigOfService(t *testing.T) {
+	cs := &checkoutService{cartSvcAddr: "cartservice:7070", maxRequestRetries: 3}
+	got := cs.redactedConfig()
+	for _, want := range []string{"cartSvcAddr:cartservice:7070", "maxRequestRetries:3"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("redactedConfig() = %q, want it to contain %q", got, want)
+		}
+	}
+}
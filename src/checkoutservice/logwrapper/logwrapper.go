@@ -1,6 +1,7 @@
 package logwrapper
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -28,3 +29,40 @@ func NewLogger() *StandardLogger {
 
 	return standardLogger
 }
+
+// SampledLogger only emits one in every n info messages, to keep high-volume
+// logs such as per-order events in check. Messages of other levels are never
+// sampled.
+type SampledLogger struct {
+	*StandardLogger
+	n     uint64
+	count uint64
+}
+
+// Sampled returns a logger writing to l which emits one in every n info
+// messages, starting with the first one. All messages are emitted if n is
+// lower than 2.
+func (l *StandardLogger) Sampled(n int) *SampledLogger {
+	if n < 1 {
+		n = 1
+	}
+	return &SampledLogger{StandardLogger: l, n: uint64(n)}
+}
+
+func (l *SampledLogger) sample() bool {
+	return (atomic.AddUint64(&l.count, 1)-1)%l.n == 0
+}
+
+// Info logs a message at info level if it is sampled.
+func (l *SampledLogger) Info(args ...interface{}) {
+	if l.sample() {
+		l.StandardLogger.Info(args...)
+	}
+}
+
+// Infof logs a message at info level if it is sampled.
+func (l *SampledLogger) Infof(format string, args ...interface{}) {
+	if l.sample() {
+		l.StandardLogger.Infof(format, args...)
+	}
+}
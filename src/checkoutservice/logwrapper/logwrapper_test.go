@@ -0,0 +1,55 @@
+package logwrapper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSampledLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger()
+	l.Out = &buf
+	sampled := l.Sampled(4)
+
+	for i := 0; i < 20; i++ {
+		sampled.Infof("order %d placed", i)
+	}
+	sampled.Warnf("payment failed")
+	sampled.Errorf("shipping failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var infos, others int
+	for _, line := range lines {
+		if strings.Contains(line, `"severity":"info"`) {
+			infos++
+		} else {
+			others++
+		}
+	}
+	if infos != 5 {
+		t.Errorf("emitted %d of 20 info lines, want 5", infos)
+	}
+	if others != 2 {
+		t.Errorf("emitted %d warning and error lines, want 2", others)
+	}
+	if !strings.Contains(lines[0], "order 0 placed") {
+		t.Errorf("first line = %s, want the first message to be sampled", lines[0])
+	}
+}
+
+func TestSampledLoggerUnsampled(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger()
+	l.Out = &buf
+	for _, n := range []int{0, 1} {
+		buf.Reset()
+		sampled := l.Sampled(n)
+		for i := 0; i < 3; i++ {
+			sampled.Info("order placed")
+		}
+		if got := strings.Count(buf.String(), "order placed"); got != 3 {
+			t.Errorf("Sampled(%d) emitted %d of 3 lines, want all", n, got)
+		}
+	}
+}
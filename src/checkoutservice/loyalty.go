@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+const (
+	pointsRedemptionFailuresMetric = "checkout_points_redemption_failures_total"
+
+	// pointsRedemptionsDroppedMetric counts the redemptions given up on,
+	// which have to be applied by hand.
+	pointsRedemptionsDroppedMetric = "checkout_points_redemptions_dropped_total"
+
+	defaultRedemptionRetryInterval = 30 * time.Second
+	redemptionRetryTimeout         = 5 * time.Second
+	maxPendingRedemptions          = 1000
+	// maxRedemptionAttempts bounds the attempts of a redemption, the
+	// first one included.
+	maxRedemptionAttempts = 10
+)
+
+// defaultPointValue is what a loyalty point is worth by default.
+var defaultPointValue = &pb.Money{CurrencyCode: usdCurrency, Nanos: 10000000}
+
+// applyPoints deducts from total, in the user currency, the value of the
+// loyalty points req redeems, and records them in prep. The points must
+// not exceed the balance of the user nor be worth more than total.
+func (cs *checkoutService) applyPoints(ctx context.Context, prep *orderPrep, req *pb.PlaceOrderRequest, total pb.Money) (pb.Money, error) {
+	points := req.GetPointsToRedeem()
+	if points == 0 {
+		return total, nil
+	}
+	balance, err := cs.clients().loyalty().GetPointsBalance(ctx, &pb.GetPointsBalanceRequest{UserId: req.GetUserId()})
+	if err != nil {
+		return pb.Money{}, downstreamError(err, "failed to get loyalty points balance")
+	}
+	if points > balance.GetPoints() {
+		return pb.Money{}, status.Errorf(codes.InvalidArgument, "cannot redeem %d loyalty points, the balance is %d", points, balance.GetPoints())
+	}
+	valueUSD := floatToMoney(moneyToFloat(cs.pointValue)*float64(points), usdCurrency)
+	value, err := cs.convertCurrency(withCallResource(ctx, "currency.convert.points"), valueUSD, total.GetCurrencyCode())
+	if err != nil {
+		return pb.Money{}, downstreamError(err, "failed to convert loyalty points value to currency")
+	}
+	left, err := sumAmounts(total.GetCurrencyCode(), []namedAmount{{"total", total}, {"points", money.Negate(*value)}})
+	if err != nil {
+		return pb.Money{}, err
+	}
+	if money.IsNegative(left) {
+		return pb.Money{}, status.Errorf(codes.InvalidArgument, "%d loyalty points are worth more than the order total", points)
+	}
+	prep.pointsRedeemed = points
+	prep.pointsDiscount = value
+	return left, nil
+}
+
+// redeemPoints deducts the points applied to an order from the balance of
+// the user once the order is paid and shipped. The order keeps its discount
+// whatever happens: a failed redemption is counted, logged and queued to be
+// retried, redeeming again for the same order is safe.
+func (cs *checkoutService) redeemPoints(ctx context.Context, orderID, userID string, points int64) {
+	if points == 0 {
+		return
+	}
+	req := &pb.RedeemPointsRequest{UserId: userID, Points: points, OrderId: orderID}
+	if _, err := cs.clients().loyalty().RedeemPoints(ctx, req); err != nil {
+		cs.stats().IncCounter(pointsRedemptionFailuresMetric, nil)
+		cs.redemptionFailed(pendingRedemption{req: req, attempts: 1}, err)
+	}
+}
+
+// redemptionFailed queues a failed redemption for retry, unless the failure
+// is permanent or the redemption was attempted too many times already, in
+// which case it is dropped and logged to be applied by hand.
+func (cs *checkoutService) redemptionFailed(r pendingRedemption, err error) {
+	if permanentRedemptionError(err) || r.attempts >= maxRedemptionAttempts {
+		cs.stats().IncCounter(pointsRedemptionsDroppedMetric, nil)
+		log.Errorf("giving up on the redemption of %d loyalty points of user %q for order %s after %d attempts: %+v",
+			r.req.GetPoints(), r.req.GetUserId(), r.req.GetOrderId(), r.attempts, err)
+		return
+	}
+	log.Warnf("failed to redeem %d loyalty points of order %s (attempt %d), queuing it for retry: %+v",
+		r.req.GetPoints(), r.req.GetOrderId(), r.attempts, err)
+	cs.redemptions.add(r)
+}
+
+// permanentRedemptionError reports whether retrying a redemption which
+// failed with err cannot succeed, e.g. when the balance is insufficient.
+func permanentRedemptionError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound, codes.PermissionDenied, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// pendingRedemption is a failed redemption waiting to be retried.
+type pendingRedemption struct {
+	req      *pb.RedeemPointsRequest
+	attempts int
+}
+
+// redemptionQueue holds the point redemptions which failed, until they are
+// retried.
+type redemptionQueue struct {
+	mu      sync.Mutex
+	pending []pendingRedemption
+	done    chan struct{}
+	retries sync.WaitGroup
+}
+
+// add queues r, unless too many redemptions are pending already.
+func (q *redemptionQueue) add(r pendingRedemption) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= maxPendingRedemptions {
+		log.Errorf("too many pending point redemptions, dropping the one of order %s", r.req.GetOrderId())
+		return
+	}
+	q.pending = append(q.pending, r)
+}
+
+// take removes and returns the pending redemptions.
+func (q *redemptionQueue) take() []pendingRedemption {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.pending
+	q.pending = nil
+	return pending
+}
+
+// retryRedemptions retries every pending redemption once, queuing again
+// those which fail for a reason which may go away.
+func (cs *checkoutService) retryRedemptions() {
+	for _, r := range cs.redemptions.take() {
+		ctx, cancel := context.WithTimeout(context.Background(), redemptionRetryTimeout)
+		_, err := cs.clients().loyalty().RedeemPoints(ctx, r.req)
+		cancel()
+		r.attempts++
+		if err != nil {
+			cs.redemptionFailed(r, err)
+			continue
+		}
+		log.Infof("redeemed %d loyalty points of order %s on retry", r.req.GetPoints(), r.req.GetOrderId())
+	}
+}
+
+// startRedemptionRetries retries the pending redemptions every interval
+// until stopRedemptionRetries is called.
+func (cs *checkoutService) startRedemptionRetries(interval time.Duration) {
+	cs.redemptions.done = make(chan struct{})
+	cs.redemptions.retries.Add(1)
+	go func() {
+		defer cs.redemptions.retries.Done()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				cs.retryRedemptions()
+			case <-cs.redemptions.done:
+				return
+			}
+		}
+	}()
+}
+
+// stopRedemptionRetries stops retrying and waits for the retry in progress,
+// if any, so that the redemptions left are all pending.
+func (cs *checkoutService) stopRedemptionRetries() {
+	if cs.redemptions.done != nil {
+		close(cs.redemptions.done)
+		cs.redemptions.retries.Wait()
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderRedeemsPoints(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.pointValue = defaultPointValue
+
+	req := testOrderRequest()
+	req.PointsToRedeem = 300
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	order := resp.GetOrder()
+	want := &pb.Money{CurrencyCode: "USD", Units: 3}
+	if order.GetPointsRedeemed() != 300 || !proto.Equal(order.GetPointsDiscount(), want) {
+		t.Errorf("order redeemed %d points worth %v, want 300 worth %v", order.GetPointsRedeemed(), order.GetPointsDiscount(), want)
+	}
+	// 726.98 USD, less 3.00 USD of points.
+	charged := &pb.Money{CurrencyCode: "USD", Units: 723, Nanos: 980000000}
+	if len(f.payment.charges) != 1 || !proto.Equal(f.payment.charges[0].GetAmount(), charged) {
+		t.Errorf("charges = %v, want one of %v", f.payment.charges, charged)
+	}
+	if len(f.loyalty.redeemed) != 1 || f.loyalty.redeemed[0].GetOrderId() != order.GetOrderId() {
+		t.Errorf("redeemed %v, want the points of order %s", f.loyalty.redeemed, order.GetOrderId())
+	}
+	if b := f.loyalty.balances["user-1"]; b != 200 {
+		t.Errorf("balance after redemption = %d, want 200", b)
+	}
+}
+
+func TestPlaceOrderRejectsOverRedemption(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.pointValue = defaultPointValue
+
+	req := testOrderRequest()
+	req.PointsToRedeem = 501
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("PlaceOrder() error = %v, want InvalidArgument", err)
+	}
+	if n := f.payment.chargeCount(); n != 0 {
+		t.Errorf("card was charged %d times", n)
+	}
+	if b := f.loyalty.balances["user-1"]; b != 500 {
+		t.Errorf("balance = %d, want it untouched", b)
+	}
+}
+
+func TestPlaceOrderRetriesFailedRedemption(t *testing.T) {
+	f := newFakeDownstreams()
+	f.loyalty.redeemErr = status.Error(codes.Unavailable, "loyalty service down")
+	cs := newTestCheckoutService(t, f)
+	cs.pointValue = defaultPointValue
+	m := &recordingMetrics{}
+	cs.metrics = m
+
+	req := testOrderRequest()
+	req.PointsToRedeem = 300
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if n := m.count(pointsRedemptionFailuresMetric, nil); n != 1 {
+		t.Errorf("counted %d failed redemptions, want 1", n)
+	}
+
+	f.loyalty.mu.Lock()
+	f.loyalty.redeemErr = nil
+	f.loyalty.mu.Unlock()
+	cs.retryRedemptions()
+	if len(f.loyalty.redeemed) != 1 || f.loyalty.redeemed[0].GetOrderId() != resp.GetOrder().GetOrderId() {
+		t.Errorf("redeemed %v, want the points of order %s on retry", f.loyalty.redeemed, resp.GetOrder().GetOrderId())
+	}
+	if b := f.loyalty.balances["user-1"]; b != 200 {
+		t.Errorf("balance after retry = %d, want 200", b)
+	}
+	if pending := cs.redemptions.take(); len(pending) != 0 {
+		t.Errorf("redemptions %v still pending after a successful retry", pending)
+	}
+}
+
+func TestPlaceOrderKeepsPointsWhenShippingFails(t *testing.T) {
+	f := newFakeDownstreams()
+	f.shipping.shipErr = status.Error(codes.Unavailable, "shipping down")
+	cs := newTestCheckoutService(t, f)
+	cs.pointValue = defaultPointValue
+
+	req := testOrderRequest()
+	req.PointsToRedeem = 300
+	if _, err := cs.PlaceOrder(context.Background(), req); err == nil {
+		t.Fatal("PlaceOrder() succeeded, want the shipping failure")
+	}
+	if len(f.loyalty.redeemed) != 0 || f.loyalty.balances["user-1"] != 500 {
+		t.Errorf("redeemed %v, balance %d; want the points untouched", f.loyalty.redeemed, f.loyalty.balances["user-1"])
+	}
+}
+
+func TestRetryRedemptionsGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+	}{
+		{"permanent failure", status.Error(codes.FailedPrecondition, "insufficient points"), 1},
+		{"too many attempts", status.Error(codes.Unavailable, "loyalty service down"), maxRedemptionAttempts - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.loyalty.redeemErr = tt.err
+			cs := newTestCheckoutService(t, f)
+			m := &recordingMetrics{}
+			cs.metrics = m
+
+			cs.redemptions.add(pendingRedemption{
+				req:      &pb.RedeemPointsRequest{UserId: "user-1", Points: 300, OrderId: "order-1"},
+				attempts: tt.attempts})
+			cs.retryRedemptions()
+			if pending := cs.redemptions.take(); len(pending) != 0 {
+				t.Errorf("redemptions %v queued again, want them dropped", pending)
+			}
+			if n := m.count(pointsRedemptionsDroppedMetric, nil); n != 1 {
+				t.Errorf("counted %d dropped redemptions, want 1", n)
+			}
+		})
+	}
+}
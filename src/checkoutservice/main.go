@@ -5,9 +5,18 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/abruneau/hipstershop/src/checkoutservice/logwrapper"
-	"github.com/google/uuid"
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/netutil"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -21,13 +30,23 @@ const (
 	listenPort  = "5050"
 	usdCurrency = "USD"
 	serviceName = "checkoutservice"
+
+	// displayLocale is the locale of the amounts in logs and error messages.
+	displayLocale = "en-US"
+
+	defaultMaxInflightPerRequest = 16
 )
 
 var log *logwrapper.StandardLogger
 
+// orderLog logs the info events of every order, sampled according to
+// ORDER_LOG_SAMPLE_RATE.
+var orderLog *logwrapper.SampledLogger
+
 func init() {
 	log = logwrapper.NewLogger()
 	log.Out = os.Stdout
+	orderLog = log.Sampled(1)
 }
 
 type checkoutService struct {
@@ -37,6 +56,147 @@ type checkoutService struct {
 	shippingSvcAddr       string
 	emailSvcAddr          string
 	paymentSvcAddr        string
+	smsSvcAddr            string
+	loyaltySvcAddr        string
+
+	productCatalogSvcConn *grpc.ClientConn
+	cartSvcConn           *grpc.ClientConn
+	currencySvcConn       *grpc.ClientConn
+	shippingSvcConn       *grpc.ClientConn
+	emailSvcConn          *grpc.ClientConn
+	paymentSvcConn        *grpc.ClientConn
+	smsSvcConn            *grpc.ClientConn
+	loyaltySvcConn        *grpc.ClientConn
+
+	// clientFactory overrides the clients built on the connections above.
+	clientFactory clientFactory
+
+	// Reads to the catalog and currency services are hedged on a second
+	// connection to their hedge address after hedgeDelay, if positive.
+	// Services without a hedge address are not hedged.
+	hedgeDelay              time.Duration
+	productCatalogHedgeAddr string
+	currencyHedgeAddr       string
+	productCatalogHedgeConn *grpc.ClientConn
+	currencyHedgeConn       *grpc.ClientConn
+
+	// currencyRegions routes the conversions of orders shipped to a region
+	// to the currency service of that region.
+	currencyRegions map[string]*regionalConn
+
+	// paymentBrands routes the payments of a card brand to the payment
+	// service of that brand.
+	paymentBrands map[string]*paymentRoute
+
+	warmConns             bool
+	downstreamTLS         bool
+	traceDials            bool
+	downstreamCompression string
+	tlsServerNames        map[string]string
+	optionalDependencies  map[string]bool
+	connectParams         grpc.ConnectParams
+	maxDistinctProducts   int
+	maxItemsBytes         int
+	maxItemNoteLength     int
+	maxInflightPerRequest int
+	maxPricingFanout      int
+	maxItemsPerShipment   int
+	maxDeliveryDays       int
+	giftWrapFee           *pb.Money
+	pointValue            *pb.Money
+	partialFulfillment    bool
+	backorders            bool
+	shippingFees          map[string]*pb.Money
+	maxShippingCost       *pb.Money
+	maxShippingRatio      float64
+	shippingInsurance     *shippingInsurance
+	taxRate               float64
+	coupons               map[string]*coupon
+	freeShippingThreshold *pb.Money
+	discountStacking      string
+	taxExemptProducts     map[string]bool
+	restrictedProducts    map[string]bool
+	userOrders            *userOrderLimiter
+	minChargeAmounts      map[string]*pb.Money
+	chargeAmountCheck     bool
+	chargeAmountTolerance float64
+	currencyRoundTrip     bool
+	roundTripTolerance    float64
+	currencyPrecision     map[string]int
+	roundAmounts          bool
+	roundDisplayAmounts   bool
+	authorizeOnly         bool
+	splitTender           bool
+	passDeclineReasons    bool
+	tagOrderID            bool
+	serviceTimeouts       map[string]time.Duration
+	serviceRetries        map[string]int
+	maxRequestRetries     int
+	maxRPCsPerOrder       int
+	deadlineFloor         time.Duration
+	propagateBudget       bool
+	addressLimits         addressLimits
+	cardExpirySkew        time.Duration
+	metadataSchema        metadataSchema
+	strictCurrencyCodes   bool
+
+	fraudScorer   FraudScorer
+	metrics       Metrics
+	tracer        Tracer
+	orderExporter *orderExporter
+	orderAudit    *orderAuditCSV
+	notifier      Notifier
+	abandonHook   AbandonHook
+	confirmations *confirmationDebouncer
+	emailLimiter  *emailRateLimiter
+
+	// stageTimingTrailer reports the stage durations of PlaceOrder in its
+	// trailing metadata.
+	stageTimingTrailer bool
+	// tagRPCCount tags the PlaceOrder span with the number of downstream
+	// calls it made, in total and per service.
+	tagRPCCount bool
+	// orderETags reports an ETag of the GetOrderStatus responses in their
+	// trailing metadata and honors if-none-match.
+	orderETags bool
+
+	// listAppliedPromotions details every promotion applied to an order in
+	// the applied_promotions of its result.
+	listAppliedPromotions bool
+
+	// maxItemDetail caps the detail level of the items of order responses
+	// whatever the request asks for.
+	maxItemDetail pb.ItemDetail
+
+	// orderSimulation enables SimulateOrder.
+	orderSimulation bool
+
+	maxConfirmationBytes int
+
+	batchCurrencyConversion    bool
+	fallbackRates              fallbackRates
+	batchConversionUnsupported int32
+
+	cartConsistencyRetry bool
+	mergeGuestCart       bool
+	mergeDuplicateItems  bool
+	cartRetryAttempts    int
+	cartRetryDelay       time.Duration
+
+	orders       *orderStore
+	orderNumbers OrderNumberAllocator
+	orderQueue   chan orderJob
+	orderTimeout time.Duration
+	orderWorkers sync.WaitGroup
+	sweepDone    chan struct{}
+
+	// redemptions holds the loyalty point redemptions to retry.
+	redemptions redemptionQueue
+
+	connMonitorDone chan struct{}
+
+	// clock returns the current time, time.Now when nil.
+	clock func() time.Time
 }
 
 func main() {
@@ -46,27 +206,436 @@ func main() {
 	}
 
 	svc := new(checkoutService)
+	svc.orders = newOrderStore()
+	firstOrderNumber := defaultFirstOrderNumber
+	mapEnvInt(&firstOrderNumber, "FIRST_ORDER_NUMBER")
+	svc.orderNumbers = newMemoryOrderNumbers(int64(firstOrderNumber))
+	svc.fraudScorer = allowAllScorer{}
+	svc.metrics = noopMetrics{}
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		m := newPromMetrics()
+		svc.metrics = m
+		go serveMetrics(addr, m)
+	}
+	tracer, err := newTracer(os.Getenv("TRACE_EXPORTER"))
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is invalid: %v", "TRACE_EXPORTER", err))
+	}
+	svc.tracer = tracer
 	mustMapEnv(&svc.shippingSvcAddr, "SHIPPING_SERVICE_ADDR")
 	mustMapEnv(&svc.productCatalogSvcAddr, "PRODUCT_CATALOG_SERVICE_ADDR")
 	mustMapEnv(&svc.cartSvcAddr, "CART_SERVICE_ADDR")
 	mustMapEnv(&svc.currencySvcAddr, "CURRENCY_SERVICE_ADDR")
 	mustMapEnv(&svc.emailSvcAddr, "EMAIL_SERVICE_ADDR")
 	mustMapEnv(&svc.paymentSvcAddr, "PAYMENT_SERVICE_ADDR")
+	svc.smsSvcAddr = os.Getenv("SMS_SERVICE_ADDR")
+	svc.loyaltySvcAddr = os.Getenv("LOYALTY_SERVICE_ADDR")
+	mapEnvDuration(&svc.hedgeDelay, "HEDGE_DELAY")
+	svc.productCatalogHedgeAddr = os.Getenv("PRODUCT_CATALOG_SERVICE_HEDGE_ADDR")
+	svc.currencyHedgeAddr = os.Getenv("CURRENCY_SERVICE_HEDGE_ADDR")
+	if v := os.Getenv("CURRENCY_SERVICE_REGIONS"); v != "" {
+		m, err := parseRegionAddrs(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "CURRENCY_SERVICE_REGIONS", err))
+		}
+		svc.currencyRegions = m
+	}
+	if v := os.Getenv("PAYMENT_SERVICE_BRANDS"); v != "" {
+		m, err := parsePaymentBrands(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "PAYMENT_SERVICE_BRANDS", err))
+		}
+		svc.paymentBrands = m
+	}
+	mapEnvBool(&svc.warmConns, "WARM_CONNECTIONS")
+	mapEnvBool(&svc.stageTimingTrailer, "STAGE_TIMING_TRAILER")
+	mapEnvBool(&svc.orderETags, "ORDER_ETAG")
+	mapEnvBool(&svc.listAppliedPromotions, "APPLIED_PROMOTIONS")
+	mapEnvBool(&svc.tagRPCCount, "TRACE_RPC_COUNT")
+	svc.tagOrderID = true
+	mapEnvBool(&svc.tagOrderID, "DOWNSTREAM_ORDER_ID")
+	mapEnvBool(&svc.downstreamTLS, "DOWNSTREAM_TLS")
+	mapEnvBool(&svc.traceDials, "TRACE_DIAL_ERRORS")
+	if v := os.Getenv("MAX_ITEM_DETAIL"); v != "" {
+		level, err := parseItemDetail(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "MAX_ITEM_DETAIL", err))
+		}
+		svc.maxItemDetail = level
+	}
+	downstreamCompression, err := parseCompression(os.Getenv("DOWNSTREAM_COMPRESSION"))
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is invalid: %v", "DOWNSTREAM_COMPRESSION", err))
+	}
+	svc.downstreamCompression = downstreamCompression
+	svc.tlsServerNames = make(map[string]string)
+	for name, key := range tlsServerNameEnv {
+		if v := os.Getenv(key); v != "" {
+			svc.tlsServerNames[name] = v
+		}
+	}
+	optionalDeps := defaultOptionalDependencies
+	if v, ok := os.LookupEnv("OPTIONAL_DEPENDENCIES"); ok {
+		optionalDeps = v
+	}
+	svc.optionalDependencies = parseDependencySet(optionalDeps)
+	svc.connectParams = defaultConnectParams()
+	mapEnvDuration(&svc.connectParams.Backoff.BaseDelay, "CONNECT_BACKOFF_BASE_DELAY")
+	mapEnvFloat(&svc.connectParams.Backoff.Multiplier, "CONNECT_BACKOFF_MULTIPLIER")
+	mapEnvDuration(&svc.connectParams.Backoff.MaxDelay, "CONNECT_BACKOFF_MAX_DELAY")
+	mapEnvInt(&svc.maxDistinctProducts, "MAX_DISTINCT_PRODUCTS")
+	mapEnvInt(&svc.maxItemsBytes, "MAX_ORDER_ITEMS_BYTES")
+	var itemNotes bool
+	mapEnvBool(&itemNotes, "ITEM_NOTES")
+	if itemNotes {
+		svc.maxItemNoteLength = defaultMaxItemNoteLength
+		mapEnvInt(&svc.maxItemNoteLength, "MAX_ITEM_NOTE_LENGTH")
+	}
+	svc.maxInflightPerRequest = defaultMaxInflightPerRequest
+	mapEnvInt(&svc.maxInflightPerRequest, "MAX_INFLIGHT_PER_REQUEST")
+	mapEnvInt(&svc.maxPricingFanout, "MAX_PRICING_FANOUT")
+	mapEnvInt(&svc.maxItemsPerShipment, "MAX_ITEMS_PER_SHIPMENT")
+	svc.maxDeliveryDays = defaultMaxDeliveryDays
+	mapEnvInt(&svc.maxDeliveryDays, "MAX_DELIVERY_DAYS")
+	mapEnvBool(&svc.partialFulfillment, "PARTIAL_FULFILLMENT")
+	mapEnvBool(&svc.backorders, "BACKORDERS")
+	if v := os.Getenv("GIFT_WRAP_FEE"); v != "" {
+		m, err := parseAmount(usdCurrency, v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "GIFT_WRAP_FEE", err))
+		}
+		svc.giftWrapFee = m
+	}
+	svc.pointValue = defaultPointValue
+	if v := os.Getenv("LOYALTY_POINT_VALUE"); v != "" {
+		m, err := parseAmount(usdCurrency, v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "LOYALTY_POINT_VALUE", err))
+		}
+		svc.pointValue = m
+	}
+	if v := os.Getenv("SHIPPING_FEES"); v != "" {
+		fees, err := parseShippingFees(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "SHIPPING_FEES", err))
+		}
+		svc.shippingFees = fees
+	}
+	if v := os.Getenv("MAX_SHIPPING_COST"); v != "" {
+		m, err := parseAmount(usdCurrency, v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "MAX_SHIPPING_COST", err))
+		}
+		svc.maxShippingCost = m
+	}
+	mapEnvFloat(&svc.maxShippingRatio, "MAX_SHIPPING_SUBTOTAL_RATIO")
+	if v := os.Getenv("SHIPPING_INSURANCE_FEE"); v != "" {
+		ins, err := parseShippingInsurance(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "SHIPPING_INSURANCE_FEE", err))
+		}
+		svc.shippingInsurance = ins
+	}
+	maxOrdersPerUser := defaultMaxOrdersPerUser
+	mapEnvInt(&maxOrdersPerUser, "MAX_ORDERS_PER_USER")
+	if maxOrdersPerUser > 0 {
+		svc.userOrders = newUserOrderLimiter(maxOrdersPerUser)
+	}
+	svc.addressLimits = defaultAddressLimits
+	mapEnvInt(&svc.addressLimits.street, "MAX_STREET_ADDRESS_LENGTH")
+	mapEnvInt(&svc.addressLimits.city, "MAX_CITY_LENGTH")
+	mapEnvInt(&svc.addressLimits.state, "MAX_STATE_LENGTH")
+	mapEnvInt(&svc.addressLimits.country, "MAX_COUNTRY_LENGTH")
+	mapEnvInt(&svc.addressLimits.zipCode, "MAX_ZIP_CODE_LENGTH")
+	svc.cardExpirySkew = defaultCardExpirySkew
+	mapEnvDuration(&svc.cardExpirySkew, "CARD_EXPIRY_SKEW")
+	if v := os.Getenv("METADATA_SCHEMA"); v != "" {
+		schema, err := parseMetadataSchema(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "METADATA_SCHEMA", err))
+		}
+		svc.metadataSchema = schema
+	}
+	svc.strictCurrencyCodes = true
+	mapEnvBool(&svc.strictCurrencyCodes, "STRICT_CURRENCY_CODES")
+	mapEnvBool(&svc.batchCurrencyConversion, "CURRENCY_BATCH_CONVERSION")
+	if v := os.Getenv("CURRENCY_FALLBACK_RATES_FILE"); v != "" {
+		rates, err := loadFallbackRates(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		svc.fallbackRates = rates
+	}
+	svc.cartRetryAttempts = defaultCartRetryAttempts
+	svc.cartRetryDelay = defaultCartRetryDelay
+	mapEnvBool(&svc.cartConsistencyRetry, "CART_CONSISTENCY_RETRY")
+	mapEnvBool(&svc.mergeGuestCart, "MERGE_GUEST_CART")
+	mapEnvBool(&svc.mergeDuplicateItems, "MERGE_DUPLICATE_CART_ITEMS")
+	mapEnvBool(&svc.orderSimulation, "ENABLE_ORDER_SIMULATION")
+	mapEnvInt(&svc.cartRetryAttempts, "CART_RETRY_ATTEMPTS")
+	mapEnvDuration(&svc.cartRetryDelay, "CART_RETRY_DELAY")
+	var confirmationDebounce time.Duration
+	mapEnvDuration(&confirmationDebounce, "CONFIRMATION_DEBOUNCE_WINDOW")
+	if confirmationDebounce > 0 {
+		svc.confirmations = newConfirmationDebouncer(confirmationDebounce)
+		svc.confirmations.startPruning(svc.now)
+	}
+	var emailRateLimit int
+	mapEnvInt(&emailRateLimit, "EMAIL_RATE_LIMIT")
+	emailRateWindow := defaultEmailRateWindow
+	mapEnvDuration(&emailRateWindow, "EMAIL_RATE_WINDOW")
+	if emailRateLimit > 0 {
+		svc.emailLimiter = newEmailRateLimiter(emailRateLimit, emailRateWindow)
+	}
+	svc.maxConfirmationBytes = defaultMaxConfirmationBytes
+	mapEnvInt(&svc.maxConfirmationBytes, "MAX_CONFIRMATION_BYTES")
+	if v := os.Getenv("ORDER_EXPORT_PATH"); v != "" {
+		e, err := newOrderExporter(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		svc.orderExporter = e
+	}
+	if v := os.Getenv("ORDER_AUDIT_CSV_PATH"); v != "" {
+		a, err := newOrderAuditCSV(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		svc.orderAudit = a
+	}
+	var webhook *webhookNotifier
+	if v := os.Getenv("ORDER_WEBHOOK_URL"); v != "" {
+		queueSize, maxAttempts := defaultWebhookQueueSize, defaultWebhookMaxAttempts
+		mapEnvInt(&queueSize, "ORDER_WEBHOOK_QUEUE_SIZE")
+		mapEnvInt(&maxAttempts, "ORDER_WEBHOOK_MAX_ATTEMPTS")
+		webhook = newWebhookNotifier(v, os.Getenv("ORDER_WEBHOOK_SECRET"), queueSize, maxAttempts)
+		svc.notifier = webhook
+	}
+	mapEnvFloat(&svc.taxRate, "TAX_RATE")
+	if v := os.Getenv("COUPONS"); v != "" {
+		m, err := parseCoupons(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "COUPONS", err))
+		}
+		svc.coupons = m
+	}
+	if v := os.Getenv("FREE_SHIPPING_THRESHOLD"); v != "" {
+		m, err := parseAmount(usdCurrency, v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "FREE_SHIPPING_THRESHOLD", err))
+		}
+		svc.freeShippingThreshold = m
+	}
+	if v := os.Getenv("DISCOUNT_STACKING"); v != "" {
+		policy, err := parseDiscountStacking(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "DISCOUNT_STACKING", err))
+		}
+		svc.discountStacking = policy
+	}
+	svc.taxExemptProducts = parseDependencySet(os.Getenv("TAX_EXEMPT_PRODUCTS"))
+	svc.restrictedProducts = parseDependencySet(os.Getenv("RESTRICTED_PRODUCTS"))
+	mapEnvBool(&svc.authorizeOnly, "PAYMENT_AUTHORIZE_ONLY")
+	mapEnvBool(&svc.splitTender, "SPLIT_TENDER")
+	svc.passDeclineReasons = true
+	mapEnvBool(&svc.passDeclineReasons, "PAYMENT_DECLINE_REASONS")
+	if v := os.Getenv("MIN_CHARGE_AMOUNTS"); v != "" {
+		m, err := parseMinChargeAmounts(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "MIN_CHARGE_AMOUNTS", err))
+		}
+		svc.minChargeAmounts = m
+	}
+	mapEnvBool(&svc.chargeAmountCheck, "CHARGE_AMOUNT_CHECK")
+	svc.chargeAmountTolerance = defaultChargeAmountTolerance
+	mapEnvFloat(&svc.chargeAmountTolerance, "CHARGE_AMOUNT_TOLERANCE")
+	mapEnvBool(&svc.currencyRoundTrip, "CURRENCY_ROUND_TRIP_CHECK")
+	svc.roundTripTolerance = defaultRoundTripTolerance
+	mapEnvFloat(&svc.roundTripTolerance, "CURRENCY_ROUND_TRIP_TOLERANCE")
+	if v := os.Getenv("CURRENCY_PRECISION"); v != "" {
+		m, err := parseCurrencyPrecision(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "CURRENCY_PRECISION", err))
+		}
+		svc.currencyPrecision = m
+	}
+	switch v := os.Getenv("AMOUNT_ROUNDING"); v {
+	case "", "truncate":
+	case "round":
+		svc.roundAmounts = true
+	default:
+		panic(fmt.Sprintf("environment variable %q is invalid: expected round or truncate, got %q", "AMOUNT_ROUNDING", v))
+	}
+	switch v := os.Getenv("DISPLAY_AMOUNT_ROUNDING"); v {
+	case "":
+		svc.roundDisplayAmounts = svc.roundAmounts
+	case "truncate":
+	case "round":
+		svc.roundDisplayAmounts = true
+	default:
+		panic(fmt.Sprintf("environment variable %q is invalid: expected round or truncate, got %q", "DISPLAY_AMOUNT_ROUNDING", v))
+	}
 
-	log.Infof("service config: %+v", svc)
+	if v := os.Getenv("SERVICE_RETRIES"); v != "" {
+		m, err := parseServiceRetries(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "SERVICE_RETRIES", err))
+		}
+		svc.serviceRetries = m
+	}
+	mapEnvInt(&svc.maxRequestRetries, "REQUEST_MAX_TOTAL_RETRIES")
+	mapEnvInt(&svc.maxRPCsPerOrder, "MAX_RPCS_PER_ORDER")
+	if v := os.Getenv("SERVICE_TIMEOUTS"); v != "" {
+		m, err := parseServiceTimeouts(v)
+		if err != nil {
+			panic(fmt.Sprintf("environment variable %q is invalid: %v", "SERVICE_TIMEOUTS", err))
+		}
+		svc.serviceTimeouts = m
+	}
+	svc.deadlineFloor = defaultDeadlineFloor
+	mapEnvDuration(&svc.deadlineFloor, "DEADLINE_FLOOR")
+	mapEnvBool(&svc.propagateBudget, "PROPAGATE_REMAINING_BUDGET")
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		lvl, err := logrus.ParseLevel(v)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.SetLevel(lvl)
+	}
+	orderLogSampleRate := 1
+	mapEnvInt(&orderLogSampleRate, "ORDER_LOG_SAMPLE_RATE")
+	orderLog = log.Sampled(orderLogSampleRate)
+
+	log.Infof("service config: %s", svc.redactedConfig())
+
+	if err := svc.dialServices(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if svc.warmConns {
+		ctx, cancel := context.WithTimeout(context.Background(), warmUpTimeout)
+		svc.warmUpConnections(ctx)
+		cancel()
+	}
+	var connMonitorInterval time.Duration
+	mapEnvDuration(&connMonitorInterval, "CONN_MONITOR_INTERVAL")
+	maxConnFailure := defaultMaxConnFailure
+	mapEnvDuration(&maxConnFailure, "CONN_MAX_FAILURE_DURATION")
+	if connMonitorInterval > 0 {
+		svc.startConnMonitor(connMonitorInterval, maxConnFailure)
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
 	if err != nil {
 		log.Fatal(err)
 	}
+	var maxConns int
+	mapEnvInt(&maxConns, "MAX_CONNECTIONS")
+	lis = limitConnections(lis, maxConns)
+
+	orderWorkers, orderQueueSize := defaultOrderWorkers, defaultOrderQueueSize
+	mapEnvInt(&orderWorkers, "ORDER_WORKERS")
+	mapEnvInt(&orderQueueSize, "ORDER_QUEUE_SIZE")
+	svc.orderTimeout = defaultOrderTimeout
+	mapEnvDuration(&svc.orderTimeout, "ORDER_TIMEOUT")
+	svc.startOrderWorkers(orderWorkers, orderQueueSize)
 
+	var orderTTL time.Duration
+	mapEnvDuration(&orderTTL, "ORDER_TTL")
+	orderRetention := defaultOrderRetention
+	mapEnvDuration(&orderRetention, "ORDER_RETENTION")
+	sweepInterval := defaultOrderSweepInterval
+	mapEnvDuration(&sweepInterval, "ORDER_SWEEP_INTERVAL")
+	if orderTTL > 0 || orderRetention > 0 {
+		svc.startOrderSweeper(orderTTL, orderRetention, sweepInterval)
+	}
+	if svc.loyaltySvcAddr != "" {
+		retryInterval := defaultRedemptionRetryInterval
+		mapEnvDuration(&retryInterval, "LOYALTY_REDEMPTION_RETRY_INTERVAL")
+		svc.startRedemptionRetries(retryInterval)
+	}
+
+	gracePeriod := defaultShutdownGracePeriod
+	mapEnvDuration(&gracePeriod, "SHUTDOWN_GRACE_PERIOD")
+	drainLogInterval := defaultDrainLogInterval
+	mapEnvDuration(&drainLogInterval, "SHUTDOWN_LOG_INTERVAL")
+
+	inFlight := new(inFlightCounter)
 	var srv *grpc.Server
-	srv = grpc.NewServer()
+	compression, err := parseCompression(os.Getenv("GRPC_COMPRESSION"))
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is invalid: %v", "GRPC_COMPRESSION", err))
+	}
+	userErrors, err := parseUserErrorMessages(os.Getenv("USER_ERROR_MESSAGES"))
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is invalid: %v", "USER_ERROR_MESSAGES", err))
+	}
+	var maxStreams int
+	mapEnvInt(&maxStreams, "GRPC_MAX_CONCURRENT_STREAMS")
+	srvOpts := append(serverCompressionOptions(compression), serverStreamOptions(maxStreams)...)
+	srvOpts = append(srvOpts,
+		grpc.ChainUnaryInterceptor(inFlight.unaryInterceptor, userErrorsInterceptor(userErrors), loggingUnaryInterceptor))
+	srv = grpc.NewServer(srvOpts...)
 	pb.RegisterCheckoutServiceServer(srv, svc)
 	healthpb.RegisterHealthServer(srv, svc)
+
+	spillDir := os.Getenv("SHUTDOWN_SPILL_DIR")
+	var graceDeadline time.Time
+	stopped := make(chan struct{})
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+		sig := <-sigs
+		log.Infof("received %v, shutting down with a grace period of %v", sig, gracePeriod)
+		graceDeadline = time.Now().Add(gracePeriod)
+		drain(srv.GracefulStop, srv.Stop, inFlight, gracePeriod, drainLogInterval)
+		close(stopped)
+	}()
+
 	log.Infof("starting to listen on tcp: %q", lis.Addr().String())
-	err = srv.Serve(lis)
-	log.Fatal(err)
+	if err := srv.Serve(lis); err != nil {
+		log.Fatal(err)
+	}
+	<-stopped
+	svc.stopOrderSweeper()
+	svc.stopRedemptionRetries()
+	if svc.confirmations != nil {
+		svc.confirmations.stopPruning()
+	}
+	svc.stopConnMonitor()
+	svc.stopOrderWorkers(graceDeadline)
+	// Queued work is flushed within what is left of the grace period.
+	svc.flushQueues(webhook, time.Until(graceDeadline), spillDir)
+	svc.closeConns()
+}
+
+// limitConnections bounds the number of simultaneously accepted connections
+// to n. Connections beyond the limit wait in the backlog until one closes.
+// A limit of zero leaves lis unbounded.
+func limitConnections(lis net.Listener, n int) net.Listener {
+	if n <= 0 {
+		return lis
+	}
+	return netutil.LimitListener(lis, n)
+}
+
+// maxConcurrentStreams is replaced in tests to inspect the server options.
+var maxConcurrentStreams = grpc.MaxConcurrentStreams
+
+// serverStreamOptions bounds to n the concurrent streams of each client
+// connection, at the HTTP/2 layer. A limit of zero keeps gRPC's default.
+func serverStreamOptions(n int) []grpc.ServerOption {
+	if n <= 0 {
+		return nil
+	}
+	return []grpc.ServerOption{maxConcurrentStreams(uint32(n))}
+}
+
+func (cs *checkoutService) now() time.Time {
+	if cs.clock != nil {
+		return cs.clock()
+	}
+	return time.Now()
 }
 
 func mustMapEnv(target *string, envKey string) {
@@ -77,7 +646,58 @@ func mustMapEnv(target *string, envKey string) {
 	*target = v
 }
 
+func mapEnvBool(target *bool, envKey string) {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is not a valid boolean: %v", envKey, err))
+	}
+	*target = b
+}
+
+func mapEnvInt(target *int, envKey string) {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is not a valid integer: %v", envKey, err))
+	}
+	*target = i
+}
+
+func mapEnvFloat(target *float64, envKey string) {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is not a valid number: %v", envKey, err))
+	}
+	*target = f
+}
+
+func mapEnvDuration(target *time.Duration, envKey string) {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		panic(fmt.Sprintf("environment variable %q is not a valid duration: %v", envKey, err))
+	}
+	*target = d
+}
+
 func (cs *checkoutService) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.GetService() == readinessService {
+		return &healthpb.HealthCheckResponse{Status: cs.checkReadiness(ctx)}, nil
+	}
 	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
 }
 
@@ -85,214 +705,728 @@ func (cs *checkoutService) Watch(req *healthpb.HealthCheckRequest, ws healthpb.H
 	return status.Errorf(codes.Unimplemented, "health check via Watch not implemented")
 }
 
-func (cs *checkoutService) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.PlaceOrderResponse, error) {
-	log.Infof("[PlaceOrder] user_id=%q user_currency=%q", req.UserId, req.UserCurrency)
+func (cs *checkoutService) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (resp *pb.PlaceOrderResponse, err error) {
+	orderLog.Infof("[PlaceOrder] user_id=%q user_currency=%q", req.UserId, req.UserCurrency)
 
-	orderID, err := uuid.NewUUID()
+	span, ctx := cs.trace().StartSpan(ctx, "checkout.place_order")
+	defer func() { span.Finish(err) }()
+	ctx = withOriginSpan(ctx, span)
+	span.SetTag("user_id", req.GetUserId())
+	span.SetTag("user_currency", req.GetUserCurrency())
+	if cs.tagRPCCount {
+		var calls *rpcCounter
+		ctx, calls = withRPCCounter(ctx)
+		defer calls.tag(span)
+	}
+	if cs.stageTimingTrailer {
+		var timings *stageTimings
+		ctx, timings = withStageTimings(ctx)
+		defer func() {
+			if err := grpc.SetTrailer(ctx, timings.trailer()); err != nil {
+				log.Debugf("failed to set stage timing trailer: %v", err)
+			}
+		}()
+	}
+
+	if err := cs.validateOrderRequest(req); err != nil {
+		tagValidationError(span, err)
+		return nil, err
+	}
+	if cs.userOrders != nil {
+		if !cs.userOrders.acquire(req.GetUserId()) {
+			return nil, status.Errorf(codes.Aborted, "another order is already in progress for this user")
+		}
+		defer cs.userOrders.release(req.GetUserId())
+	}
+	orderID, err := newOrderID()
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to generate order uuid")
 	}
+	span.SetTag("order_id", orderID)
+
+	orderResult, err := cs.placeOrder(ctx, orderID, req)
+	if err != nil {
+		return nil, err
+	}
+	span.SetTag("order_status", orderResult.GetStatus().String())
+	span.SetTag("item_count", len(orderResult.GetItems()))
+	resp = &pb.PlaceOrderResponse{Order: withItemDetail(orderResult, cs.itemDetail(req.GetItemDetail()))}
+	return resp, nil
+}
+
+// placeOrder runs all the stages of the checkout for an already validated
+// request and returns the placed order.
+func (cs *checkoutService) placeOrder(ctx context.Context, orderID string, req *pb.PlaceOrderRequest) (_ *pb.OrderResult, err error) {
+	var (
+		prep    orderPrep
+		stage   = "prep"
+		charged bool
+	)
+	defer func() {
+		if err != nil {
+			cs.checkoutFailed(orderID, req, &prep, stage, charged, err)
+		}
+	}()
 
-	prep, err := cs.prepareOrderItemsAndShippingQuoteFromCart(ctx, req.UserId, req.UserCurrency, req.Address)
+	ctx = cs.orderContext(ctx, orderID, req)
+	prep, total, err := cs.priceOrder(ctx, req)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, err.Error())
+		return nil, err
+	}
+	stage = "order_number"
+	orderNumber, err := cs.allocateOrderNumber(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to allocate order number: %v", err)
 	}
 
-	total := pb.Money{CurrencyCode: req.UserCurrency,
-		Units: 0,
-		Nanos: 0}
-	total = money.Must(money.Sum(total, *prep.shippingCostLocalized))
-	for _, it := range prep.orderItems {
-		total = money.Must(money.Sum(total, *it.Cost))
+	stage = "fraud"
+	verdict := cs.scoreOrder(ctx, FraudCheck{
+		OrderID: orderID,
+		UserID:  req.GetUserId(),
+		Email:   req.GetEmail(),
+		Total:   &total,
+		Address: req.GetAddress(),
+		IP:      clientIP(ctx)})
+	switch verdict.Decision {
+	case FraudDeny:
+		return nil, status.Errorf(codes.PermissionDenied, "order was declined")
+	case FraudReview:
+		log.Infof("order %s held for review (fraud score: %.2f)", orderID, verdict.Score)
+		orderResult := prep.orderResult(orderID, orderNumber, req, pb.OrderStatus_ORDER_STATUS_REVIEW)
+		cs.orders.put(orderID, &orderRecord{status: pb.OrderStatus_ORDER_STATUS_REVIEW, order: orderResult, created: cs.now(), detail: req.GetItemDetail()})
+		return orderResult, nil
 	}
 
-	txID, err := cs.chargeCard(ctx, &total, req.CreditCard)
+	// Taxes, discounts and loyalty points are not priced from the catalog,
+	// leave them out of the comparison with the converted amounts.
+	stage = "charge"
+	if cs.chargeAmountCheck {
+		checked, err := sumAmounts(total.GetCurrencyCode(), prep.convertedAmounts())
+		if err != nil {
+			return nil, err
+		}
+		cs.checkChargeAmount(ctx, orderID, &checked, prep.orderItems, prep.conversions)
+	}
+	if cs.currencyRoundTrip {
+		cs.checkRoundTrip(ctx, orderID, &total)
+	}
+	total = *cs.normalizeAmount(&total)
+	if err := cs.checkGrandTotal(prep.grandTotal, &total); err != nil {
+		return nil, err
+	}
+
+	var (
+		txID   string
+		splits []splitAuthorization
+	)
+	chargeStart := time.Now()
+	if len(req.GetPaymentSplits()) > 0 {
+		if err := checkPaymentSplits(req.GetPaymentSplits(), &total); err != nil {
+			return nil, err
+		}
+		splits, err = cs.authorizeSplits(ctx, orderID, req.GetPaymentSplits())
+	} else {
+		txID, err = cs.chargeCard(ctx, &total, req.CreditCard, chargeIdempotencyKey(orderID))
+	}
+	cs.observeStage(ctx, "charge", chargeStart)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to charge card: %+v", err)
+		return nil, downstreamError(err, "failed to charge card")
 	}
-	log.Infof("payment went through (transaction_id: %s)", txID)
+	if txID != "" {
+		orderLog.Infof("payment went through (transaction_id: %s)", txID)
+	}
+	charged = true
+	// Past the charge, failing the order for its budget would only leave
+	// the payment behind.
+	ctx = withoutRPCBudget(ctx)
 
-	shippingTrackingID, err := cs.shipOrder(ctx, req.Address, prep.cartItems)
+	stage = "ship"
+	shipStart := time.Now()
+	shippingTrackingIDs, err := cs.shipOrder(ctx, req.Address, prep.cartItems, req.GetDeliveryDate())
+	cs.observeStage(ctx, "ship", shipStart)
 	if err != nil {
-		return nil, status.Errorf(codes.Unavailable, "shipping error: %+v", err)
+		if cs.authorizeOnly && txID != "" {
+			cs.voidPayment(ctx, txID)
+		}
+		cs.voidSplits(ctx, splits)
+		return nil, downstreamError(err, "shipping error")
+	}
+	switch {
+	case len(splits) > 0:
+		stage = "capture"
+		if err := cs.captureSplits(ctx, splits); err != nil {
+			return nil, downstreamError(err, "failed to capture payment")
+		}
+	case cs.authorizeOnly:
+		stage = "capture"
+		if err := cs.capturePayment(ctx, txID, &total); err != nil {
+			return nil, downstreamError(err, "failed to capture payment")
+		}
 	}
+	// The points are spent only once the order cannot fail anymore, as
+	// they cannot be given back.
+	cs.redeemPoints(ctx, orderID, req.GetUserId(), prep.pointsRedeemed)
 
 	_ = cs.emptyUserCart(ctx, req.UserId)
 
-	orderResult := &pb.OrderResult{
-		OrderId:            orderID.String(),
-		ShippingTrackingId: shippingTrackingID,
-		ShippingCost:       prep.shippingCostLocalized,
-		ShippingAddress:    req.Address,
-		Items:              prep.orderItems,
+	orderResult := prep.orderResult(orderID, orderNumber, req, pb.OrderStatus_ORDER_STATUS_COMPLETED)
+	orderResult.ShippingTrackingId = shippingTrackingIDs[0]
+	orderResult.ShippingTrackingIds = shippingTrackingIDs
+
+	cs.confirmOrder(ctx, req, orderResult)
+	if cs.orderExporter != nil {
+		if err := cs.orderExporter.export(orderResult); err != nil {
+			log.Warnf("failed to export order %s: %+v", orderID, err)
+		}
+	}
+	if cs.orderAudit != nil {
+		if err := cs.orderAudit.record(orderID, req.GetUserId(), &total, cs.now()); err != nil {
+			log.Warnf("failed to audit order %s: %+v", orderID, err)
+		}
+	}
+	if cs.notifier != nil {
+		cs.notifier.OrderPlaced(orderResult)
 	}
+	return orderResult, nil
+}
 
-	if err := cs.sendOrderConfirmation(ctx, req.Email, orderResult); err != nil {
-		log.Warnf("failed to send order confirmation to %q: %+v", req.Email, err)
-	} else {
-		log.Infof("order confirmation email sent to %q", req.Email)
+// orderContext derives the context of the downstream calls made to place
+// req.
+func (cs *checkoutService) orderContext(ctx context.Context, orderID string, req *pb.PlaceOrderRequest) context.Context {
+	if cs.tagOrderID && orderID != "" {
+		ctx = withOrderID(ctx, orderID)
 	}
-	resp := &pb.PlaceOrderResponse{Order: orderResult}
-	return resp, nil
+	if cs.maxRequestRetries > 0 {
+		ctx = withRetryBudget(ctx, cs.maxRequestRetries)
+	}
+	if cs.maxRPCsPerOrder > 0 {
+		ctx = withRPCBudget(ctx, cs.maxRPCsPerOrder)
+	}
+	ctx = withCardBrand(ctx, cardBrand(req.GetCreditCard().GetCreditCardNumber()))
+	return withRegion(ctx, regionOf(req.GetAddress()))
+}
+
+// priceOrder prices the items in the cart of the user and the shipping of
+// req, and returns the total to charge.
+func (cs *checkoutService) priceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (orderPrep, pb.Money, error) {
+	prepStart := time.Now()
+	prep, err := cs.prepareOrderItemsAndShippingQuoteFromCart(ctx, req.UserId, req.UserCurrency, req.Address, req.GetGuestItems(), req.GetItemNotes())
+	cs.observeStage(ctx, "prep", prepStart)
+	if err != nil {
+		return prep, pb.Money{}, err
+	}
+	if err := cs.checkRestrictedItems(prep.orderItems, req.GetMetadata()); err != nil {
+		return prep, pb.Money{}, err
+	}
+	if req.GetInsured() {
+		if err := cs.insureShipping(ctx, &prep, req.UserCurrency); err != nil {
+			return prep, pb.Money{}, err
+		}
+	}
+	if err := cs.wrapGifts(ctx, &prep, req.UserCurrency); err != nil {
+		return prep, pb.Money{}, err
+	}
+	if err := cs.applyPromotions(ctx, &prep, req.GetCouponCode(), req.UserCurrency); err != nil {
+		return prep, pb.Money{}, err
+	}
+	if cs.taxRate > 0 {
+		if err := cs.applyTax(&prep, req.UserCurrency); err != nil {
+			return prep, pb.Money{}, err
+		}
+	}
+
+	amounts := prep.convertedAmounts()
+	if prep.nativeShipping {
+		amounts = append(amounts, namedAmount{"shipping", *prep.shippingCostLocalized})
+	}
+	if prep.taxCost != nil {
+		amounts = append(amounts, namedAmount{"tax", *prep.taxCost})
+	}
+	if prep.discount != nil {
+		amounts = append(amounts, namedAmount{"discount", money.Negate(*prep.discount)})
+	}
+	total, err := sumAmounts(req.UserCurrency, amounts)
+	if err != nil {
+		return prep, pb.Money{}, err
+	}
+	if total, err = cs.applyPoints(ctx, &prep, req, total); err != nil {
+		return prep, pb.Money{}, err
+	}
+
+	if err := cs.checkMinimumCharge(&total); err != nil {
+		return prep, pb.Money{}, err
+	}
+	prep.grandTotal = cs.displayAmount(&total)
+	return prep, total, nil
 }
 
 type orderPrep struct {
 	orderItems            []*pb.OrderItem
 	cartItems             []*pb.CartItem
 	shippingCostLocalized *pb.Money
+	// nativeShipping is set when the shipping cost is a fee configured in
+	// the user currency rather than the converted quote.
+	nativeShipping   bool
+	conversions      []*pb.ConversionRecord
+	unavailableItems []*pb.CartItem
+	insuranceCost    *pb.Money
+	giftWrapCost     *pb.Money
+	pointsRedeemed   int64
+	pointsDiscount   *pb.Money
+	taxCost          *pb.Money
+	discount         *pb.Money
+	promotions       []string
+
+	// appliedPromotions details promotions when they are listed.
+	appliedPromotions []*pb.AppliedPromotion
+
+	// approximatePricing is set when the items were priced from the
+	// product listing.
+	approximatePricing bool
+
+	// grandTotal is the total displayed to the user.
+	grandTotal *pb.Money
+
+	// taxExempt holds the ids of the products exempt from sales tax.
+	taxExempt map[string]bool
+}
+
+// orderResult returns the order placed by req, as priced in p, with the
+// given status.
+func (p *orderPrep) orderResult(orderID string, orderNumber int64, req *pb.PlaceOrderRequest, orderStatus pb.OrderStatus) *pb.OrderResult {
+	return &pb.OrderResult{
+		OrderId:            orderID,
+		ShippingCost:       p.shippingCostLocalized,
+		ShippingAddress:    req.Address,
+		Items:              p.orderItems,
+		Metadata:           req.GetMetadata(),
+		Status:             orderStatus,
+		OrderNumber:        orderNumber,
+		Conversions:        p.conversions,
+		UnavailableItems:   p.unavailableItems,
+		InsuranceCost:      p.insuranceCost,
+		GiftWrapCost:       p.giftWrapCost,
+		TaxCost:            p.taxCost,
+		Discount:           p.discount,
+		Promotions:         p.promotions,
+		DeliveryDate:       req.GetDeliveryDate(),
+		PointsRedeemed:     p.pointsRedeemed,
+		PointsDiscount:     p.pointsDiscount,
+		ApproximatePricing: p.approximatePricing,
+		GrandTotal:         p.grandTotal,
+		AppliedPromotions:  p.appliedPromotions,
+	}
 }
 
-func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(ctx context.Context, userID, userCurrency string, address *pb.Address) (orderPrep, error) {
+// namedAmount is an amount making up the total of an order.
+type namedAmount struct {
+	name   string
+	amount pb.Money
+}
+
+// lineAmounts returns the cost of every item of the order for its
+// quantity.
+func (p *orderPrep) lineAmounts() []namedAmount {
+	var amounts []namedAmount
+	for _, it := range p.orderItems {
+		cost := money.MultiplySlow(*it.GetCost(), uint32(it.GetItem().GetQuantity()))
+		amounts = append(amounts, namedAmount{"item " + it.GetItem().GetProductId(), cost})
+	}
+	return amounts
+}
+
+// convertedAmounts returns the shipping cost, unless it is a native fee, the
+// cost of every item for its quantity and the gift wrapping fee of the
+// order, all converted to the user currency.
+func (p *orderPrep) convertedAmounts() []namedAmount {
+	var amounts []namedAmount
+	if !p.nativeShipping {
+		amounts = append(amounts, namedAmount{"shipping", *p.shippingCostLocalized})
+	}
+	amounts = append(amounts, p.lineAmounts()...)
+	if p.giftWrapCost != nil {
+		amounts = append(amounts, namedAmount{"gift wrap", *p.giftWrapCost})
+	}
+	return amounts
+}
+
+// sumAmounts sums amounts, which must all be in currency. Amounts in other
+// currencies can only come from a bug, they are logged and reported as
+// Internal rather than crashing the handler.
+func sumAmounts(currency string, amounts []namedAmount) (pb.Money, error) {
+	total := pb.Money{CurrencyCode: currency}
+	var mismatched []string
+	for _, a := range amounts {
+		if a.amount.GetCurrencyCode() != currency {
+			mismatched = append(mismatched, fmt.Sprintf("%s in %q", a.name, a.amount.GetCurrencyCode()))
+			continue
+		}
+		sum, err := money.Sum(total, a.amount)
+		if err != nil {
+			return pb.Money{}, status.Errorf(codes.Internal, "failed to sum the %s: %v", a.name, err)
+		}
+		total = sum
+	}
+	if len(mismatched) > 0 {
+		log.Errorf("amounts of the order are not all in %s: %s", currency, strings.Join(mismatched, ", "))
+		return pb.Money{}, status.Errorf(codes.Internal, "amounts of the order are in mixed currencies")
+	}
+	return total, nil
+}
+
+func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(ctx context.Context, userID, userCurrency string, address *pb.Address, guestItems []*pb.CartItem, itemNotes map[string]string) (orderPrep, error) {
 	var out orderPrep
-	cartItems, err := cs.getUserCart(ctx, userID)
+	cartItems, err := cs.getUserCartConsistent(ctx, userID)
 	if err != nil {
-		return out, fmt.Errorf("cart failure: %+v", err)
+		return out, downstreamError(err, "cart failure")
 	}
-	orderItems, err := cs.prepOrderItems(ctx, cartItems, userCurrency)
-	if err != nil {
-		return out, fmt.Errorf("failed to prepare order: %+v", err)
+	if len(guestItems) > 0 {
+		if cs.mergeGuestCart {
+			cartItems = mergeCartItems(cartItems, guestItems)
+		} else {
+			log.Debugf("ignoring %d guest items of user %q, guest cart merging is disabled", len(guestItems), userID)
+		}
 	}
-	shippingUSD, err := cs.quoteShipping(ctx, address, cartItems)
-	if err != nil {
-		return out, fmt.Errorf("shipping quote failure: %+v", err)
+	if cs.mergeDuplicateItems {
+		cartItems = mergeCartItems(cartItems, nil)
 	}
-	shippingPrice, err := cs.convertCurrency(ctx, shippingUSD, userCurrency)
-	if err != nil {
-		return out, fmt.Errorf("failed to convert shipping cost to currency: %+v", err)
+	if cartItems, err = applyItemNotes(cartItems, itemNotes); err != nil {
+		return out, err
+	}
+	if err := cs.checkDistinctProducts(cartItems); err != nil {
+		return out, err
+	}
+	if err := cs.checkItemsMemory(cartItems); err != nil {
+		return out, err
+	}
+	var backorders map[string]string
+	if cs.partialFulfillment || cs.backorders {
+		if cartItems, out.unavailableItems, backorders, err = cs.filterAvailable(ctx, cartItems); err != nil {
+			return out, err
+		}
+	}
+	// Pricing the items and quoting the shipping are independent, run them
+	// concurrently and abort the other one as soon as one fails.
+	var (
+		orderItems  []*pb.OrderItem
+		conversions []*pb.ConversionRecord
+		shippingUSD *pb.Money
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		if orderItems, conversions, out.taxExempt, err = cs.prepOrderItems(gctx, cartItems, userCurrency); err != nil {
+			return downstreamError(err, "failed to prepare order")
+		}
+		return nil
+	})
+	g.Go(func() error {
+		var err error
+		if shippingUSD, err = cs.quoteShipping(gctx, address, cartItems); err != nil {
+			return downstreamError(err, "shipping quote failure")
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return out, err
+	}
+	out.approximatePricing = cs.approximatePricing(cartItems)
+	shippingPrice := cs.nativeShippingFee(address, userCurrency)
+	if shippingPrice != nil {
+		out.nativeShipping = true
+	} else {
+		var err error
+		if shippingPrice, err = cs.convertCurrency(withCallResource(ctx, "currency.convert.shipping"), shippingUSD, userCurrency); err != nil {
+			return out, downstreamError(err, "failed to convert shipping cost to currency")
+		}
+		conversions = append(conversions, newConversionRecord("shipping", shippingUSD, shippingPrice))
+	}
+	if err := cs.checkShippingQuote(shippingUSD, shippingPrice, orderItems); err != nil {
+		return out, err
 	}
 
-	out.shippingCostLocalized = shippingPrice
+	// Backordered items are paid for with the order, shipping included, but
+	// only ship once restocked.
 	out.cartItems = cartItems
+	if len(backorders) > 0 {
+		out.cartItems = nil
+		for _, item := range cartItems {
+			if _, ok := backorders[item.GetProductId()]; !ok {
+				out.cartItems = append(out.cartItems, item)
+			}
+		}
+		for _, it := range orderItems {
+			if date, ok := backorders[it.GetItem().GetProductId()]; ok {
+				it.Backordered = true
+				it.AvailableDate = date
+			}
+		}
+	}
+	out.shippingCostLocalized = shippingPrice
 	out.orderItems = orderItems
+	out.conversions = conversions
 	return out, nil
 }
 
+// quoteShipping returns the cost of shipping items, summed over every
+// shipment the order is split into.
 func (cs *checkoutService) quoteShipping(ctx context.Context, address *pb.Address, items []*pb.CartItem) (*pb.Money, error) {
-	conn, err := grpc.DialContext(ctx, cs.shippingSvcAddr, grpc.WithInsecure())
-	if err != nil {
-		return nil, fmt.Errorf("could not connect shipping service: %+v", err)
-	}
-	defer conn.Close()
-
-	shippingQuote, err := pb.NewShippingServiceClient(conn).
-		GetQuote(ctx, &pb.GetQuoteRequest{
-			Address: address,
-			Items:   items})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get shipping quote: %+v", err)
+	var total *pb.Money
+	for _, shipment := range splitShipments(items, cs.maxItemsPerShipment) {
+		shippingQuote, err := cs.clients().shipping().
+			GetQuote(ctx, &pb.GetQuoteRequest{
+				Address: address,
+				Items:   shipment})
+		if err != nil {
+			return nil, downstreamError(err, "failed to get shipping quote")
+		}
+		if total == nil {
+			total = shippingQuote.GetCostUsd()
+			continue
+		}
+		sum, err := money.Sum(*total, *shippingQuote.GetCostUsd())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to sum shipping quotes: %v", err)
+		}
+		total = &sum
 	}
-	return shippingQuote.GetCostUsd(), nil
+	return total, nil
 }
 
 func (cs *checkoutService) getUserCart(ctx context.Context, userID string) ([]*pb.CartItem, error) {
-	conn, err := grpc.DialContext(ctx, cs.cartSvcAddr, grpc.WithInsecure())
+	cart, err := cs.clients().cart().GetCart(ctx, &pb.GetCartRequest{UserId: userID})
 	if err != nil {
-		return nil, fmt.Errorf("could not connect cart service: %+v", err)
-	}
-	defer conn.Close()
-
-	cart, err := pb.NewCartServiceClient(conn).GetCart(ctx, &pb.GetCartRequest{UserId: userID})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user cart during checkout: %+v", err)
+		return nil, downstreamError(err, "failed to get user cart during checkout")
 	}
 	return cart.GetItems(), nil
 }
 
 func (cs *checkoutService) emptyUserCart(ctx context.Context, userID string) error {
-	conn, err := grpc.DialContext(ctx, cs.cartSvcAddr, grpc.WithInsecure())
-	if err != nil {
-		return fmt.Errorf("could not connect cart service: %+v", err)
-	}
-	defer conn.Close()
-
-	if _, err = pb.NewCartServiceClient(conn).EmptyCart(ctx, &pb.EmptyCartRequest{UserId: userID}); err != nil {
-		return fmt.Errorf("failed to empty user cart during checkout: %+v", err)
+	if _, err := cs.clients().cart().EmptyCart(ctx, &pb.EmptyCartRequest{UserId: userID}); err != nil {
+		return downstreamError(err, "failed to empty user cart during checkout")
 	}
 	return nil
 }
 
-func (cs *checkoutService) prepOrderItems(ctx context.Context, items []*pb.CartItem, userCurrency string) ([]*pb.OrderItem, error) {
-	out := make([]*pb.OrderItem, len(items))
+func (cs *checkoutService) prepOrderItems(ctx context.Context, items []*pb.CartItem, userCurrency string) ([]*pb.OrderItem, []*pb.ConversionRecord, map[string]bool, error) {
+	for _, item := range items {
+		if q := item.GetQuantity(); q <= 0 {
+			return nil, nil, nil, status.Errorf(codes.InvalidArgument, "invalid quantity %d of product %q, must be positive", q, item.GetProductId())
+		}
+	}
 
-	conn, err := grpc.DialContext(ctx, cs.productCatalogSvcAddr, grpc.WithInsecure())
-	if err != nil {
-		return nil, fmt.Errorf("could not connect product catalog service: %+v", err)
+	// A product listed on several lines is only fetched and converted once.
+	var productIDs []string
+	index := make(map[string]int, len(items))
+	for _, item := range items {
+		if _, ok := index[item.GetProductId()]; !ok {
+			index[item.GetProductId()] = len(productIDs)
+			productIDs = append(productIDs, item.GetProductId())
+		}
 	}
-	defer conn.Close()
-	cl := pb.NewProductCatalogServiceClient(conn)
+	products := make([]*pb.Product, len(productIDs))
+	converted := make([]*pb.Money, len(productIDs))
 
-	for i, item := range items {
-		product, err := cl.GetProduct(ctx, &pb.GetProductRequest{Id: item.GetProductId()})
-		if err != nil {
-			return nil, fmt.Errorf("failed to get product #%q", item.GetProductId())
+	// Beyond the fan-out threshold, products are priced from a single
+	// listing of the catalog and their prices converted at once.
+	approximate := cs.approximatePricing(items)
+	if approximate {
+		log.Infof("pricing %d products from the product listing, more than the %d priced live", len(productIDs), cs.maxPricingFanout)
+		if err := cs.priceFromListing(ctx, productIDs, products); err != nil {
+			return nil, nil, nil, err
 		}
-		price, err := cs.convertCurrency(ctx, product.GetPriceUsd(), userCurrency)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert price of %q to %s", item.GetProductId(), userCurrency)
+	} else if err := cs.priceLive(ctx, productIDs, products, converted, userCurrency); err != nil {
+		return nil, nil, nil, err
+	}
+	prices := make([]*pb.Money, len(productIDs))
+	for i, product := range products {
+		prices[i] = product.GetPriceUsd()
+	}
+
+	if cs.batchCurrencyConversion || approximate {
+		var err error
+		if converted, err = cs.convertCurrencyBatch(withCallResource(ctx, "currency.convert.item"), prices, userCurrency); err != nil {
+			return nil, nil, nil, downstreamError(err, "failed to convert prices to %s", userCurrency)
 		}
+	}
+
+	out := make([]*pb.OrderItem, len(items))
+	conversions := make([]*pb.ConversionRecord, len(items))
+	for i, item := range items {
+		p := index[item.GetProductId()]
 		out[i] = &pb.OrderItem{
-			Item: item,
-			Cost: price}
+			Item:    item,
+			Cost:    converted[p],
+			Product: products[p]}
+		conversions[i] = newConversionRecord("product:"+item.GetProductId(), prices[p], converted[p])
 	}
-	return out, nil
+	taxExempt := make(map[string]bool)
+	for i, id := range productIDs {
+		if cs.taxExempt(products[i]) {
+			taxExempt[id] = true
+		}
+	}
+	return out, conversions, taxExempt, nil
 }
 
-func (cs *checkoutService) convertCurrency(ctx context.Context, from *pb.Money, toCurrency string) (*pb.Money, error) {
-	conn, err := grpc.DialContext(ctx, cs.currencySvcAddr, grpc.WithInsecure())
+// priceLive fetches the products concurrently, bounded so that a single
+// large cart cannot flood the downstream services, and fills products and,
+// unless batch conversion is enabled, converted, by index of productIDs.
+func (cs *checkoutService) priceLive(ctx context.Context, productIDs []string, products []*pb.Product, converted []*pb.Money, userCurrency string) error {
+	limit := cs.maxInflightPerRequest
+	if limit <= 0 {
+		limit = defaultMaxInflightPerRequest
+	}
+	sem := make(chan struct{}, limit)
+	g, gctx := errgroup.WithContext(ctx)
+	for i, id := range productIDs {
+		i, id := i, id
+		g.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+			defer func() { <-sem }()
+
+			product, err := cs.getProduct(gctx, id)
+			if err != nil {
+				return downstreamError(err, "failed to get product #%q", id)
+			}
+			products[i] = product
+			if cs.batchCurrencyConversion {
+				return nil
+			}
+			if converted[i], err = cs.convertCurrency(withCallResource(gctx, "currency.convert.item"), product.GetPriceUsd(), userCurrency); err != nil {
+				return downstreamError(err, "failed to convert price of %q to %s", id, userCurrency)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// approximatePricing reports whether items have too many distinct products
+// to price every one of them live.
+func (cs *checkoutService) approximatePricing(items []*pb.CartItem) bool {
+	if cs.maxPricingFanout <= 0 {
+		return false
+	}
+	distinct := make(map[string]struct{}, len(items))
+	for _, it := range items {
+		distinct[it.GetProductId()] = struct{}{}
+	}
+	return len(distinct) > cs.maxPricingFanout
+}
+
+// priceFromListing fills products, by index of productIDs, from a single
+// listing of the catalog. Listed prices may lag behind the ones served per
+// product.
+func (cs *checkoutService) priceFromListing(ctx context.Context, productIDs []string, products []*pb.Product) error {
+	resp, err := cs.clients().catalog().ListProducts(ctx, &pb.Empty{})
 	if err != nil {
-		return nil, fmt.Errorf("could not connect currency service: %+v", err)
+		return downstreamError(err, "failed to list products")
+	}
+	listed := make(map[string]*pb.Product, len(resp.GetProducts()))
+	for _, p := range resp.GetProducts() {
+		listed[p.GetId()] = p
 	}
-	defer conn.Close()
-	result, err := pb.NewCurrencyServiceClient(conn).Convert(ctx, &pb.CurrencyConversionRequest{
+	for i, id := range productIDs {
+		product, ok := listed[id]
+		if !ok {
+			return status.Errorf(codes.NotFound, "no product with ID %s", id)
+		}
+		products[i] = product
+	}
+	return nil
+}
+
+func (cs *checkoutService) convertCurrency(ctx context.Context, from *pb.Money, toCurrency string) (*pb.Money, error) {
+	// Converting to the same currency is a no-op, so there is no need to
+	// depend on the currency service being available.
+	if from.GetCurrencyCode() == toCurrency {
+		return from, nil
+	}
+
+	result, err := cs.convertMoney(ctx, &pb.CurrencyConversionRequest{
 		From:   from,
 		ToCode: toCurrency})
+	if status.Code(err) == codes.Unavailable && cs.fallbackRates != nil {
+		return cs.fallbackConvert(ctx, from, toCurrency)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert currency: %+v", err)
+		return nil, downstreamError(err, "failed to convert currency")
 	}
 	return result, err
 }
 
-func (cs *checkoutService) chargeCard(ctx context.Context, amount *pb.Money, paymentInfo *pb.CreditCardInfo) (string, error) {
-	conn, err := grpc.DialContext(ctx, cs.paymentSvcAddr, grpc.WithInsecure())
-	if err != nil {
-		return "", fmt.Errorf("failed to connect payment service: %+v", err)
-	}
-	defer conn.Close()
+func (cs *checkoutService) chargeCard(ctx context.Context, amount *pb.Money, paymentInfo *pb.CreditCardInfo, idempotencyKey string) (string, error) {
+	return cs.charge(ctx, amount, paymentInfo, idempotencyKey, cs.authorizeOnly)
+}
 
-	paymentResp, err := pb.NewPaymentServiceClient(conn).Charge(ctx, &pb.ChargeRequest{
-		Amount:     amount,
-		CreditCard: paymentInfo})
+// charge charges amount on a card, or only authorizes it if authorizeOnly
+// is set.
+func (cs *checkoutService) charge(ctx context.Context, amount *pb.Money, paymentInfo *pb.CreditCardInfo, idempotencyKey string, authorizeOnly bool) (string, error) {
+	payment := cs.paymentClient(ctx)
+	paymentResp, err := payment.Charge(ctx, &pb.ChargeRequest{
+		Amount:         amount,
+		CreditCard:     paymentInfo,
+		IdempotencyKey: idempotencyKey,
+		AuthorizeOnly:  authorizeOnly})
 	if err != nil {
-		return "", fmt.Errorf("could not charge the card: %+v", err)
+		if err == context.DeadlineExceeded || status.Code(err) == codes.DeadlineExceeded {
+			if authorizeOnly {
+				cs.releaseAuthorization(payment, idempotencyKey, "the charge timed out")
+			}
+			return "", status.Errorf(codes.DeadlineExceeded, "payment timed out, the card may not have been charged: %s", status.Convert(err).Message())
+		}
+		if reason := declineReason(err); reason != "" && cs.passDeclineReasons {
+			return "", declinedError(reason, err)
+		}
+		return "", downstreamError(err, "could not charge the card")
 	}
 	return paymentResp.GetTransactionId(), nil
 }
 
-func (cs *checkoutService) sendOrderConfirmation(ctx context.Context, email string, order *pb.OrderResult) error {
-	conn, err := grpc.DialContext(ctx, cs.emailSvcAddr, grpc.WithInsecure())
-	if err != nil {
-		return fmt.Errorf("failed to connect email service: %+v", err)
+// newConfirmationRequest returns the request confirming order to email,
+// with the gift message of the order if any.
+func newConfirmationRequest(email string, order *pb.OrderResult, attachment *pb.EmailAttachment) *pb.SendOrderConfirmationRequest {
+	return &pb.SendOrderConfirmationRequest{
+		Email:       email,
+		Order:       order,
+		Attachment:  attachment,
+		GiftMessage: order.GetMetadata()[giftMessageKey]}
+}
+
+// sendOrderConfirmation sends req, shrunk to the configured maximum size.
+func (cs *checkoutService) sendOrderConfirmation(ctx context.Context, req *pb.SendOrderConfirmationRequest) error {
+	if max := cs.maxConfirmationBytes; max > 0 {
+		size := proto.Size(req)
+		if !fitConfirmation(req, max) {
+			return status.Errorf(codes.ResourceExhausted, "order confirmation of %d bytes exceeds the %d bytes limit", size, max)
+		}
+		if size > max {
+			log.Warnf("order confirmation of %d bytes exceeds the %d bytes limit, truncated to %d bytes", size, max, proto.Size(req))
+		}
 	}
-	defer conn.Close()
-	_, err = pb.NewEmailServiceClient(conn).SendOrderConfirmation(ctx, &pb.SendOrderConfirmationRequest{
-		Email: email,
-		Order: order})
+	_, err := cs.clients().email().SendOrderConfirmation(ctx, req)
 	return err
 }
 
-func (cs *checkoutService) shipOrder(ctx context.Context, address *pb.Address, items []*pb.CartItem) (string, error) {
-	conn, err := grpc.DialContext(ctx, cs.shippingSvcAddr, grpc.WithInsecure())
-	if err != nil {
-		return "", fmt.Errorf("failed to connect email service: %+v", err)
-	}
-	defer conn.Close()
-	resp, err := pb.NewShippingServiceClient(conn).ShipOrder(ctx, &pb.ShipOrderRequest{
-		Address: address,
-		Items:   items})
-	if err != nil {
-		return "", fmt.Errorf("shipment failed: %+v", err)
+// shipOrder ships items, possibly in several shipments, and returns the
+// tracking id of each one. deliveryDate, if not empty, schedules the
+// delivery of every shipment.
+func (cs *checkoutService) shipOrder(ctx context.Context, address *pb.Address, items []*pb.CartItem, deliveryDate string) ([]string, error) {
+	var trackingIDs []string
+	for _, shipment := range splitShipments(items, cs.maxItemsPerShipment) {
+		resp, err := cs.clients().shipping().ShipOrder(ctx, &pb.ShipOrderRequest{
+			Address:      address,
+			Items:        shipment,
+			DeliveryDate: deliveryDate})
+		if err != nil {
+			return nil, downstreamError(err, "shipment failed")
+		}
+		trackingIDs = append(trackingIDs, resp.GetTrackingId())
 	}
-	return resp.GetTrackingId(), nil
+	return trackingIDs, nil
 }
-
-// TODO: Dial and create client once, reuse.
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestConvertCurrencySameCurrency(t *testing.T) {
+	fc := &fakeCurrencyService{rates: map[string]float64{"EUR": 0.5}}
+	cs := &checkoutService{currencySvcAddr: startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterCurrencyServiceServer(s, fc)
+	})}
+	dialTestService(t, cs)
+
+	in := &pb.Money{CurrencyCode: "USD", Units: 10, Nanos: 500000000}
+	got, err := cs.convertCurrency(context.Background(), in, "USD")
+	if err != nil {
+		t.Fatalf("convertCurrency() failed: %v", err)
+	}
+	if got.GetUnits() != 10 || got.GetNanos() != 500000000 || got.GetCurrencyCode() != "USD" {
+		t.Errorf("convertCurrency() = %v, want %v", got, in)
+	}
+	if n := fc.callCount(); n != 0 {
+		t.Errorf("expected no currency RPC for same-currency conversion, got %d", n)
+	}
+
+	got, err = cs.convertCurrency(context.Background(), in, "EUR")
+	if err != nil {
+		t.Fatalf("convertCurrency() failed: %v", err)
+	}
+	if got.GetCurrencyCode() != "EUR" || got.GetUnits() != 5 {
+		t.Errorf("convertCurrency() = %v, want 5.25 EUR", got)
+	}
+	if n := fc.callCount(); n != 1 {
+		t.Errorf("expected one currency RPC, got %d", n)
+	}
+}
+
+func TestPrepareOrderCancelsShippingQuoteOnPrepFailure(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"] = []*pb.CartItem{{ProductId: "UNKNOWN", Quantity: 1}}
+	cancelled := make(chan struct{})
+	f.shipping.quoteHook = func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			close(cancelled)
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+	cs := newTestCheckoutService(t, f)
+
+	start := time.Now()
+	_, err := cs.prepareOrderItemsAndShippingQuoteFromCart(context.Background(), "user-1", "USD", testOrderRequest().Address, nil, nil)
+	if err == nil {
+		t.Fatal("prepareOrderItemsAndShippingQuoteFromCart() should fail for an unknown product")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("prep failure took %v, it should not wait for the shipping quote", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("the in-flight shipping quote was not cancelled")
+	}
+}
+
+func TestPrepOrderItemsBoundsConcurrency(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.delay = 20 * time.Millisecond
+	var items []*pb.CartItem
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("PRODUCT%d", i)
+		f.catalog.products[id] = &pb.Product{Id: id, PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 1}}
+		items = append(items, &pb.CartItem{ProductId: id, Quantity: 1})
+	}
+	cs := newTestCheckoutService(t, f)
+	cs.maxInflightPerRequest = 3
+
+	out, _, _, err := cs.prepOrderItems(context.Background(), items, "USD")
+	if err != nil {
+		t.Fatalf("prepOrderItems() failed: %v", err)
+	}
+	if len(out) != len(items) {
+		t.Fatalf("got %d order items, want %d", len(out), len(items))
+	}
+	for i, it := range out {
+		if it.GetItem() != items[i] {
+			t.Errorf("order item %d does not match cart item %d", i, i)
+		}
+	}
+	if f.catalog.maxInFlight > 3 {
+		t.Errorf("%d concurrent product calls, want at most 3", f.catalog.maxInFlight)
+	}
+	if f.catalog.maxInFlight < 2 {
+		t.Errorf("product calls did not run concurrently (max %d in flight)", f.catalog.maxInFlight)
+	}
+}
+
+func TestPlaceOrderApproximatePricing(t *testing.T) {
+	tests := []struct {
+		name            string
+		fanout          int
+		wantApproximate bool
+		wantFetched     int
+	}{
+		{"below threshold", 2, false, 2},
+		{"past threshold", 1, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.maxPricingFanout = tt.fanout
+
+			req := testOrderRequest()
+			req.UserCurrency = "EUR"
+			resp, err := cs.PlaceOrder(context.Background(), req)
+			if err != nil {
+				t.Fatalf("PlaceOrder() failed: %v", err)
+			}
+			if got := resp.GetOrder().GetApproximatePricing(); got != tt.wantApproximate {
+				t.Errorf("approximate_pricing = %v, want %v", got, tt.wantApproximate)
+			}
+			if f.catalog.calls != tt.wantFetched {
+				t.Errorf("catalog served %d products, want %d", f.catalog.calls, tt.wantFetched)
+			}
+			// 19.99 + 349 USD at 0.5 EUR, whichever way the items are priced.
+			items := resp.GetOrder().GetItems()
+			if len(items) != 2 || items[0].GetCost().GetUnits() != 9 || items[1].GetCost().GetUnits() != 174 {
+				t.Errorf("order items = %v, want them priced at 9.995 and 174.5 EUR", items)
+			}
+		})
+	}
+}
+
+func TestPrepOrderItemsFetchesDuplicateProductsOnce(t *testing.T) {
+	f := newFakeDownstreams()
+	items := []*pb.CartItem{
+		{ProductId: "OLJCESPC7Z", Quantity: 1},
+		{ProductId: "66VCHSJNUP", Quantity: 1},
+		{ProductId: "OLJCESPC7Z", Quantity: 2},
+	}
+	cs := newTestCheckoutService(t, f)
+
+	out, conversions, _, err := cs.prepOrderItems(context.Background(), items, "EUR")
+	if err != nil {
+		t.Fatalf("prepOrderItems() failed: %v", err)
+	}
+	if f.catalog.calls != 2 || f.currency.callCount() != 2 {
+		t.Errorf("got %d product fetches and %d conversions, want one of each per product", f.catalog.calls, f.currency.callCount())
+	}
+	if len(out) != 3 || len(conversions) != 3 {
+		t.Fatalf("got %d order items and %d conversions, want one per line", len(out), len(conversions))
+	}
+	if out[2].GetItem() != items[2] || !proto.Equal(out[2].GetCost(), out[0].GetCost()) {
+		t.Errorf("duplicate line priced %v, want %v", out[2], out[0].GetCost())
+	}
+}
+
+func TestPlaceOrderMergesDuplicateItems(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"] = append(f.cart.carts["user-1"], &pb.CartItem{ProductId: "OLJCESPC7Z", Quantity: 3})
+	cs := newTestCheckoutService(t, f)
+	cs.mergeDuplicateItems = true
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	items := resp.GetOrder().GetItems()
+	if len(items) != 2 || items[0].GetItem().GetProductId() != "OLJCESPC7Z" || items[0].GetItem().GetQuantity() != 4 {
+		t.Errorf("order items = %v, want OLJCESPC7Z merged into a single line of 4", items)
+	}
+}
+
+func TestPlaceOrderRejectsNonPositiveQuantities(t *testing.T) {
+	for _, q := range []int32{0, -2} {
+		f := newFakeDownstreams()
+		f.cart.carts["user-1"][1].Quantity = q
+		cs := newTestCheckoutService(t, f)
+
+		_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("quantity %d: PlaceOrder() error = %v, want InvalidArgument", q, err)
+		}
+		if !strings.Contains(status.Convert(err).Message(), "66VCHSJNUP") {
+			t.Errorf("quantity %d: error %q does not name the product", q, status.Convert(err).Message())
+		}
+		if n := f.payment.chargeCount(); n != 0 {
+			t.Errorf("quantity %d: card was charged %d times", q, n)
+		}
+	}
+}
+
+func TestPlaceOrderRejectsMixedCurrencies(t *testing.T) {
+	f := newFakeDownstreams()
+	f.currency.code = "GBP"
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.UserCurrency = "EUR"
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("PlaceOrder() error = %v, want Internal", err)
+	}
+	if n := f.payment.chargeCount(); n != 0 {
+		t.Errorf("card was charged %d times", n)
+	}
+}
+
+func TestServerStreamOptions(t *testing.T) {
+	var got []uint32
+	orig := maxConcurrentStreams
+	maxConcurrentStreams = func(n uint32) grpc.ServerOption {
+		got = append(got, n)
+		return orig(n)
+	}
+	defer func() { maxConcurrentStreams = orig }()
+
+	if opts := serverStreamOptions(0); len(opts) != 0 || len(got) != 0 {
+		t.Errorf("serverStreamOptions(0) = %d options, want none", len(opts))
+	}
+	opts := serverStreamOptions(100)
+	if len(opts) != 1 || len(got) != 1 || got[0] != 100 {
+		t.Fatalf("serverStreamOptions(100) applied %v, want [100]", got)
+	}
+	grpc.NewServer(opts...).Stop()
+}
+
+func TestLimitConnections(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := limitConnections(inner, 1)
+	defer lis.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("accepted a connection beyond the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not accepted after one closed")
+	}
+}
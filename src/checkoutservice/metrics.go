@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const stageDurationMetric = "checkout_stage_duration_seconds"
+
+// Metrics is the sink the service reports its metrics to. Implementations
+// must be safe for concurrent use.
+type Metrics interface {
+	// ObserveHistogram records a single observation of a histogram.
+	ObserveHistogram(name string, value float64, labels map[string]string)
+	// IncCounter increments a counter by one.
+	IncCounter(name string, labels map[string]string)
+}
+
+// noopMetrics discards all metrics.
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveHistogram(string, float64, map[string]string) {}
+func (noopMetrics) IncCounter(string, map[string]string)                {}
+
+// defaultHistogramBuckets are the upper bounds, in seconds, of the histogram
+// buckets exported by promMetrics.
+var defaultHistogramBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// promMetrics keeps the metrics in memory and serves them in the Prometheus
+// text exposition format.
+type promMetrics struct {
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func newPromMetrics() *promMetrics {
+	return &promMetrics{
+		counters:   make(map[string]map[string]float64),
+		histograms: make(map[string]map[string]*histogram),
+	}
+}
+
+func (m *promMetrics) IncCounter(name string, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters[name] == nil {
+		m.counters[name] = make(map[string]float64)
+	}
+	m.counters[name][renderLabels(labels)]++
+}
+
+func (m *promMetrics) ObserveHistogram(name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.histograms[name] == nil {
+		m.histograms[name] = make(map[string]*histogram)
+	}
+	key := renderLabels(labels)
+	h, ok := m.histograms[name][key]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(defaultHistogramBuckets))}
+		m.histograms[name][key] = h
+	}
+	for i, le := range defaultHistogramBuckets {
+		if value <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// ServeHTTP writes all metrics, sorted by name and labels.
+func (m *promMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.write(w)
+}
+
+func (m *promMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name := range m.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "# TYPE %s counter\n", name)
+		var keys []string
+		for labels := range m.counters[name] {
+			keys = append(keys, labels)
+		}
+		sort.Strings(keys)
+		for _, labels := range keys {
+			fmt.Fprintf(w, "%s%s %s\n", name, braced(labels), formatFloat(m.counters[name][labels]))
+		}
+	}
+
+	names = names[:0]
+	for name := range m.histograms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+		var keys []string
+		for labels := range m.histograms[name] {
+			keys = append(keys, labels)
+		}
+		sort.Strings(keys)
+		for _, labels := range keys {
+			h := m.histograms[name][labels]
+			for i, le := range defaultHistogramBuckets {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, braced(labels, `le="`+formatFloat(le)+`"`), h.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, braced(labels, `le="+Inf"`), h.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, braced(labels), formatFloat(h.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, braced(labels), h.count)
+		}
+	}
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// renderLabels renders labels as comma-separated name="value" pairs, sorted
+// by name.
+func renderLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+`="`+labelValueEscaper.Replace(v)+`"`)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// braced joins the non-empty rendered label lists in braces, or returns ""
+// if there are none.
+func braced(labels ...string) string {
+	var nonEmpty []string
+	for _, l := range labels {
+		if l != "" {
+			nonEmpty = append(nonEmpty, l)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(nonEmpty, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// serveMetrics serves m on /metrics at addr, e.g. ":9090".
+func serveMetrics(addr string, m *promMetrics) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	log.Infof("serving metrics on %q", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Errorf("metrics server failed: %v", err)
+	}
+}
+
+// stats returns the configured metrics sink.
+func (cs *checkoutService) stats() Metrics {
+	if cs.metrics == nil {
+		return noopMetrics{}
+	}
+	return cs.metrics
+}
+
+// observeStage records the time spent in a stage of the checkout since start.
+func (cs *checkoutService) observeStage(ctx context.Context, stage string, start time.Time) {
+	d := time.Since(start)
+	cs.stats().ObserveHistogram(stageDurationMetric, d.Seconds(), map[string]string{"stage": stage})
+	if t, ok := ctx.Value(stageTimingsKey{}).(*stageTimings); ok {
+		t.add(stage, d)
+	}
+}
+
+// stageTimingTrailerPrefix prefixes the trailer keys reporting the duration
+// of each checkout stage to the client.
+const stageTimingTrailerPrefix = "checkout-stage-"
+
+type stageTimingsKey struct{}
+
+// stageTimings collects the stage durations of a single request.
+type stageTimings struct {
+	mu sync.Mutex
+	d  map[string]time.Duration
+}
+
+// withStageTimings returns a context collecting the stage durations observed
+// while handling the request.
+func withStageTimings(ctx context.Context) (context.Context, *stageTimings) {
+	t := &stageTimings{d: make(map[string]time.Duration)}
+	return context.WithValue(ctx, stageTimingsKey{}, t), t
+}
+
+func (t *stageTimings) add(stage string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.d[stage] += d
+}
+
+// trailer renders the durations as metadata, e.g. checkout-stage-prep: 12.5ms.
+func (t *stageTimings) trailer() metadata.MD {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	md := make(metadata.MD, len(t.d))
+	for stage, d := range t.d {
+		md.Set(stageTimingTrailerPrefix+stage, d.String())
+	}
+	return md
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPlaceOrderRecordsStageDurations(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	m := &recordingMetrics{}
+	cs.metrics = m
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	for _, stage := range []string{"prep", "charge", "ship", "email"} {
+		if n := m.count(stageDurationMetric, map[string]string{"stage": stage}); n != 1 {
+			t.Errorf("stage %q has %d observations, want 1", stage, n)
+		}
+	}
+}
+
+func TestPlaceOrderStageTimingTrailer(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.stageTimingTrailer = true
+	addr := startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterCheckoutServiceServer(s, cs)
+	})
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var trailer metadata.MD
+	_, err = pb.NewCheckoutServiceClient(conn).PlaceOrder(context.Background(), testOrderRequest(), grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	for _, stage := range []string{"prep", "charge", "ship", "email"} {
+		v := trailer.Get(stageTimingTrailerPrefix + stage)
+		if len(v) != 1 {
+			t.Errorf("trailer has no timing for stage %q: %v", stage, trailer)
+			continue
+		}
+		if _, err := time.ParseDuration(v[0]); err != nil {
+			t.Errorf("timing of stage %q = %q, want a duration", stage, v[0])
+		}
+	}
+}
+
+func TestPromMetricsServesTextFormat(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	m := newPromMetrics()
+	cs.metrics = m
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	m.IncCounter("test_total", map[string]string{"path": `a"b`})
+	m.ObserveHistogram("test_seconds", 0.2, nil)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE test_total counter\n",
+		`test_total{path="a\"b"} 1` + "\n",
+		"# TYPE test_seconds histogram\n",
+		`test_seconds_bucket{le="0.1"} 0` + "\n",
+		`test_seconds_bucket{le="0.25"} 1` + "\n",
+		`test_seconds_bucket{le="+Inf"} 1` + "\n",
+		"test_seconds_sum 0.2\n",
+		"test_seconds_count 1\n",
+		`checkout_stage_duration_seconds_count{stage="charge"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
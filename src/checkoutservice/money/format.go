@@ -0,0 +1,118 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// currencyDecimals holds the number of decimals of the currencies which do
+// not have two, per ISO 4217.
+var currencyDecimals = map[string]int{
+	"CLP": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// currencySymbols holds the symbols of common currencies. Others are
+// rendered with their code.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"KRW": "₩",
+	"INR": "₹",
+}
+
+// localeFormat describes how a locale renders amounts.
+type localeFormat struct {
+	decimal     string
+	group       string
+	symbolAfter bool
+}
+
+// localeFormats is keyed by language, amounts are rendered the English way
+// for other languages. Spaces are non-breaking so that an amount is never
+// split across lines.
+var localeFormats = map[string]localeFormat{
+	"en": {decimal: ".", group: ","},
+	"ja": {decimal: ".", group: ","},
+	"de": {decimal: ",", group: ".", symbolAfter: true},
+	"es": {decimal: ",", group: ".", symbolAfter: true},
+	"it": {decimal: ",", group: ".", symbolAfter: true},
+	"fr": {decimal: ",", group: "\u202f", symbolAfter: true},
+}
+
+// Decimals returns the number of decimals of a currency, two unless ISO 4217
+// says otherwise.
+func Decimals(currencyCode string) int {
+	if d, ok := currencyDecimals[currencyCode]; ok {
+		return d
+	}
+	return 2
+}
+
+// Format renders m for display in the given locale, e.g. "en-US" or "fr_FR",
+// with the currency symbol and digit grouping of the locale. Digits beyond
+// the precision of the currency are truncated.
+func Format(m pb.Money, locale string) string {
+	f := lookupLocale(locale)
+	units, nanos := m.GetUnits(), m.GetNanos()
+	sign := ""
+	if units < 0 || nanos < 0 {
+		sign, units, nanos = "-", -units, -nanos
+	}
+
+	amount := groupDigits(strconv.FormatInt(units, 10), f.group)
+	if d := Decimals(m.GetCurrencyCode()); d > 0 {
+		frac := strconv.FormatInt(int64(nanos)+nanosMod, 10)[1:]
+		amount += f.decimal + frac[:d]
+	}
+
+	symbol, ok := currencySymbols[m.GetCurrencyCode()]
+	if !ok {
+		symbol = m.GetCurrencyCode()
+	}
+	switch {
+	case f.symbolAfter:
+		return sign + amount + "\u00a0" + symbol
+	case !ok:
+		return sign + symbol + "\u00a0" + amount
+	}
+	return sign + symbol + amount
+}
+
+func lookupLocale(locale string) localeFormat {
+	lang := strings.ToLower(strings.SplitN(strings.Replace(locale, "_", "-", -1), "-", 2)[0])
+	if f, ok := localeFormats[lang]; ok {
+		return f
+	}
+	return localeFormats["en"]
+}
+
+// groupDigits inserts sep between every group of three digits.
+func groupDigits(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head == 0 {
+		head = 3
+	}
+	b.WriteString(digits[:head])
+	for i := head; i < len(digits); i += 3 {
+		b.WriteString(sep)
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package money
+
+import (
+	"testing"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     pb.Money
+		locale string
+		want   string
+	}{
+		{"USD", mmc(1234567, 890000000, "USD"), "en-US", "$1,234,567.89"},
+		{"USD small", mmc(5, 50000000, "USD"), "en-US", "$5.05"},
+		{"USD zero", mmc(0, 0, "USD"), "en-US", "$0.00"},
+		{"USD negative", mmc(-1234, -500000000, "USD"), "en-US", "-$1,234.50"},
+		{"USD negative cents", mmc(0, -990000000, "USD"), "en-US", "-$0.99"},
+		{"USD truncated", mmc(9, 999999999, "USD"), "en-US", "$9.99"},
+		{"EUR in German", mmc(1234, 560000000, "EUR"), "de-DE", "1.234,56\u00a0€"},
+		{"EUR in French", mmc(1234, 560000000, "EUR"), "fr_FR", "1\u202f234,56\u00a0€"},
+		{"EUR in English", mmc(1234, 560000000, "EUR"), "en", "€1,234.56"},
+		{"EUR negative", mmc(-12, -340000000, "EUR"), "de-DE", "-12,34\u00a0€"},
+		{"JPY", mmc(1234567, 0, "JPY"), "ja-JP", "¥1,234,567"},
+		{"JPY zero", mmc(0, 0, "JPY"), "ja-JP", "¥0"},
+		{"JPY drops nanos", mmc(1000, 600000000, "JPY"), "en-US", "¥1,000"},
+		{"unknown currency", mmc(1000, 0, "CHF"), "en-US", "CHF\u00a01,000.00"},
+		{"unknown locale", mmc(1000, 0, "USD"), "xx", "$1,000.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.in, tt.locale); got != tt.want {
+				t.Errorf("Format(%v, %q) = %q, want %q", tt.in, tt.locale, got, tt.want)
+			}
+		})
+	}
+}
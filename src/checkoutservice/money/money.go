@@ -85,19 +85,16 @@ func Sum(l, r pb.Money) (pb.Money, error) {
 	units := l.GetUnits() + r.GetUnits()
 	nanos := l.GetNanos() + r.GetNanos()
 
-	if (units == 0 && nanos == 0) || (units > 0 && nanos >= 0) || (units < 0 && nanos <= 0) {
-		// same sign <units, nanos>
-		units += int64(nanos / nanosMod)
-		nanos = nanos % nanosMod
-	} else {
-		// different sign. nanos guaranteed to not to go over the limit
-		if units > 0 {
-			units--
-			nanos += nanosMod
-		} else {
-			units++
-			nanos -= nanosMod
-		}
+	// carry over nanos overflow, then make sure units and nanos have the
+	// same sign.
+	units += int64(nanos / nanosMod)
+	nanos = nanos % nanosMod
+	if units > 0 && nanos < 0 {
+		units--
+		nanos += nanosMod
+	} else if units < 0 && nanos > 0 {
+		units++
+		nanos -= nanosMod
 	}
 
 	return pb.Money{
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	defaultWebhookQueueSize    = 100
+	defaultWebhookMaxAttempts  = 3
+	defaultWebhookRetryBackoff = 500 * time.Millisecond
+	webhookTimeout             = 5 * time.Second
+
+	// webhookSignatureHeader carries the hex encoded HMAC-SHA256 of the body
+	// when a secret is configured.
+	webhookSignatureHeader = "X-Checkout-Signature"
+)
+
+// Notifier is told about every completed order, e.g. to inform external
+// systems. OrderPlaced is called on the request path and must not block.
+type Notifier interface {
+	OrderPlaced(order *pb.OrderResult)
+}
+
+// webhookNotifier POSTs completed orders as proto JSON to a URL. Orders are
+// delivered in the background from a bounded queue, those arriving while
+// the queue is full are dropped.
+type webhookNotifier struct {
+	url          string
+	secret       []byte
+	client       *http.Client
+	maxAttempts  int
+	retryBackoff time.Duration
+
+	queue chan *pb.OrderResult
+	done  sync.WaitGroup
+
+	// stop makes the delivery give up, keeping the orders left in
+	// undelivered.
+	stop        chan struct{}
+	undelivered []*pb.OrderResult
+}
+
+// newWebhookNotifier starts delivering orders to url, signing them with
+// secret unless it is empty.
+func newWebhookNotifier(url, secret string, queueSize, maxAttempts int) *webhookNotifier {
+	n := &webhookNotifier{
+		url:          url,
+		client:       &http.Client{Timeout: webhookTimeout},
+		maxAttempts:  maxAttempts,
+		retryBackoff: defaultWebhookRetryBackoff,
+		queue:        make(chan *pb.OrderResult, queueSize),
+		stop:         make(chan struct{}),
+	}
+	if secret != "" {
+		n.secret = []byte(secret)
+	}
+	n.done.Add(1)
+	go func() {
+		defer n.done.Done()
+		for order := range n.queue {
+			if n.stopped() {
+				n.undelivered = append(n.undelivered, order)
+				continue
+			}
+			if err := n.deliver(order); err != nil {
+				if n.stopped() {
+					n.undelivered = append(n.undelivered, order)
+					continue
+				}
+				log.Warnf("failed to notify webhook of order %s: %+v", order.GetOrderId(), err)
+			}
+		}
+	}()
+	return n
+}
+
+func (n *webhookNotifier) OrderPlaced(order *pb.OrderResult) {
+	select {
+	case n.queue <- order:
+	default:
+		log.Warnf("webhook queue is full, dropping notification of order %s", order.GetOrderId())
+	}
+}
+
+// close stops accepting orders and waits for the queued ones to be
+// delivered.
+func (n *webhookNotifier) close() {
+	close(n.queue)
+	n.done.Wait()
+}
+
+// flush stops accepting orders and delivers the queued ones for up to
+// timeout. It returns the orders left undelivered, waiting for the request
+// in progress when the timeout expires.
+func (n *webhookNotifier) flush(timeout time.Duration) []*pb.OrderResult {
+	close(n.queue)
+	delivered := make(chan struct{})
+	go func() {
+		n.done.Wait()
+		close(delivered)
+	}()
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-delivered:
+	case <-t.C:
+		close(n.stop)
+		<-delivered
+	}
+	return n.undelivered
+}
+
+func (n *webhookNotifier) stopped() bool {
+	select {
+	case <-n.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver POSTs order, retrying with exponential backoff on network errors
+// and on 429 and 5xx responses.
+func (n *webhookNotifier) deliver(order *pb.OrderResult) error {
+	body, err := protojson.Marshal(proto.MessageV2(order))
+	if err != nil {
+		return err
+	}
+	backoff := n.retryBackoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= n.maxAttempts {
+			return err
+		}
+		log.Debugf("webhook notification of order %s failed (attempt %d): %v", order.GetOrderId(), attempt, err)
+		select {
+		case <-time.After(backoff):
+		case <-n.stop:
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post sends body once and reports whether a failure is worth retrying.
+func (n *webhookNotifier) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != nil {
+		req.Header.Set(webhookSignatureHeader, signWebhook(n.secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook responded with %s", resp.Status)
+}
+
+// signWebhook returns the signature of body, in the form sha256=<hex>.
+func signWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
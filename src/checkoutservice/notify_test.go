@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// webhookRecorder answers webhook requests with the given status codes in
+// turn, then 200, and records the bodies it received.
+type webhookRecorder struct {
+	mu         sync.Mutex
+	statuses   []int
+	bodies     [][]byte
+	signatures []string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	r.signatures = append(r.signatures, req.Header.Get(webhookSignatureHeader))
+	code := http.StatusOK
+	if len(r.statuses) > 0 {
+		code, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(code)
+}
+
+func TestWebhookNotifierPostsOrder(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	n := newWebhookNotifier(srv.URL, "s3cret", 10, 1)
+	cs.notifier = n
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	n.close()
+
+	if len(rec.bodies) != 1 {
+		t.Fatalf("webhook received %d requests, want 1", len(rec.bodies))
+	}
+	got := new(pb.OrderResult)
+	if err := protojson.Unmarshal(rec.bodies[0], proto.MessageV2(got)); err != nil {
+		t.Fatalf("webhook body is not an order: %v", err)
+	}
+	if got.GetOrderId() != resp.GetOrder().GetOrderId() {
+		t.Errorf("webhook received order %q, want %q", got.GetOrderId(), resp.GetOrder().GetOrderId())
+	}
+	if want := signWebhook([]byte("s3cret"), rec.bodies[0]); rec.signatures[0] != want {
+		t.Errorf("signature = %q, want %q", rec.signatures[0], want)
+	}
+}
+
+func TestWebhookNotifierRetriesTransientErrors(t *testing.T) {
+	rec := &webhookRecorder{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := newWebhookNotifier(srv.URL, "", 10, 3)
+	n.retryBackoff = 0
+	n.OrderPlaced(&pb.OrderResult{OrderId: "order-1"})
+	n.close()
+
+	if len(rec.bodies) != 3 {
+		t.Errorf("webhook received %d requests, want 3", len(rec.bodies))
+	}
+	if rec.signatures[0] != "" {
+		t.Errorf("unsigned webhook sent signature %q", rec.signatures[0])
+	}
+}
+
+func TestWebhookNotifierDoesNotRetryClientErrors(t *testing.T) {
+	rec := &webhookRecorder{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n := newWebhookNotifier(srv.URL, "", 10, 3)
+	n.retryBackoff = 0
+	n.OrderPlaced(&pb.OrderResult{OrderId: "order-1"})
+	n.close()
+
+	if len(rec.bodies) != 1 {
+		t.Errorf("webhook received %d requests, want 1", len(rec.bodies))
+	}
+}
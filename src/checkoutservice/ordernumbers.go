@@ -0,0 +1,54 @@
+package main
+
+import (
+	"sync"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	// tenantKey is the order metadata entry identifying the tenant an order
+	// belongs to.
+	tenantKey = "tenant"
+
+	defaultFirstOrderNumber = 1
+)
+
+// OrderNumberAllocator hands out increasing order numbers per tenant.
+// Numbers of orders that end up failing are not reused. Implementations
+// must be safe for concurrent use.
+type OrderNumberAllocator interface {
+	Next(tenant string) (int64, error)
+}
+
+// memoryOrderNumbers allocates order numbers in memory, starting at first
+// for every tenant.
+type memoryOrderNumbers struct {
+	mu    sync.Mutex
+	first int64
+	next  map[string]int64
+}
+
+func newMemoryOrderNumbers(first int64) *memoryOrderNumbers {
+	return &memoryOrderNumbers{first: first, next: make(map[string]int64)}
+}
+
+func (m *memoryOrderNumbers) Next(tenant string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, ok := m.next[tenant]
+	if !ok {
+		n = m.first
+	}
+	m.next[tenant] = n + 1
+	return n, nil
+}
+
+// allocateOrderNumber returns the next order number of the tenant of req, or
+// zero when no allocator is configured.
+func (cs *checkoutService) allocateOrderNumber(req *pb.PlaceOrderRequest) (int64, error) {
+	if cs.orderNumbers == nil {
+		return 0, nil
+	}
+	return cs.orderNumbers.Next(req.GetMetadata()[tenantKey])
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestMemoryOrderNumbersPerTenant(t *testing.T) {
+	alloc := newMemoryOrderNumbers(1)
+	const n = 50
+
+	var (
+		mu  sync.Mutex
+		got = map[string][]int64{}
+		wg  sync.WaitGroup
+	)
+	for _, tenant := range []string{"acme", "globex"} {
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(tenant string) {
+				defer wg.Done()
+				num, err := alloc.Next(tenant)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				got[tenant] = append(got[tenant], num)
+				mu.Unlock()
+			}(tenant)
+		}
+	}
+	wg.Wait()
+
+	for tenant, nums := range got {
+		sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+		for i, num := range nums {
+			if num != int64(i+1) {
+				t.Fatalf("tenant %s got numbers %v, want 1 to %d without duplicates", tenant, nums, n)
+			}
+		}
+	}
+}
+
+func TestPlaceOrderAssignsOrderNumbers(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.orderNumbers = newMemoryOrderNumbers(1000)
+
+	var got []int64
+	for _, tenant := range []string{"acme", "acme", "globex"} {
+		req := testOrderRequest()
+		req.Metadata = map[string]string{tenantKey: tenant}
+		resp, err := cs.PlaceOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+		got = append(got, resp.GetOrder().GetOrderNumber())
+	}
+	if got[0] != 1000 || got[1] != 1001 || got[2] != 1000 {
+		t.Errorf("order numbers = %v, want [1000 1001 1000]", got)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const (
+	defaultOrderWorkers   = 4
+	defaultOrderQueueSize = 100
+
+	defaultOrderSweepInterval = time.Minute
+	defaultOrderRetention     = time.Hour
+	defaultOrderTimeout       = time.Minute
+)
+
+// Metadata keys of the order ETags.
+const (
+	orderETagTrailer  = "etag"
+	ifNoneMatchHeader = "if-none-match"
+)
+
+func newOrderID() (string, error) {
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// orderRecord tracks the progression of an order submitted asynchronously.
+type orderRecord struct {
+	status  pb.OrderStatus
+	order   *pb.OrderResult
+	err     string
+	created time.Time
+
+	// updated is when the status last changed, i.e. when the order ended
+	// for completed, failed and expired orders.
+	updated time.Time
+
+	// processing is set while a worker places the pending order, which
+	// can then no longer expire.
+	processing bool
+
+	// detail is the item detail level the order was requested with, which
+	// GetOrderStatus applies like PlaceOrder does.
+	detail pb.ItemDetail
+}
+
+// orderStore keeps the state of asynchronous orders in memory.
+type orderStore struct {
+	mu     sync.Mutex
+	orders map[string]*orderRecord
+}
+
+func newOrderStore() *orderStore {
+	return &orderStore{orders: make(map[string]*orderRecord)}
+}
+
+// put stores r under id. The creation time of an existing record is kept,
+// and is the update time of r unless set.
+func (s *orderStore) put(id string, r *orderRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.orders[id]; ok {
+		r.created = prev.created
+	}
+	if r.updated.IsZero() {
+		r.updated = r.created
+	}
+	s.orders[id] = r
+}
+
+func (s *orderStore) get(id string) (orderRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.orders[id]
+	if !ok {
+		return orderRecord{}, false
+	}
+	return *r, true
+}
+
+// start marks the pending order id as being processed, reporting false if
+// it is not pending anymore, e.g. because it expired while queued.
+func (s *orderStore) start(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.orders[id]
+	if !ok || r.status != pb.OrderStatus_ORDER_STATUS_PENDING || r.processing {
+		return false
+	}
+	r.processing = true
+	return true
+}
+
+// expire marks the pending and in review orders created before cutoff as
+// expired at now and returns their ids. Orders being processed are left
+// alone, their outcome is about to be known.
+func (s *orderStore) expire(cutoff, now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for id, r := range s.orders {
+		switch r.status {
+		case pb.OrderStatus_ORDER_STATUS_PENDING, pb.OrderStatus_ORDER_STATUS_REVIEW:
+		default:
+			continue
+		}
+		if r.created.Before(cutoff) && !r.processing {
+			r.status = pb.OrderStatus_ORDER_STATUS_EXPIRED
+			r.err = "order expired"
+			r.updated = now
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// evict forgets the completed, failed and expired orders which ended before
+// cutoff and returns how many there were.
+func (s *orderStore) evict(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, r := range s.orders {
+		switch r.status {
+		case pb.OrderStatus_ORDER_STATUS_COMPLETED, pb.OrderStatus_ORDER_STATUS_FAILED, pb.OrderStatus_ORDER_STATUS_EXPIRED:
+		default:
+			continue
+		}
+		if r.updated.Before(cutoff) {
+			delete(s.orders, id)
+			n++
+		}
+	}
+	return n
+}
+
+type orderJob struct {
+	id  string
+	req *pb.PlaceOrderRequest
+
+	// md is the metadata SubmitOrder was called with, the client address
+	// included, for the checks relying on it, e.g. the fraud check or the
+	// expected number of cart items.
+	md metadata.MD
+}
+
+// submittedMetadata returns a copy of the incoming metadata of ctx, with
+// the address of the client as x-forwarded-for if not set already, to be
+// attached to the context an asynchronous order is placed with.
+func submittedMetadata(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	if len(md.Get("x-forwarded-for")) == 0 {
+		if ip := clientIP(ctx); ip != "" {
+			md.Set("x-forwarded-for", ip)
+		}
+	}
+	return md
+}
+
+// startOrderWorkers starts n workers placing the orders enqueued by
+// SubmitOrder.
+func (cs *checkoutService) startOrderWorkers(n, queueSize int) {
+	cs.orderQueue = make(chan orderJob, queueSize)
+	for i := 0; i < n; i++ {
+		cs.orderWorkers.Add(1)
+		go func() {
+			defer cs.orderWorkers.Done()
+			for job := range cs.orderQueue {
+				cs.processOrder(job)
+			}
+		}()
+	}
+}
+
+// stopOrderWorkers stops accepting new orders and waits, until deadline at
+// the latest, for the queued ones to be processed. It reports whether they
+// all were.
+func (cs *checkoutService) stopOrderWorkers(deadline time.Time) bool {
+	if cs.orderQueue == nil {
+		return true
+	}
+	close(cs.orderQueue)
+	done := make(chan struct{})
+	go func() {
+		cs.orderWorkers.Wait()
+		close(done)
+	}()
+	t := time.NewTimer(time.Until(deadline))
+	defer t.Stop()
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		log.Warnf("%d asynchronous orders left unprocessed at the shutdown deadline", len(cs.orderQueue))
+		return false
+	}
+}
+
+func (cs *checkoutService) processOrder(job orderJob) {
+	if !cs.orders.start(job.id) {
+		log.Infof("skipping asynchronous order %s, it is not pending anymore", job.id)
+		return
+	}
+	timeout := cs.orderTimeout
+	if timeout <= 0 {
+		timeout = defaultOrderTimeout
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), job.md)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	order, err := cs.placeOrder(ctx, job.id, job.req)
+	if err != nil {
+		log.Warnf("asynchronous order %s failed: %+v", job.id, err)
+		cs.orders.put(job.id, &orderRecord{status: pb.OrderStatus_ORDER_STATUS_FAILED, err: status.Convert(err).Message(), updated: cs.now()})
+		return
+	}
+	cs.orders.put(job.id, &orderRecord{status: order.GetStatus(), order: order, updated: cs.now(), detail: job.req.GetItemDetail()})
+}
+
+// expireOrders expires the orders left pending or in review for longer than
+// ttl.
+func (cs *checkoutService) expireOrders(ttl time.Duration) {
+	now := cs.now()
+	for _, id := range cs.orders.expire(now.Add(-ttl), now) {
+		log.Infof("order %s expired after %v", id, ttl)
+	}
+}
+
+// evictOrders forgets the orders which ended more than retention ago.
+func (cs *checkoutService) evictOrders(retention time.Duration) {
+	if n := cs.orders.evict(cs.now().Add(-retention)); n > 0 {
+		log.Debugf("evicted %d orders which ended more than %v ago", n, retention)
+	}
+}
+
+// startOrderSweeper periodically expires stale orders, unless ttl is zero,
+// and evicts ended ones, unless retention is zero, until stopOrderSweeper is
+// called.
+func (cs *checkoutService) startOrderSweeper(ttl, retention, interval time.Duration) {
+	cs.sweepDone = make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if ttl > 0 {
+					cs.expireOrders(ttl)
+				}
+				if retention > 0 {
+					cs.evictOrders(retention)
+				}
+			case <-cs.sweepDone:
+				return
+			}
+		}
+	}()
+}
+
+func (cs *checkoutService) stopOrderSweeper() {
+	if cs.sweepDone != nil {
+		close(cs.sweepDone)
+	}
+}
+
+func (cs *checkoutService) SubmitOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.SubmitOrderResponse, error) {
+	log.Infof("[SubmitOrder] user_id=%q user_currency=%q", req.UserId, req.UserCurrency)
+
+	if cs.orderQueue == nil {
+		return nil, status.Errorf(codes.Unimplemented, "asynchronous orders are not enabled")
+	}
+	if err := cs.validateOrderRequest(req); err != nil {
+		return nil, err
+	}
+	orderID, err := newOrderID()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate order uuid")
+	}
+
+	cs.orders.put(orderID, &orderRecord{status: pb.OrderStatus_ORDER_STATUS_PENDING, created: cs.now()})
+	select {
+	case cs.orderQueue <- orderJob{id: orderID, req: req, md: submittedMetadata(ctx)}:
+	default:
+		cs.orders.put(orderID, &orderRecord{status: pb.OrderStatus_ORDER_STATUS_FAILED, err: "order queue is full", updated: cs.now()})
+		return nil, status.Errorf(codes.ResourceExhausted, "too many pending orders, try again later")
+	}
+	return &pb.SubmitOrderResponse{OrderId: orderID, Status: pb.OrderStatus_ORDER_STATUS_PENDING}, nil
+}
+
+func (cs *checkoutService) GetOrderStatus(ctx context.Context, req *pb.GetOrderStatusRequest) (*pb.GetOrderStatusResponse, error) {
+	if cs.orders == nil {
+		return nil, status.Errorf(codes.Unimplemented, "asynchronous orders are not enabled")
+	}
+	r, ok := cs.orders.get(req.GetOrderId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "order %q not found", req.GetOrderId())
+	}
+	resp := &pb.GetOrderStatusResponse{
+		OrderId: req.GetOrderId(),
+		Status:  r.status,
+		Order:   withItemDetail(r.order, cs.itemDetail(r.detail)),
+		Error:   r.err}
+	if !cs.orderETags {
+		return resp, nil
+	}
+	tag, err := orderETag(resp)
+	if err != nil {
+		log.Warnf("failed to compute the ETag of order %s: %v", req.GetOrderId(), err)
+		return resp, nil
+	}
+	if err := grpc.SetTrailer(ctx, metadata.Pairs(orderETagTrailer, tag)); err != nil {
+		log.Debugf("failed to set order ETag trailer: %v", err)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(ifNoneMatchHeader) {
+		if v == tag {
+			return &pb.GetOrderStatusResponse{OrderId: req.GetOrderId(), NotModified: true}, nil
+		}
+	}
+	return resp, nil
+}
+
+// orderETag hashes the state of an order reported by GetOrderStatus.
+func orderETag(resp *pb.GetOrderStatusResponse) (string, error) {
+	b := proto.NewBuffer(nil)
+	b.SetDeterministic(true)
+	if err := b.Marshal(resp); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b.Bytes())
+	return hex.EncodeToString(sum[:16]), nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// waitForOrderStatus polls GetOrderStatus until the order leaves the pending
+// state.
+func waitForOrderStatus(t *testing.T, cs *checkoutService, orderID string) *pb.GetOrderStatusResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := cs.GetOrderStatus(context.Background(), &pb.GetOrderStatusRequest{OrderId: orderID})
+		if err != nil {
+			t.Fatalf("GetOrderStatus() failed: %v", err)
+		}
+		if resp.GetStatus() != pb.OrderStatus_ORDER_STATUS_PENDING {
+			return resp
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("order %s is still pending", orderID)
+	return nil
+}
+
+func TestSubmitOrderCompletes(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.startOrderWorkers(2, 10)
+	defer cs.stopOrderWorkers(time.Now().Add(5 * time.Second))
+
+	resp, err := cs.SubmitOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	if resp.GetOrderId() == "" || resp.GetStatus() != pb.OrderStatus_ORDER_STATUS_PENDING {
+		t.Fatalf("SubmitOrder() = %v, want a pending order", resp)
+	}
+
+	got := waitForOrderStatus(t, cs, resp.GetOrderId())
+	if got.GetStatus() != pb.OrderStatus_ORDER_STATUS_COMPLETED {
+		t.Fatalf("order status = %v (%s), want COMPLETED", got.GetStatus(), got.GetError())
+	}
+	if got.GetOrder().GetOrderId() != resp.GetOrderId() {
+		t.Errorf("completed order id = %q, want %q", got.GetOrder().GetOrderId(), resp.GetOrderId())
+	}
+	if f.payment.chargeCount() != 1 {
+		t.Errorf("expected the card to be charged once, got %d", f.payment.chargeCount())
+	}
+}
+
+func TestSubmitOrderFails(t *testing.T) {
+	f := newFakeDownstreams()
+	f.payment.err = status.Error(codes.InvalidArgument, "card declined")
+	cs := newTestCheckoutService(t, f)
+	cs.startOrderWorkers(1, 10)
+	defer cs.stopOrderWorkers(time.Now().Add(5 * time.Second))
+
+	resp, err := cs.SubmitOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	got := waitForOrderStatus(t, cs, resp.GetOrderId())
+	if got.GetStatus() != pb.OrderStatus_ORDER_STATUS_FAILED || got.GetError() == "" {
+		t.Errorf("order status = %v (%q), want FAILED with a reason", got.GetStatus(), got.GetError())
+	}
+}
+
+func TestGetOrderStatusNotFound(t *testing.T) {
+	cs := &checkoutService{orders: newOrderStore()}
+
+	_, err := cs.GetOrderStatus(context.Background(), &pb.GetOrderStatusRequest{OrderId: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetOrderStatus() code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestGetOrderStatusETag(t *testing.T) {
+	cs := &checkoutService{orders: newOrderStore(), orderETags: true}
+	cs.orders.put("order-1", &orderRecord{
+		status: pb.OrderStatus_ORDER_STATUS_COMPLETED,
+		order:  &pb.OrderResult{OrderId: "order-1", ShippingTrackingId: "track-1"}})
+	addr := startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterCheckoutServiceServer(s, cs)
+	})
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewCheckoutServiceClient(conn)
+	req := &pb.GetOrderStatusRequest{OrderId: "order-1"}
+
+	var trailer metadata.MD
+	resp, err := client.GetOrderStatus(context.Background(), req, grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatalf("GetOrderStatus() failed: %v", err)
+	}
+	etag := trailer.Get(orderETagTrailer)
+	if len(etag) != 1 || etag[0] == "" || resp.GetNotModified() {
+		t.Fatalf("GetOrderStatus() = %v with ETag %v, want the order with its ETag", resp, etag)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ifNoneMatchHeader, etag[0])
+	resp, err = client.GetOrderStatus(ctx, req)
+	if err != nil {
+		t.Fatalf("conditional GetOrderStatus() failed: %v", err)
+	}
+	if !resp.GetNotModified() || resp.GetOrder() != nil {
+		t.Errorf("conditional GetOrderStatus() = %v, want not modified", resp)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), ifNoneMatchHeader, "stale")
+	resp, err = client.GetOrderStatus(ctx, req)
+	if err != nil {
+		t.Fatalf("conditional GetOrderStatus() failed: %v", err)
+	}
+	if resp.GetNotModified() || resp.GetOrder().GetShippingTrackingId() != "track-1" {
+		t.Errorf("GetOrderStatus() with a stale ETag = %v, want the order", resp)
+	}
+}
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestExpireOrders(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cs.clock = clock.Now
+	// No workers, the order stays pending in the queue.
+	cs.orderQueue = make(chan orderJob, 1)
+
+	resp, err := cs.SubmitOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+
+	clock.Advance(29 * time.Minute)
+	cs.expireOrders(30 * time.Minute)
+	if r, _ := cs.orders.get(resp.GetOrderId()); r.status != pb.OrderStatus_ORDER_STATUS_PENDING {
+		t.Fatalf("order status = %v before the ttl, want PENDING", r.status)
+	}
+
+	clock.Advance(2 * time.Minute)
+	cs.expireOrders(30 * time.Minute)
+	got, err := cs.GetOrderStatus(context.Background(), &pb.GetOrderStatusRequest{OrderId: resp.GetOrderId()})
+	if err != nil {
+		t.Fatalf("GetOrderStatus() failed: %v", err)
+	}
+	if got.GetStatus() != pb.OrderStatus_ORDER_STATUS_EXPIRED {
+		t.Fatalf("order status = %v, want EXPIRED", got.GetStatus())
+	}
+
+	cs.processOrder(<-cs.orderQueue)
+	if f.payment.chargeCount() != 0 {
+		t.Errorf("expired order was charged %d times", f.payment.chargeCount())
+	}
+}
+
+func TestSubmitOrderKeepsClientMetadata(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	scorer := &fakeFraudScorer{verdict: FraudVerdict{Decision: FraudAllow}}
+	cs.fraudScorer = scorer
+	// No workers, the job is processed by hand.
+	cs.orderQueue = make(chan orderJob, 1)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 4242}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(cartExpectedItemsHeader, "2"))
+	if _, err := cs.SubmitOrder(ctx, testOrderRequest()); err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	job := <-cs.orderQueue
+	if v := job.md.Get(cartExpectedItemsHeader); len(v) != 1 || v[0] != "2" {
+		t.Errorf("job metadata %v lacks the expected cart items", job.md)
+	}
+	cs.processOrder(job)
+	if len(scorer.checks) != 1 || scorer.checks[0].IP != "203.0.113.7" {
+		t.Errorf("fraud checks = %+v, want one from the client address", scorer.checks)
+	}
+}
+
+func TestExpireOrdersSkipsOrdersInProgress(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cs.clock = clock.Now
+	cs.orderQueue = make(chan orderJob, 1)
+
+	resp, err := cs.SubmitOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	if !cs.orders.start(resp.GetOrderId()) {
+		t.Fatal("start() of a pending order = false, want true")
+	}
+	clock.Advance(time.Hour)
+	cs.expireOrders(30 * time.Minute)
+	if r, _ := cs.orders.get(resp.GetOrderId()); r.status != pb.OrderStatus_ORDER_STATUS_PENDING {
+		t.Errorf("order status = %v while processed, want PENDING", r.status)
+	}
+	if cs.orders.start(resp.GetOrderId()) {
+		t.Error("start() of an order in progress = true, want false")
+	}
+}
+
+func TestEvictOrders(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)}
+	cs := &checkoutService{orders: newOrderStore(), clock: clock.Now}
+	cs.orders.put("completed", &orderRecord{status: pb.OrderStatus_ORDER_STATUS_COMPLETED, created: cs.now(), updated: cs.now()})
+	cs.orders.put("pending", &orderRecord{status: pb.OrderStatus_ORDER_STATUS_PENDING, created: cs.now()})
+
+	clock.Advance(30 * time.Minute)
+	cs.evictOrders(time.Hour)
+	if _, ok := cs.orders.get("completed"); !ok {
+		t.Fatal("completed order evicted before the retention")
+	}
+
+	clock.Advance(31 * time.Minute)
+	cs.evictOrders(time.Hour)
+	if _, ok := cs.orders.get("completed"); ok {
+		t.Error("completed order kept after the retention")
+	}
+	if _, ok := cs.orders.get("pending"); !ok {
+		t.Error("pending order evicted")
+	}
+}
+
+func TestSubmitOrderTimesOut(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.delay = time.Second
+	cs := newTestCheckoutService(t, f)
+	cs.orderTimeout = 50 * time.Millisecond
+	cs.startOrderWorkers(1, 10)
+	defer cs.stopOrderWorkers(time.Now().Add(5 * time.Second))
+
+	start := time.Now()
+	resp, err := cs.SubmitOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	got := waitForOrderStatus(t, cs, resp.GetOrderId())
+	if got.GetStatus() != pb.OrderStatus_ORDER_STATUS_FAILED {
+		t.Errorf("order status = %v, want FAILED", got.GetStatus())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("order took %v to fail, want the 50ms order timeout to apply", elapsed)
+	}
+}
+
+func TestStopOrderWorkersHonorsDeadline(t *testing.T) {
+	f := newFakeDownstreams()
+	f.catalog.delay = time.Second
+	cs := newTestCheckoutService(t, f)
+	cs.startOrderWorkers(1, 10)
+
+	if _, err := cs.SubmitOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("SubmitOrder() failed: %v", err)
+	}
+	start := time.Now()
+	if cs.stopOrderWorkers(time.Now().Add(50 * time.Millisecond)) {
+		t.Error("stopOrderWorkers() reported the slow order as processed")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("stopOrderWorkers() took %v, want it to give up at the 50ms deadline", elapsed)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+const releaseAuthorizationTimeout = 5 * time.Second
+
+// chargeIdempotencyKey returns the key sent along with every charge attempt
+// of an order, so that the payment service can recognize retries.
+func chargeIdempotencyKey(orderID string) string {
+	return "charge-" + orderID
+}
+
+// capturePayment captures the amount held by an authorize-only charge once
+// the order has shipped.
+func (cs *checkoutService) capturePayment(ctx context.Context, txID string, amount *pb.Money) error {
+	start := time.Now()
+	_, err := cs.paymentClient(ctx).Capture(ctx, &pb.CaptureRequest{TransactionId: txID, Amount: amount})
+	cs.observeStage(ctx, "capture", start)
+	if err != nil {
+		return downstreamError(err, "could not capture transaction %s", txID)
+	}
+	orderLog.Infof("payment captured (transaction_id: %s)", txID)
+	return nil
+}
+
+// voidPayment releases the amount held by an authorize-only charge of an
+// order that could not ship. Like releaseAuthorization, it has its own
+// deadline since the order has likely run out of time. Failures are only
+// logged, the hold expires on its own eventually.
+func (cs *checkoutService) voidPayment(ctx context.Context, txID string) {
+	payment := cs.paymentClient(ctx)
+	voidCtx, cancel := context.WithTimeout(context.Background(), releaseAuthorizationTimeout)
+	defer cancel()
+	if orderID, ok := ctx.Value(orderIDKey{}).(string); ok {
+		voidCtx = withOrderID(voidCtx, orderID)
+	}
+	if _, err := payment.Void(voidCtx, &pb.VoidRequest{TransactionId: txID}); err != nil {
+		log.Warnf("failed to void transaction %s: %+v", txID, err)
+		return
+	}
+	log.Infof("payment voided (transaction_id: %s)", txID)
+}
+
+// declineDomain is the domain of the ErrorInfo detail attached to declined
+// charges.
+const declineDomain = "payment.hipstershop"
+
+// declineMessages maps fragments of the messages of the payment service to
+// the reason of the decline they describe.
+var declineMessages = []struct{ fragment, reason string }{
+	{"insufficient funds", "insufficient_funds"},
+	{"blocked", "card_blocked"},
+	{"stolen", "card_blocked"},
+	{"lost", "card_blocked"},
+	{"expired", "card_expired"},
+	{"cannot process", "card_type_unsupported"},
+	{"invalid", "card_invalid"},
+}
+
+// declineReason extracts a machine-readable reason, e.g. "insufficient_funds",
+// from a failed charge. The ErrorInfo detail of the payment service takes
+// precedence over its message. Failures which are not declines, such as an
+// unavailable service, have no reason.
+func declineReason(err error) string {
+	s, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.ResourceExhausted:
+		return ""
+	}
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetReason() != "" {
+			return strings.ToLower(info.GetReason())
+		}
+	}
+	msg := strings.ToLower(s.Message())
+	for _, m := range declineMessages {
+		if strings.Contains(msg, m.fragment) {
+			return m.reason
+		}
+	}
+	return ""
+}
+
+// declinedError reports a charge declined for reason as FailedPrecondition,
+// with an ErrorInfo detail so that clients can tell declines apart.
+func declinedError(reason string, err error) error {
+	st := status.Newf(codes.FailedPrecondition, "card declined (%s): %s", reason, status.Convert(err).Message())
+	if withDetails, derr := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: declineDomain}); derr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// releaseAuthorization voids the authorize-only charge made with
+// idempotencyKey, e.g. when its outcome is unknown or the order failed after
+// it, so that a hold it may have placed on the card does not linger. It has
+// its own deadline since the one of the order has likely expired. reason
+// completes the logs, e.g. "the charge timed out".
+func (cs *checkoutService) releaseAuthorization(payment pb.PaymentServiceClient, idempotencyKey, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseAuthorizationTimeout)
+	defer cancel()
+	if _, err := payment.Void(ctx, &pb.VoidRequest{IdempotencyKey: idempotencyKey}); err != nil {
+		log.Warnf("failed to release authorization %s after %s: %+v", idempotencyKey, reason, err)
+		return
+	}
+	log.Infof("authorization %s released after %s", idempotencyKey, reason)
+}
+
+// checkMinimumCharge rejects totals below the minimum amount the payment
+// provider accepts for their currency. Currencies without a configured
+// minimum are not checked.
+func (cs *checkoutService) checkMinimumCharge(total *pb.Money) error {
+	min, ok := cs.minChargeAmounts[total.GetCurrencyCode()]
+	if !ok {
+		return nil
+	}
+	diff, err := money.Sum(*total, money.Negate(*min))
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to compare order total with minimum charge: %+v", err)
+	}
+	if money.IsNegative(diff) {
+		return status.Errorf(codes.FailedPrecondition, "order total of %s is below the minimum charge of %s",
+			displayAmount(total), displayAmount(min))
+	}
+	return nil
+}
+
+const (
+	chargeDiscrepancyMetric = "checkout_charge_amount_discrepancy_total"
+
+	// defaultChargeAmountTolerance absorbs the rounding of each conversion.
+	defaultChargeAmountTolerance = 0.01
+)
+
+// checkChargeAmount compares the amount about to be charged, summed from
+// individually converted prices, with the USD total converted at once. The
+// price of each product counts for the quantity ordered. A difference above
+// the configured tolerance is logged and counted but does not fail the
+// order, it usually comes from rounding each conversion.
+func (cs *checkoutService) checkChargeAmount(ctx context.Context, orderID string, total *pb.Money, items []*pb.OrderItem, conversions []*pb.ConversionRecord) {
+	prices := make(map[string]*pb.Money)
+	var amounts []pb.Money
+	for _, c := range conversions {
+		if strings.HasPrefix(c.GetDescription(), "product:") {
+			prices[c.GetDescription()] = c.GetFrom()
+			continue
+		}
+		amounts = append(amounts, *c.GetFrom())
+	}
+	for _, it := range items {
+		price, ok := prices["product:"+it.GetItem().GetProductId()]
+		if !ok {
+			log.Debugf("skipping charge amount check of order %s: no conversion of product %s", orderID, it.GetItem().GetProductId())
+			return
+		}
+		amounts = append(amounts, money.MultiplySlow(*price, uint32(it.GetItem().GetQuantity())))
+	}
+	usd := pb.Money{CurrencyCode: usdCurrency}
+	for _, a := range amounts {
+		sum, err := money.Sum(usd, a)
+		if err != nil {
+			log.Debugf("skipping charge amount check of order %s: %v", orderID, err)
+			return
+		}
+		usd = sum
+	}
+	expected, err := cs.convertCurrency(withCallResource(ctx, "currency.convert.total"), &usd, total.GetCurrencyCode())
+	if err != nil {
+		log.Warnf("skipping charge amount check of order %s: %+v", orderID, err)
+		return
+	}
+	diff, err := money.Sum(*total, money.Negate(*expected))
+	if err != nil {
+		log.Warnf("skipping charge amount check of order %s: %v", orderID, err)
+		return
+	}
+	if money.IsNegative(diff) {
+		diff = money.Negate(diff)
+	}
+	if moneyToFloat(&diff) <= cs.chargeAmountTolerance {
+		return
+	}
+	log.Warnf("charge amount of order %s is %s, expected %s", orderID,
+		displayAmount(total), displayAmount(expected))
+	cs.stats().IncCounter(chargeDiscrepancyMetric, map[string]string{"currency": total.GetCurrencyCode()})
+}
+
+const (
+	roundTripDriftMetric = "checkout_currency_round_trip_drift_total"
+
+	defaultRoundTripTolerance = 0.01
+)
+
+// checkRoundTrip converts the total about to be charged to USD, the currency
+// of the catalog, and back. A drift from the total above the configured
+// tolerance hints at inconsistent rates; it is logged and counted but does
+// not fail the order.
+func (cs *checkoutService) checkRoundTrip(ctx context.Context, orderID string, total *pb.Money) {
+	if total.GetCurrencyCode() == usdCurrency {
+		return
+	}
+	usd, err := cs.convertCurrency(withCallResource(ctx, "currency.convert.round_trip"), total, usdCurrency)
+	if err != nil {
+		log.Warnf("skipping currency round trip check of order %s: %+v", orderID, err)
+		return
+	}
+	back, err := cs.convertCurrency(withCallResource(ctx, "currency.convert.round_trip"), usd, total.GetCurrencyCode())
+	if err != nil {
+		log.Warnf("skipping currency round trip check of order %s: %+v", orderID, err)
+		return
+	}
+	diff, err := money.Sum(*total, money.Negate(*back))
+	if err != nil {
+		log.Warnf("skipping currency round trip check of order %s: %v", orderID, err)
+		return
+	}
+	if money.IsNegative(diff) {
+		diff = money.Negate(diff)
+	}
+	if moneyToFloat(&diff) <= cs.roundTripTolerance {
+		return
+	}
+	log.Warnf("total of order %s drifts from %s to %s through %s", orderID,
+		displayAmount(total), displayAmount(back), usdCurrency)
+	cs.stats().IncCounter(roundTripDriftMetric, map[string]string{"currency": total.GetCurrencyCode()})
+}
+
+// normalizeAmount rounds m to the precision of its currency with the
+// rounding of the amounts charged. Payment providers reject amounts with
+// sub-precision digits.
+func (cs *checkoutService) normalizeAmount(m *pb.Money) *pb.Money {
+	return cs.roundAmount(m, cs.roundAmounts)
+}
+
+// displayAmount is normalizeAmount with the rounding of the amounts shown
+// to the user, which may differ from the one of the amounts charged.
+func (cs *checkoutService) displayAmount(m *pb.Money) *pb.Money {
+	return cs.roundAmount(m, cs.roundDisplayAmounts)
+}
+
+// minorUnit returns the smallest amount of a currency, in nanos, e.g. 1e7
+// for a cent.
+func (cs *checkoutService) minorUnit(currency string) int32 {
+	decimals, ok := cs.currencyPrecision[currency]
+	if !ok {
+		decimals = money.Decimals(currency)
+	}
+	step := int32(1)
+	for i := decimals; i < 9; i++ {
+		step *= 10
+	}
+	return step
+}
+
+// roundAmount discards the nanos of m below the minor unit of its currency,
+// rounding half away from zero if round is set and truncating otherwise.
+func (cs *checkoutService) roundAmount(m *pb.Money, round bool) *pb.Money {
+	step := cs.minorUnit(m.GetCurrencyCode())
+	if step == 1 {
+		return m
+	}
+	units, nanos := m.GetUnits(), m.GetNanos()
+	rest := nanos % step
+	nanos -= rest
+	if round {
+		switch {
+		case 2*rest >= step:
+			nanos += step
+		case -2*rest >= step:
+			nanos -= step
+		}
+	}
+	switch {
+	case nanos >= 1e9:
+		units, nanos = units+1, nanos-1e9
+	case nanos <= -1e9:
+		units, nanos = units-1, nanos+1e9
+	}
+	return &pb.Money{CurrencyCode: m.GetCurrencyCode(), Units: units, Nanos: nanos}
+}
+
+// checkGrandTotal verifies that the total displayed to the user differs by
+// at most one minor unit from the amount about to be charged, whatever the
+// rounding conventions of each.
+func (cs *checkoutService) checkGrandTotal(display, charged *pb.Money) error {
+	diff, err := money.Sum(*display, money.Negate(*charged))
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to compare the displayed total with the amount charged: %v", err)
+	}
+	if money.IsNegative(diff) {
+		diff = money.Negate(diff)
+	}
+	if diff.GetUnits() > 0 || diff.GetNanos() > cs.minorUnit(charged.GetCurrencyCode()) {
+		log.Errorf("displayed total %s differs from the amount charged %s by more than one minor unit",
+			displayAmount(display), displayAmount(charged))
+		return status.Errorf(codes.Internal, "displayed total does not match the amount charged")
+	}
+	return nil
+}
+
+// parseCurrencyPrecision parses a comma-separated list of CURRENCY=DECIMALS
+// pairs, e.g. "JPY=0,KWD=3".
+func parseCurrencyPrecision(v string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid currency precision %q, expected CURRENCY=DECIMALS", pair)
+		}
+		d, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || d < 0 || d > 9 {
+			return nil, fmt.Errorf("invalid number of decimals %q", kv[1])
+		}
+		out[strings.TrimSpace(kv[0])] = d
+	}
+	return out, nil
+}
+
+// parseMinChargeAmounts parses a comma-separated list of CURRENCY=AMOUNT
+// pairs, e.g. "USD=0.50,EUR=0.50".
+func parseMinChargeAmounts(v string) (map[string]*pb.Money, error) {
+	out := make(map[string]*pb.Money)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid minimum charge %q, expected CURRENCY=AMOUNT", pair)
+		}
+		m, err := parseAmount(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, err
+		}
+		out[m.CurrencyCode] = m
+	}
+	return out, nil
+}
+
+// parseAmount converts a non-negative decimal string such as "0.50" into a
+// money value of the given currency.
+func parseAmount(currency, v string) (*pb.Money, error) {
+	parts := strings.SplitN(v, ".", 2)
+	units, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || units < 0 {
+		return nil, fmt.Errorf("invalid amount %q", v)
+	}
+	var nanos int64
+	if len(parts) == 2 {
+		frac := parts[1]
+		if len(frac) == 0 || len(frac) > 9 {
+			return nil, fmt.Errorf("invalid amount %q", v)
+		}
+		frac += strings.Repeat("0", 9-len(frac))
+		if nanos, err = strconv.ParseInt(frac, 10, 32); err != nil || nanos < 0 {
+			return nil, fmt.Errorf("invalid amount %q", v)
+		}
+	}
+	return &pb.Money{CurrencyCode: currency, Units: units, Nanos: int32(nanos)}, nil
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderMinimumCharge(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    *pb.Money
+		wantCode codes.Code
+	}{
+		{"below minimum", &pb.Money{CurrencyCode: "USD", Units: 0, Nanos: 490000000}, codes.FailedPrecondition},
+		{"at minimum", &pb.Money{CurrencyCode: "USD", Units: 0, Nanos: 500000000}, codes.OK},
+		{"above minimum", &pb.Money{CurrencyCode: "USD", Units: 12}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.cart.carts["user-1"] = []*pb.CartItem{{ProductId: "STICKER", Quantity: 1}}
+			f.catalog.products["STICKER"] = &pb.Product{Id: "STICKER", PriceUsd: tt.price}
+			f.shipping.quote = &pb.Money{CurrencyCode: "USD"}
+			cs := newTestCheckoutService(t, f)
+			cs.minChargeAmounts = map[string]*pb.Money{"USD": {CurrencyCode: "USD", Nanos: 500000000}}
+
+			_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("PlaceOrder() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if want := "order total of $0.49 is below the minimum charge of $0.50"; err != nil && status.Convert(err).Message() != want {
+				t.Errorf("PlaceOrder() error = %q, want %q", status.Convert(err).Message(), want)
+			}
+			wantCharges := 1
+			if tt.wantCode != codes.OK {
+				wantCharges = 0
+			}
+			if n := f.payment.chargeCount(); n != wantCharges {
+				t.Errorf("payment service was called %d times, want %d", n, wantCharges)
+			}
+		})
+	}
+}
+
+func TestParseMinChargeAmounts(t *testing.T) {
+	got, err := parseMinChargeAmounts("USD=0.50, JPY=50")
+	if err != nil {
+		t.Fatalf("parseMinChargeAmounts() failed: %v", err)
+	}
+	if m := got["USD"]; m.GetUnits() != 0 || m.GetNanos() != 500000000 {
+		t.Errorf("USD minimum = %v, want 0.50", m)
+	}
+	if m := got["JPY"]; m.GetUnits() != 50 || m.GetNanos() != 0 {
+		t.Errorf("JPY minimum = %v, want 50", m)
+	}
+	for _, bad := range []string{"USD", "USD=abc", "USD=-1", "USD=1.0000000001"} {
+		if _, err := parseMinChargeAmounts(bad); err == nil {
+			t.Errorf("parseMinChargeAmounts(%q) should fail", bad)
+		}
+	}
+}
+
+func TestChargeCardRetriesShareIdempotencyKey(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	key := chargeIdempotencyKey("order-1")
+	amount := &pb.Money{CurrencyCode: "USD", Units: 10}
+	for i := 0; i < 2; i++ {
+		if _, err := cs.chargeCard(context.Background(), amount, testOrderRequest().CreditCard, key); err != nil {
+			t.Fatalf("chargeCard() attempt %d failed: %v", i+1, err)
+		}
+	}
+
+	if len(f.payment.charges) != 2 {
+		t.Fatalf("expected 2 charge attempts, got %d", len(f.payment.charges))
+	}
+	first, second := f.payment.charges[0].GetIdempotencyKey(), f.payment.charges[1].GetIdempotencyKey()
+	if first == "" || first != second {
+		t.Errorf("charge attempts used keys %q and %q, want the same non-empty key", first, second)
+	}
+	if other := chargeIdempotencyKey("order-2"); other == key {
+		t.Errorf("different orders should use different keys, both got %q", key)
+	}
+}
+
+func TestPlaceOrderSendsIdempotencyKey(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	want := chargeIdempotencyKey(resp.GetOrder().GetOrderId())
+	if got := f.payment.charges[0].GetIdempotencyKey(); got != want {
+		t.Errorf("idempotency key = %q, want %q", got, want)
+	}
+}
+
+func TestPlaceOrderAuthorizeThenCapture(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.authorizeOnly = true
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if len(f.payment.charges) != 1 || !f.payment.charges[0].GetAuthorizeOnly() {
+		t.Fatalf("charges = %v, want a single authorization", f.payment.charges)
+	}
+	if len(f.payment.captured) != 1 || f.payment.captured[0] != "tx-1" {
+		t.Errorf("captured = %v, want [tx-1]", f.payment.captured)
+	}
+	if len(f.payment.voided) != 0 {
+		t.Errorf("voided = %v, want none", f.payment.voided)
+	}
+}
+
+func TestPlaceOrderAuthorizeThenVoidOnShippingFailure(t *testing.T) {
+	f := newFakeDownstreams()
+	f.shipping.shipErr = status.Error(codes.Unavailable, "no carrier available")
+	cs := newTestCheckoutService(t, f)
+	cs.authorizeOnly = true
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("PlaceOrder() = %v, want Unavailable", err)
+	}
+	if len(f.payment.voided) != 1 || f.payment.voided[0] != "tx-1" {
+		t.Errorf("voided = %v, want [tx-1]", f.payment.voided)
+	}
+	if len(f.payment.captured) != 0 {
+		t.Errorf("captured = %v, want none", f.payment.captured)
+	}
+}
+
+func TestVoidPaymentOutlivesTheOrderContext(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cs.voidPayment(ctx, "tx-1")
+	if len(f.payment.voided) != 1 || f.payment.voided[0] != "tx-1" {
+		t.Errorf("voided = %v, want [tx-1] even though the order context is done", f.payment.voided)
+	}
+}
+
+func TestCheckChargeAmount(t *testing.T) {
+	conversions := []*pb.ConversionRecord{
+		newConversionRecord("product:A", &pb.Money{CurrencyCode: "USD", Units: 10}, &pb.Money{CurrencyCode: "EUR", Units: 5}),
+		newConversionRecord("shipping", &pb.Money{CurrencyCode: "USD", Units: 4}, &pb.Money{CurrencyCode: "EUR", Units: 2}),
+	}
+	items := []*pb.OrderItem{{Item: &pb.CartItem{ProductId: "A", Quantity: 2}, Cost: &pb.Money{CurrencyCode: "EUR", Units: 5}}}
+	tests := []struct {
+		name      string
+		total     *pb.Money
+		tolerance float64
+		want      int
+	}{
+		{"exact", &pb.Money{CurrencyCode: "EUR", Units: 12}, 0, 0},
+		{"one cent off", &pb.Money{CurrencyCode: "EUR", Units: 12, Nanos: 10000000}, 0, 1},
+		{"one cent under", &pb.Money{CurrencyCode: "EUR", Units: 11, Nanos: 990000000}, 0, 1},
+		{"within tolerance", &pb.Money{CurrencyCode: "EUR", Units: 12, Nanos: 10000000}, 0.01, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			m := &recordingMetrics{}
+			cs.metrics = m
+			cs.chargeAmountTolerance = tt.tolerance
+
+			cs.checkChargeAmount(context.Background(), "order-1", tt.total, items, conversions)
+			if n := m.count(chargeDiscrepancyMetric, map[string]string{"currency": "EUR"}); n != tt.want {
+				t.Errorf("got %d discrepancies, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderChargeAmountCheckIsOptional(t *testing.T) {
+	calls := make(map[bool]int)
+	for _, enabled := range []bool{false, true} {
+		f := newFakeDownstreams()
+		cs := newTestCheckoutService(t, f)
+		cs.chargeAmountCheck = enabled
+		req := testOrderRequest()
+		req.UserCurrency = "EUR"
+		if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+			t.Fatalf("enabled=%v: PlaceOrder() failed: %v", enabled, err)
+		}
+		calls[enabled] = f.currency.callCount()
+	}
+	if calls[true] != calls[false]+1 {
+		t.Errorf("got %d conversions with the check and %d without, want it to take one", calls[true], calls[false])
+	}
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	total := &pb.Money{CurrencyCode: "EUR", Units: 100}
+	tests := []struct {
+		name      string
+		fromRates map[string]float64
+		want      int
+	}{
+		{"symmetric rates", nil, 0},
+		// 100 EUR buy 200 USD at 0.5 but 200 USD sell back for 96 EUR.
+		{"asymmetric rates", map[string]float64{"USD": 1 / 0.96}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.currency.fromRates = tt.fromRates
+			cs := newTestCheckoutService(t, f)
+			m := &recordingMetrics{}
+			cs.metrics = m
+			cs.roundTripTolerance = defaultRoundTripTolerance
+
+			cs.checkRoundTrip(context.Background(), "order-1", total)
+			if n := m.count(roundTripDriftMetric, map[string]string{"currency": "EUR"}); n != tt.want {
+				t.Errorf("got %d round trip drifts, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        *pb.Money
+		round     bool
+		wantUnits int64
+		wantNanos int32
+	}{
+		{"USD truncated", &pb.Money{CurrencyCode: "USD", Units: 10, Nanos: 126789000}, false, 10, 120000000},
+		{"USD rounded", &pb.Money{CurrencyCode: "USD", Units: 10, Nanos: 126789000}, true, 10, 130000000},
+		{"USD rounded down", &pb.Money{CurrencyCode: "USD", Units: 10, Nanos: 124999999}, true, 10, 120000000},
+		{"USD rounded to next unit", &pb.Money{CurrencyCode: "USD", Units: 9, Nanos: 995000000}, true, 10, 0},
+		{"JPY truncated", &pb.Money{CurrencyCode: "JPY", Units: 1234, Nanos: 600000000}, false, 1234, 0},
+		{"JPY rounded", &pb.Money{CurrencyCode: "JPY", Units: 1234, Nanos: 600000000}, true, 1235, 0},
+		{"USD already normalized", &pb.Money{CurrencyCode: "USD", Units: 3, Nanos: 500000000}, true, 3, 500000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &checkoutService{roundAmounts: tt.round}
+			got := cs.normalizeAmount(tt.in)
+			if got.GetUnits() != tt.wantUnits || got.GetNanos() != tt.wantNanos || got.GetCurrencyCode() != tt.in.GetCurrencyCode() {
+				t.Errorf("normalizeAmount(%v) = %v, want %d.%09d", tt.in, got, tt.wantUnits, tt.wantNanos)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderChargesNormalizedTotal(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"] = []*pb.CartItem{{ProductId: "STICKER", Quantity: 1}}
+	f.catalog.products["STICKER"] = &pb.Product{Id: "STICKER", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 1, Nanos: 234567890}}
+	f.shipping.quote = &pb.Money{CurrencyCode: "USD"}
+	cs := newTestCheckoutService(t, f)
+	cs.currencyPrecision = map[string]int{"USD": 1}
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if got := f.payment.charges[0].GetAmount(); got.GetUnits() != 1 || got.GetNanos() != 200000000 {
+		t.Errorf("charged %v, want 1.2 USD", got)
+	}
+}
+
+func TestPlaceOrderDisplayRounding(t *testing.T) {
+	f := newFakeDownstreams()
+	f.cart.carts["user-1"] = []*pb.CartItem{{ProductId: "STICKER", Quantity: 1}}
+	f.catalog.products["STICKER"] = &pb.Product{Id: "STICKER", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 1, Nanos: 236000000}}
+	f.shipping.quote = &pb.Money{CurrencyCode: "USD"}
+	cs := newTestCheckoutService(t, f)
+	cs.roundDisplayAmounts = true
+
+	order, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if got := order.GetOrder().GetGrandTotal(); got.GetUnits() != 1 || got.GetNanos() != 240000000 {
+		t.Errorf("grand total = %v, want 1.24 USD", got)
+	}
+	if got := f.payment.charges[0].GetAmount(); got.GetUnits() != 1 || got.GetNanos() != 230000000 {
+		t.Errorf("charged %v, want 1.23 USD", got)
+	}
+}
+
+func TestCheckGrandTotal(t *testing.T) {
+	cs := &checkoutService{}
+	charged := &pb.Money{CurrencyCode: "USD", Units: 1, Nanos: 230000000}
+	if err := cs.checkGrandTotal(&pb.Money{CurrencyCode: "USD", Units: 1, Nanos: 240000000}, charged); err != nil {
+		t.Errorf("checkGrandTotal() of a one cent difference failed: %v", err)
+	}
+	err := cs.checkGrandTotal(&pb.Money{CurrencyCode: "USD", Units: 1, Nanos: 250000000}, charged)
+	if status.Code(err) != codes.Internal {
+		t.Errorf("checkGrandTotal() of a two cents difference = %v, want Internal", err)
+	}
+}
+
+func TestPlaceOrderSurfacesDeclineReason(t *testing.T) {
+	withInfo, _ := status.New(codes.InvalidArgument, "charge refused").
+		WithDetails(&errdetails.ErrorInfo{Reason: "INSUFFICIENT_FUNDS", Domain: "payment.example"})
+	tests := []struct {
+		name       string
+		err        error
+		passthru   bool
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{"error info", withInfo.Err(), true, codes.FailedPrecondition, "insufficient_funds"},
+		{"message", status.Error(codes.InvalidArgument, "Insufficient funds on card ending 0454"), true, codes.FailedPrecondition, "insufficient_funds"},
+		{"blocked card", status.Error(codes.PermissionDenied, "card is blocked"), true, codes.FailedPrecondition, "card_blocked"},
+		{"not a decline", status.Error(codes.Unavailable, "payment is down"), true, codes.Unavailable, ""},
+		{"passthrough disabled", withInfo.Err(), false, codes.InvalidArgument, "INSUFFICIENT_FUNDS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.payment.err = tt.err
+			cs := newTestCheckoutService(t, f)
+			cs.passDeclineReasons = tt.passthru
+
+			_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Fatalf("PlaceOrder() code = %v, want %v (err: %v)", st.Code(), tt.wantCode, err)
+			}
+			var reason string
+			for _, d := range st.Details() {
+				if info, ok := d.(*errdetails.ErrorInfo); ok {
+					reason = info.GetReason()
+				}
+			}
+			if reason != tt.wantReason {
+				t.Errorf("ErrorInfo reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderReleasesAuthorizationOnPaymentTimeout(t *testing.T) {
+	f := newFakeDownstreams()
+	f.payment.delay = 500 * time.Millisecond
+	cs := newTestCheckoutService(t, f)
+	cs.serviceTimeouts = map[string]time.Duration{"paymentservice": 50 * time.Millisecond}
+	cs.passDeclineReasons = true
+	cs.authorizeOnly = true
+
+	_, err := cs.placeOrder(context.Background(), "order-1", testOrderRequest())
+	st := status.Convert(err)
+	if st.Code() != codes.DeadlineExceeded {
+		t.Fatalf("placeOrder() = %v, want DeadlineExceeded", err)
+	}
+	if len(st.Details()) != 0 {
+		t.Errorf("timeout reported with details %v, want none as it is not a decline", st.Details())
+	}
+	f.payment.mu.Lock()
+	defer f.payment.mu.Unlock()
+	if want := chargeIdempotencyKey("order-1"); len(f.payment.voidedKeys) != 1 || f.payment.voidedKeys[0] != want {
+		t.Errorf("voided keys = %v, want [%s]", f.payment.voidedKeys, want)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+// Discount stacking policies, deciding which promotions apply when several
+// do.
+const (
+	// stackingAllow applies every promotion.
+	stackingAllow = "allow"
+	// stackingDisallow only applies the first promotion, coupons coming
+	// before free shipping.
+	stackingDisallow = "disallow"
+	// stackingBest only applies the promotion with the largest discount.
+	stackingBest = "best"
+
+	defaultDiscountStacking = stackingBest
+
+	couponPromotion       = "coupon"
+	freeShippingPromotion = "free_shipping"
+)
+
+// parseDiscountStacking validates a discount stacking policy.
+func parseDiscountStacking(v string) (string, error) {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case stackingAllow, stackingDisallow, stackingBest:
+		return v, nil
+	}
+	return "", fmt.Errorf("unsupported discount stacking policy %q, expected %s, %s or %s", v, stackingAllow, stackingDisallow, stackingBest)
+}
+
+// coupon is the discount granted by a coupon code, either a flat amount in
+// USD or a percentage of the items value.
+type coupon struct {
+	flat    *pb.Money
+	percent float64
+}
+
+// parseCoupons parses a comma-separated list of CODE=DISCOUNT pairs, where
+// the discount is a flat amount in USD or a percentage, e.g.
+// "SAVE5=5.00,WELCOME10=10%". Codes are case-insensitive.
+func parseCoupons(v string) (map[string]*coupon, error) {
+	out := make(map[string]*coupon)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid coupon %q, expected CODE=DISCOUNT", pair)
+		}
+		code, discount := strings.ToUpper(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+		if p := strings.TrimSuffix(discount, "%"); p != discount {
+			percent, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || percent < 0 || percent > 100 {
+				return nil, fmt.Errorf("invalid discount percentage %q of coupon %s", discount, code)
+			}
+			out[code] = &coupon{percent: percent}
+			continue
+		}
+		flat, err := parseAmount(usdCurrency, discount)
+		if err != nil {
+			return nil, err
+		}
+		out[code] = &coupon{flat: flat}
+	}
+	return out, nil
+}
+
+// promotion is a discount applicable to an order. code is the coupon code
+// of coupons.
+type promotion struct {
+	kind     string
+	code     string
+	discount *pb.Money
+}
+
+// name identifies p in the promotions of an order, e.g. "coupon:WELCOME10".
+func (p promotion) name() string {
+	if p.code == "" {
+		return p.kind
+	}
+	return p.kind + ":" + p.code
+}
+
+// applicablePromotions returns the promotions prep is eligible to, coupons
+// first.
+func (cs *checkoutService) applicablePromotions(ctx context.Context, prep *orderPrep, couponCode, userCurrency string) ([]promotion, error) {
+	subtotal, err := sumAmounts(userCurrency, prep.lineAmounts())
+	if err != nil {
+		return nil, err
+	}
+
+	var promos []promotion
+	if code := strings.ToUpper(strings.TrimSpace(couponCode)); code != "" {
+		c, ok := cs.coupons[code]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown coupon code %q", couponCode)
+		}
+		var discount *pb.Money
+		if c.flat != nil {
+			if discount, err = cs.convertCurrency(withCallResource(ctx, "currency.convert.coupon"), c.flat, userCurrency); err != nil {
+				return nil, downstreamError(err, "failed to convert coupon discount to currency")
+			}
+			// A coupon never discounts more than the items are worth.
+			left, err := sumAmounts(userCurrency, []namedAmount{{"items", subtotal}, {"coupon", money.Negate(*discount)}})
+			if err != nil {
+				return nil, err
+			}
+			if money.IsNegative(left) {
+				discount = &subtotal
+			}
+		} else {
+			discount = floatToMoney(moneyToFloat(&subtotal)*c.percent/100, userCurrency)
+		}
+		promos = append(promos, promotion{kind: couponPromotion, code: code, discount: discount})
+	}
+
+	if cs.freeShippingThreshold != nil {
+		threshold, err := cs.convertCurrency(withCallResource(ctx, "currency.convert.promotion"), cs.freeShippingThreshold, userCurrency)
+		if err != nil {
+			return nil, downstreamError(err, "failed to convert free shipping threshold to currency")
+		}
+		left, err := sumAmounts(userCurrency, []namedAmount{{"items", subtotal}, {"free shipping threshold", money.Negate(*threshold)}})
+		if err != nil {
+			return nil, err
+		}
+		if !money.IsNegative(left) {
+			// Free shipping does not waive the insurance fee.
+			amounts := []namedAmount{{"shipping", *prep.shippingCostLocalized}}
+			if prep.insuranceCost != nil {
+				amounts = append(amounts, namedAmount{"insurance", money.Negate(*prep.insuranceCost)})
+			}
+			shipping, err := sumAmounts(userCurrency, amounts)
+			if err != nil {
+				return nil, err
+			}
+			promos = append(promos, promotion{kind: freeShippingPromotion, discount: &shipping})
+		}
+	}
+	return promos, nil
+}
+
+// stackPromotions selects, according to policy, the promotions which apply
+// among the applicable ones.
+func stackPromotions(promos []promotion, policy string) []promotion {
+	if len(promos) <= 1 || policy == stackingAllow {
+		return promos
+	}
+	if policy == stackingDisallow {
+		return promos[:1]
+	}
+	best := promos[0]
+	for _, p := range promos[1:] {
+		if moneyToFloat(p.discount) > moneyToFloat(best.discount) {
+			best = p
+		}
+	}
+	return []promotion{best}
+}
+
+// applyPromotions records in prep the discount of the promotions applying
+// to the order, and each of them when applied promotions are listed.
+func (cs *checkoutService) applyPromotions(ctx context.Context, prep *orderPrep, couponCode, userCurrency string) error {
+	promos, err := cs.applicablePromotions(ctx, prep, couponCode, userCurrency)
+	if err != nil || len(promos) == 0 {
+		return err
+	}
+	policy := cs.discountStacking
+	if policy == "" {
+		policy = defaultDiscountStacking
+	}
+	discount := pb.Money{CurrencyCode: userCurrency}
+	for _, p := range stackPromotions(promos, policy) {
+		sum, err := money.Sum(discount, *p.discount)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to sum discounts: %v", err)
+		}
+		discount = sum
+		prep.promotions = append(prep.promotions, p.name())
+		if cs.listAppliedPromotions {
+			prep.appliedPromotions = append(prep.appliedPromotions, &pb.AppliedPromotion{
+				Type:     p.kind,
+				Code:     p.code,
+				Discount: p.discount})
+		}
+	}
+	prep.discount = &discount
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestParseCoupons(t *testing.T) {
+	got, err := parseCoupons("save5=5.00, WELCOME10=10%")
+	if err != nil {
+		t.Fatalf("parseCoupons() failed: %v", err)
+	}
+	if c := got["SAVE5"]; c == nil || !proto.Equal(c.flat, &pb.Money{CurrencyCode: "USD", Units: 5}) {
+		t.Errorf("SAVE5 = %+v, want a flat 5 USD discount", c)
+	}
+	if c := got["WELCOME10"]; c == nil || c.percent != 10 {
+		t.Errorf("WELCOME10 = %+v, want a 10%% discount", c)
+	}
+	for _, v := range []string{"SAVE5", "SAVE5=abc", "HALF=150%"} {
+		if _, err := parseCoupons(v); err == nil {
+			t.Errorf("parseCoupons(%q) succeeded, want an error", v)
+		}
+	}
+}
+
+func TestPlaceOrderDiscountStacking(t *testing.T) {
+	tests := []struct {
+		policy         string
+		wantPromotions []string
+		wantCharged    pb.Money
+	}{
+		{stackingAllow, []string{"coupon:SAVE5", freeShippingPromotion}, pb.Money{CurrencyCode: "USD", Units: 712, Nanos: 990000000}},
+		{stackingBest, []string{freeShippingPromotion}, pb.Money{CurrencyCode: "USD", Units: 717, Nanos: 990000000}},
+		{stackingDisallow, []string{"coupon:SAVE5"}, pb.Money{CurrencyCode: "USD", Units: 721, Nanos: 980000000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.coupons = map[string]*coupon{"SAVE5": {flat: &pb.Money{CurrencyCode: "USD", Units: 5}}}
+			cs.freeShippingThreshold = &pb.Money{CurrencyCode: "USD", Units: 100}
+			cs.discountStacking = tt.policy
+			req := testOrderRequest()
+			req.CouponCode = "save5"
+
+			res, err := cs.PlaceOrder(context.Background(), req)
+			if err != nil {
+				t.Fatalf("PlaceOrder() failed: %v", err)
+			}
+			if got := res.GetOrder().GetPromotions(); !reflect.DeepEqual(got, tt.wantPromotions) {
+				t.Errorf("promotions = %v, want %v", got, tt.wantPromotions)
+			}
+			if got := f.payment.charges[0].GetAmount(); !proto.Equal(got, &tt.wantCharged) {
+				t.Errorf("charged %v, want %v", got, tt.wantCharged)
+			}
+		})
+	}
+}
+
+func TestPromotionsWeighItemsByQuantity(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.coupons = map[string]*coupon{"WELCOME10": {percent: 10}}
+	// 19.99 + 2 * 349 USD of items, above the threshold only when the
+	// quantities are accounted for.
+	cs.freeShippingThreshold = &pb.Money{CurrencyCode: "USD", Units: 500}
+	cs.discountStacking = stackingAllow
+	cs.listAppliedPromotions = true
+	req := testOrderRequest()
+	req.CouponCode = "WELCOME10"
+
+	res, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	want := []*pb.AppliedPromotion{
+		{Type: couponPromotion, Code: "WELCOME10", Discount: &pb.Money{CurrencyCode: "USD", Units: 71, Nanos: 799000000}},
+		{Type: freeShippingPromotion, Discount: &pb.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000}},
+	}
+	got := res.GetOrder().GetAppliedPromotions()
+	if len(got) != len(want) {
+		t.Fatalf("applied promotions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("applied promotion %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPlaceOrderListsAppliedPromotions(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.coupons = map[string]*coupon{"SAVE5": {flat: &pb.Money{CurrencyCode: "USD", Units: 5}}}
+	cs.freeShippingThreshold = &pb.Money{CurrencyCode: "USD", Units: 100}
+	cs.discountStacking = stackingAllow
+	cs.listAppliedPromotions = true
+	req := testOrderRequest()
+	req.CouponCode = "save5"
+
+	res, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	want := []*pb.AppliedPromotion{
+		{Type: couponPromotion, Code: "SAVE5", Discount: &pb.Money{CurrencyCode: "USD", Units: 5}},
+		{Type: freeShippingPromotion, Discount: &pb.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000}},
+	}
+	got := res.GetOrder().GetAppliedPromotions()
+	if len(got) != len(want) {
+		t.Fatalf("applied promotions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("applied promotion %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPlaceOrderRejectsUnknownCoupon(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	req := testOrderRequest()
+	req.CouponCode = "NOPE"
+
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("PlaceOrder() = %v, want InvalidArgument", err)
+	}
+	if f.payment.chargeCount() != 0 {
+		t.Error("card should not be charged when the coupon is unknown")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// countryRegions maps countries, by lower-case name or ISO 3166 code, to the
+// region whose services should handle their orders.
+var countryRegions = map[string]string{}
+
+func init() {
+	regions := map[string][]string{
+		"eu": {
+			"at", "austria", "be", "belgium", "bg", "bulgaria", "hr", "croatia",
+			"cy", "cyprus", "cz", "czechia", "czech republic", "dk", "denmark",
+			"ee", "estonia", "fi", "finland", "fr", "france", "de", "germany",
+			"gr", "greece", "hu", "hungary", "ie", "ireland", "it", "italy",
+			"lv", "latvia", "lt", "lithuania", "lu", "luxembourg", "mt", "malta",
+			"nl", "netherlands", "pl", "poland", "pt", "portugal", "ro", "romania",
+			"sk", "slovakia", "si", "slovenia", "es", "spain", "se", "sweden",
+		},
+		"na": {
+			"us", "usa", "united states", "united states of america",
+			"ca", "canada", "mx", "mexico",
+		},
+		"apac": {
+			"au", "australia", "cn", "china", "hk", "hong kong", "in", "india",
+			"jp", "japan", "kr", "south korea", "nz", "new zealand",
+			"sg", "singapore", "tw", "taiwan",
+		},
+	}
+	for region, countries := range regions {
+		for _, c := range countries {
+			countryRegions[c] = region
+		}
+	}
+}
+
+// regionOf returns the region of a shipping address, or "" if unknown.
+func regionOf(addr *pb.Address) string {
+	return countryRegions[strings.ToLower(strings.TrimSpace(addr.GetCountry()))]
+}
+
+type regionKey struct{}
+
+// withRegion returns a context routing the downstream calls made with it to
+// the services of region, when configured.
+func withRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// regionalConn is a connection to the replica of a service in a region.
+type regionalConn struct {
+	addr string
+	conn *grpc.ClientConn
+}
+
+// parseRegionAddrs parses a comma-separated list of REGION=ADDRESS pairs,
+// e.g. "eu=currencyservice.eu:7000,apac=currencyservice.apac:7000".
+func parseRegionAddrs(v string) (map[string]*regionalConn, error) {
+	out := make(map[string]*regionalConn)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid regional address %q, expected REGION=ADDRESS", pair)
+		}
+		out[strings.ToLower(strings.TrimSpace(kv[0]))] = &regionalConn{addr: strings.TrimSpace(kv[1])}
+	}
+	return out, nil
+}
+
+// currencyClient returns a client of the currency service of the region of
+// ctx, falling back to the global currency service.
+func (cs *checkoutService) currencyClient(ctx context.Context) pb.CurrencyServiceClient {
+	if c, ok := cs.regionalCurrencyClient(ctx); ok {
+		return c
+	}
+	return cs.clients().currency()
+}
+
+// regionalCurrencyClient returns a client of the currency service of the
+// region of ctx, if one is configured.
+func (cs *checkoutService) regionalCurrencyClient(ctx context.Context) (pb.CurrencyServiceClient, bool) {
+	region, _ := ctx.Value(regionKey{}).(string)
+	if rc, ok := cs.currencyRegions[region]; ok && rc.conn != nil {
+		return pb.NewCurrencyServiceClient(rc.conn), true
+	}
+	return nil, false
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestRegionOf(t *testing.T) {
+	for country, want := range map[string]string{
+		"Germany":       "eu",
+		" FR ":          "eu",
+		"United States": "na",
+		"Japan":         "apac",
+		"Atlantis":      "",
+	} {
+		if got := regionOf(&pb.Address{Country: country}); got != want {
+			t.Errorf("regionOf(%q) = %q, want %q", country, got, want)
+		}
+	}
+}
+
+func TestPlaceOrderRoutesCurrencyToRegion(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	eu := &fakeCurrencyService{rates: map[string]float64{"EUR": 0.5}}
+	rc := &regionalConn{addr: startFakeServer(t, func(s *grpc.Server) {
+		pb.RegisterCurrencyServiceServer(s, eu)
+	})}
+	conn, err := grpc.Dial(rc.addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rc.conn = conn
+	cs.currencyRegions = map[string]*regionalConn{"eu": rc}
+
+	req := testOrderRequest()
+	req.UserCurrency = "EUR"
+	req.Address.Country = "Germany"
+	if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if eu.callCount() == 0 || f.currency.callCount() != 0 {
+		t.Errorf("got %d EU and %d global conversions, want all of them in the EU", eu.callCount(), f.currency.callCount())
+	}
+
+	req = testOrderRequest()
+	req.UserCurrency = "EUR"
+	euCalls := eu.callCount()
+	if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if eu.callCount() != euCalls || f.currency.callCount() == 0 {
+		t.Error("orders shipped outside configured regions should use the global currency service")
+	}
+}
+
+func TestParseRegionAddrs(t *testing.T) {
+	got, err := parseRegionAddrs("EU=currency-eu:7000, apac=currency-apac:7000")
+	if err != nil {
+		t.Fatalf("parseRegionAddrs() failed: %v", err)
+	}
+	if got["eu"].addr != "currency-eu:7000" || got["apac"].addr != "currency-apac:7000" {
+		t.Errorf("parseRegionAddrs() = %v", got)
+	}
+	for _, bad := range []string{"eu", "eu="} {
+		if _, err := parseRegionAddrs(bad); err == nil {
+			t.Errorf("parseRegionAddrs(%q) should fail", bad)
+		}
+	}
+}
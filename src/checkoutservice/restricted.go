@@ -0,0 +1,28 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// ageVerifiedKey is the order metadata entry asserting, when "true", that
+// the user is eligible to buy restricted products, e.g. once the frontend
+// verified their age.
+const ageVerifiedKey = "age_verified"
+
+// checkRestrictedItems fails with PermissionDenied if items contain one of
+// the products listed in RESTRICTED_PRODUCTS and the order metadata does not
+// assert the eligibility of the user.
+func (cs *checkoutService) checkRestrictedItems(items []*pb.OrderItem, metadata map[string]string) error {
+	if len(cs.restrictedProducts) == 0 || metadata[ageVerifiedKey] == "true" {
+		return nil
+	}
+	for _, it := range items {
+		if id := it.GetItem().GetProductId(); cs.restrictedProducts[id] {
+			return status.Errorf(codes.PermissionDenied, "product %s is restricted, the order metadata must assert eligibility with %s=true", id, ageVerifiedKey)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlaceOrderRestrictedItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     codes.Code
+	}{
+		{"eligible", map[string]string{ageVerifiedKey: "true"}, codes.OK},
+		{"not asserted", nil, codes.PermissionDenied},
+		{"not eligible", map[string]string{ageVerifiedKey: "false"}, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.restrictedProducts = map[string]bool{"66VCHSJNUP": true}
+
+			req := testOrderRequest()
+			req.Metadata = tt.metadata
+			_, err := cs.PlaceOrder(context.Background(), req)
+			if status.Code(err) != tt.want {
+				t.Fatalf("PlaceOrder() = %v, want %v", err, tt.want)
+			}
+			wantCharges := 0
+			if tt.want == codes.OK {
+				wantCharges = 1
+			}
+			if n := f.payment.chargeCount(); n != wantCharges {
+				t.Errorf("card charged %d times, want %d", n, wantCharges)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const retryBackoff = 20 * time.Millisecond
+
+// retryableMethods lists the read-only downstream methods which are safe to
+// call again after a transient failure.
+var retryableMethods = map[string]bool{
+	"/hipstershop.CartService/GetCart":                     true,
+	"/hipstershop.ProductCatalogService/GetProduct":        true,
+	"/hipstershop.ProductCatalogService/CheckAvailability": true,
+	"/hipstershop.CurrencyService/Convert":                 true,
+	"/hipstershop.CurrencyService/ConvertBatch":            true,
+	"/hipstershop.CurrencyService/GetSupportedCurrencies":  true,
+	"/hipstershop.ShippingService/GetQuote":                true,
+}
+
+// parseServiceRetries parses a comma-separated list of SERVICE=RETRIES
+// pairs, e.g. "productcatalogservice=2,currencyservice=1".
+func parseServiceRetries(v string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid service retries %q, expected SERVICE=RETRIES", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number of retries %q for %s", kv[1], kv[0])
+		}
+		out[strings.TrimSpace(kv[0])] = n
+	}
+	return out, nil
+}
+
+type retryBudgetKey struct{}
+
+// withRetryBudget caps to n the retries of all the downstream calls made
+// with the returned context, so that a large cart cannot pile up retries.
+func withRetryBudget(ctx context.Context, n int) context.Context {
+	left := int64(n)
+	return context.WithValue(ctx, retryBudgetKey{}, &left)
+}
+
+// takeRetry reports whether another retry is allowed in ctx, and counts it
+// against the budget of ctx if any.
+func takeRetry(ctx context.Context) bool {
+	left, ok := ctx.Value(retryBudgetKey{}).(*int64)
+	if !ok {
+		return true
+	}
+	return atomic.AddInt64(left, -1) >= 0
+}
+
+// retryInterceptor retries the read-only calls to service failing with
+// Unavailable, up to the retries configured for the service and as long as
+// the budget of the request allows.
+func (cs *checkoutService) retryInterceptor(service string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if !retryableMethods[method] {
+			return err
+		}
+		for i := 0; i < cs.serviceRetries[service] && status.Code(err) == codes.Unavailable; i++ {
+			if !takeRetry(ctx) {
+				log.Debugf("retry budget of the request exhausted, not retrying %s", method)
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(retryBackoff):
+			}
+			log.Debugf("retrying %s after %v (%d/%d)", method, err, i+1, cs.serviceRetries[service])
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return err
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// flakyCatalog returns downstreams whose cart holds n products, the first
+// lookup of each failing with Unavailable.
+func flakyCatalog(n int) *fakeDownstreams {
+	f := newFakeDownstreams()
+	f.catalog.failures = make(map[string]int)
+	var cart []*pb.CartItem
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("PRODUCT%d", i)
+		f.catalog.products[id] = &pb.Product{Id: id, PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 5}}
+		f.catalog.failures[id] = 1
+		cart = append(cart, &pb.CartItem{ProductId: id, Quantity: 1})
+	}
+	f.cart.carts["user-1"] = cart
+	return f
+}
+
+func TestPlaceOrderRetriesTransientFailures(t *testing.T) {
+	f := flakyCatalog(5)
+	cs := newTestCheckoutService(t, f)
+	cs.serviceRetries = map[string]int{"productcatalogservice": 1}
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if f.catalog.calls != 10 {
+		t.Errorf("got %d product lookups, want 10", f.catalog.calls)
+	}
+}
+
+func TestPlaceOrderExhaustsRetryBudget(t *testing.T) {
+	f := flakyCatalog(5)
+	cs := newTestCheckoutService(t, f)
+	cs.serviceRetries = map[string]int{"productcatalogservice": 1}
+	cs.maxRequestRetries = 3
+
+	_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("PlaceOrder() error = %v, want Unavailable", err)
+	}
+	if retries := f.catalog.calls - 5; retries > 3 {
+		t.Errorf("got %d retries, want at most 3", retries)
+	}
+	if n := f.payment.chargeCount(); n != 0 {
+		t.Errorf("card was charged %d times", n)
+	}
+}
+
+func TestRetryInterceptorSkipsNonIdempotentMethods(t *testing.T) {
+	f := newFakeDownstreams()
+	f.payment.err = status.Error(codes.Unavailable, "payment gateway down")
+	cs := newTestCheckoutService(t, f)
+	cs.serviceRetries = map[string]int{"paymentservice": 3}
+
+	if _, err := cs.chargeCard(context.Background(), &pb.Money{CurrencyCode: "USD", Units: 1}, nil, "key"); status.Code(err) != codes.Unavailable {
+		t.Fatalf("chargeCard() error = %v, want Unavailable", err)
+	}
+	if n := f.payment.calls; n != 1 {
+		t.Errorf("charge attempted %d times, want 1", n)
+	}
+}
+
+func TestParseServiceRetries(t *testing.T) {
+	got, err := parseServiceRetries("cartservice=2, currencyservice=0")
+	if err != nil {
+		t.Fatalf("parseServiceRetries() failed: %v", err)
+	}
+	if got["cartservice"] != 2 || got["currencyservice"] != 0 || len(got) != 2 {
+		t.Errorf("parseServiceRetries() = %v", got)
+	}
+	for _, bad := range []string{"cartservice", "cartservice=-1", "cartservice=x"} {
+		if _, err := parseServiceRetries(bad); err == nil {
+			t.Errorf("parseServiceRetries(%q) should fail", bad)
+		}
+	}
+}
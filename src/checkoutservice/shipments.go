@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+// splitShipments splits items into shipments of at most maxItems units each,
+// splitting the quantity of an item across shipments if needed. The parts
+// of an item keep all its other fields. A limit of zero ships everything
+// together.
+func splitShipments(items []*pb.CartItem, maxItems int) [][]*pb.CartItem {
+	if maxItems <= 0 {
+		return [][]*pb.CartItem{items}
+	}
+	var (
+		shipments [][]*pb.CartItem
+		current   []*pb.CartItem
+		room      = maxItems
+	)
+	for _, item := range items {
+		for left := int(item.GetQuantity()); left > 0; {
+			n := left
+			if n > room {
+				n = room
+			}
+			part := proto.Clone(item).(*pb.CartItem)
+			part.Quantity = int32(n)
+			current = append(current, part)
+			left -= n
+			room -= n
+			if room == 0 {
+				shipments = append(shipments, current)
+				current, room = nil, maxItems
+			}
+		}
+	}
+	if len(current) > 0 || len(shipments) == 0 {
+		shipments = append(shipments, current)
+	}
+	return shipments
+}
+
+// parseShippingFees parses a comma-separated list of [REGION:]CURRENCY=FEE
+// entries setting the shipping fee of orders in a currency, optionally only
+// for a region, e.g. "EUR=5.90,apac:JPY=800".
+func parseShippingFees(v string) (map[string]*pb.Money, error) {
+	out := make(map[string]*pb.Money)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid shipping fee %q, expected [REGION:]CURRENCY=FEE", pair)
+		}
+		key := strings.TrimSpace(kv[0])
+		region, currency := "", key
+		if i := strings.Index(key, ":"); i >= 0 {
+			region, currency = strings.ToLower(strings.TrimSpace(key[:i])), strings.TrimSpace(key[i+1:])
+		}
+		currency = strings.ToUpper(currency)
+		if !currencyCodeRe.MatchString(currency) {
+			return nil, fmt.Errorf("invalid currency code %q in shipping fee %q", currency, pair)
+		}
+		fee, err := parseAmount(currency, strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, err
+		}
+		out[shippingFeeKey(region, currency)] = fee
+	}
+	return out, nil
+}
+
+func shippingFeeKey(region, currency string) string {
+	if region == "" {
+		return currency
+	}
+	return region + ":" + currency
+}
+
+// nativeShippingFee returns the shipping fee configured for orders shipped
+// to addr in currency, preferring the one of its region, or nil if none is.
+func (cs *checkoutService) nativeShippingFee(addr *pb.Address, currency string) *pb.Money {
+	if region := regionOf(addr); region != "" {
+		if fee, ok := cs.shippingFees[shippingFeeKey(region, currency)]; ok {
+			return fee
+		}
+	}
+	return cs.shippingFees[currency]
+}
+
+// checkShippingQuote rejects shipping quotes that look wrong, either above
+// the configured absolute maximum or too large a multiple of the items
+// subtotal, rather than charging them to the customer.
+func (cs *checkoutService) checkShippingQuote(shippingUSD, shippingLocal *pb.Money, items []*pb.OrderItem) error {
+	if max := cs.maxShippingCost; max != nil && moneyToFloat(shippingUSD) > moneyToFloat(max) {
+		log.Warnf("shipping quote of %s is above the %s maximum", displayAmount(shippingUSD), displayAmount(max))
+		return status.Errorf(codes.Internal, "shipping quote of %s exceeds the maximum shipping cost", displayAmount(shippingUSD))
+	}
+	if cs.maxShippingRatio > 0 {
+		var subtotal float64
+		for _, it := range items {
+			subtotal += moneyToFloat(it.GetCost()) * float64(it.GetItem().GetQuantity())
+		}
+		if shipping := moneyToFloat(shippingLocal); shipping > cs.maxShippingRatio*subtotal {
+			log.Warnf("shipping quote of %s is more than %v times the items subtotal", displayAmount(shippingLocal), cs.maxShippingRatio)
+			return status.Errorf(codes.Internal, "shipping quote of %s is out of proportion with the order", displayAmount(shippingLocal))
+		}
+	}
+	return nil
+}
+
+// shippingInsurance is the fee charged to insure a shipment, either a flat
+// amount in USD or a percentage of the items value.
+type shippingInsurance struct {
+	flat    *pb.Money
+	percent float64
+}
+
+// parseShippingInsurance parses a flat fee in USD such as "2.50", or a
+// percentage of the items value such as "1.5%".
+func parseShippingInsurance(v string) (*shippingInsurance, error) {
+	v = strings.TrimSpace(v)
+	if p := strings.TrimSuffix(v, "%"); p != v {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || percent < 0 {
+			return nil, fmt.Errorf("invalid insurance percentage %q", v)
+		}
+		return &shippingInsurance{percent: percent}, nil
+	}
+	flat, err := parseAmount(usdCurrency, v)
+	if err != nil {
+		return nil, err
+	}
+	return &shippingInsurance{flat: flat}, nil
+}
+
+// insureShipping adds the insurance fee, in the user currency, to the
+// shipping cost of prep.
+func (cs *checkoutService) insureShipping(ctx context.Context, prep *orderPrep, userCurrency string) error {
+	if cs.shippingInsurance == nil {
+		return status.Errorf(codes.FailedPrecondition, "shipping insurance is not available")
+	}
+	var fee *pb.Money
+	if flat := cs.shippingInsurance.flat; flat != nil {
+		var err error
+		if fee, err = cs.convertCurrency(withCallResource(ctx, "currency.convert.insurance"), flat, userCurrency); err != nil {
+			return downstreamError(err, "failed to convert insurance fee to currency")
+		}
+		prep.conversions = append(prep.conversions, newConversionRecord("insurance", flat, fee))
+	} else {
+		var value, valueUSD float64
+		for _, it := range prep.orderItems {
+			value += moneyToFloat(it.GetCost()) * float64(it.GetItem().GetQuantity())
+		}
+		// The fee is recorded against the same percentage of the catalog
+		// prices, so that it can be told apart from conversion drift.
+		prices := make(map[string]*pb.Money)
+		for _, c := range prep.conversions {
+			prices[c.GetDescription()] = c.GetFrom()
+		}
+		for _, it := range prep.orderItems {
+			valueUSD += moneyToFloat(prices["product:"+it.GetItem().GetProductId()]) * float64(it.GetItem().GetQuantity())
+		}
+		fee = floatToMoney(value*cs.shippingInsurance.percent/100, userCurrency)
+		feeUSD := floatToMoney(valueUSD*cs.shippingInsurance.percent/100, usdCurrency)
+		prep.conversions = append(prep.conversions, newConversionRecord("insurance", feeUSD, fee))
+	}
+	shipping, err := money.Sum(*prep.shippingCostLocalized, *fee)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to add insurance fee to shipping cost: %v", err)
+	}
+	prep.shippingCostLocalized = &shipping
+	prep.insuranceCost = fee
+	return nil
+}
+
+// floatToMoney converts a non-negative amount to a money value, rounded to
+// the nano.
+func floatToMoney(amount float64, currency string) *pb.Money {
+	units := int64(amount)
+	nanos := int64(math.Round((amount - float64(units)) * 1e9))
+	if nanos >= 1e9 {
+		units, nanos = units+1, nanos-1e9
+	}
+	return &pb.Money{CurrencyCode: currency, Units: units, Nanos: int32(nanos)}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestSplitShipments(t *testing.T) {
+	items := []*pb.CartItem{
+		{ProductId: "A", Quantity: 1},
+		{ProductId: "B", Quantity: 4, Note: "blue", GiftWrap: true},
+	}
+	got := splitShipments(items, 2)
+	want := [][]*pb.CartItem{
+		{{ProductId: "A", Quantity: 1}, {ProductId: "B", Quantity: 1}},
+		{{ProductId: "B", Quantity: 2}},
+		{{ProductId: "B", Quantity: 1}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("splitShipments() returned %d shipments, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("shipment %d = %v, want %v", i, got[i], want[i])
+		}
+		for j := range want[i] {
+			if got[i][j].GetProductId() != want[i][j].GetProductId() || got[i][j].GetQuantity() != want[i][j].GetQuantity() {
+				t.Errorf("shipment %d item %d = %v, want %v", i, j, got[i][j], want[i][j])
+			}
+			if it := got[i][j]; it.GetProductId() == "B" && (!it.GetGiftWrap() || it.GetNote() != "blue") {
+				t.Errorf("shipment %d item %d = %v, want the note and gift wrapping of the item", i, j, it)
+			}
+		}
+	}
+
+	if got := splitShipments(items, 0); len(got) != 1 || len(got[0]) != 2 {
+		t.Errorf("splitShipments() without a limit = %v, want a single shipment", got)
+	}
+}
+
+func TestPlaceOrderSplitsShipments(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.maxItemsPerShipment = 2
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	order := resp.GetOrder()
+	if ids := order.GetShippingTrackingIds(); len(ids) != 2 || ids[0] != "TRACK-1" || ids[1] != "TRACK-2" {
+		t.Errorf("tracking ids = %v, want [TRACK-1 TRACK-2]", ids)
+	}
+	if order.GetShippingTrackingId() != "TRACK-1" {
+		t.Errorf("tracking id = %q, want TRACK-1", order.GetShippingTrackingId())
+	}
+	// Two shipments quoted at $8.99 each.
+	if cost := order.GetShippingCost(); cost.GetUnits() != 17 || cost.GetNanos() != 980000000 {
+		t.Errorf("shipping cost = %v, want 17.98", cost)
+	}
+}
+
+func TestPlaceOrderShippingQuoteGuard(t *testing.T) {
+	outlier := &pb.Money{CurrencyCode: "USD", Units: 5000}
+	tests := []struct {
+		name     string
+		quote    *pb.Money
+		maxCost  *pb.Money
+		maxRatio float64
+		wantCode codes.Code
+	}{
+		{"normal quote", nil, &pb.Money{CurrencyCode: "USD", Units: 100}, 2, codes.OK},
+		{"above absolute maximum", outlier, &pb.Money{CurrencyCode: "USD", Units: 100}, 0, codes.Internal},
+		{"out of proportion", outlier, nil, 2, codes.Internal},
+		{"guard disabled", outlier, nil, 0, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			if tt.quote != nil {
+				f.shipping.quote = tt.quote
+			}
+			cs := newTestCheckoutService(t, f)
+			cs.maxShippingCost = tt.maxCost
+			cs.maxShippingRatio = tt.maxRatio
+
+			_, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("PlaceOrder() = %v, want %v", err, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK && f.payment.chargeCount() != 0 {
+				t.Error("order with a suspicious shipping quote should not be charged")
+			}
+		})
+	}
+}
+
+func TestPlaceOrderShippingInsurance(t *testing.T) {
+	tests := []struct {
+		name         string
+		insured      bool
+		fee          string
+		wantFee      *pb.Money
+		wantShipping *pb.Money
+	}{
+		{"uninsured", false, "2.00", nil, &pb.Money{CurrencyCode: "EUR", Units: 4, Nanos: 495000000}},
+		{"flat fee", true, "2.00", &pb.Money{CurrencyCode: "EUR", Units: 1}, &pb.Money{CurrencyCode: "EUR", Units: 5, Nanos: 495000000}},
+		// 1% of 9.995 + 2 * 174.50 EUR.
+		{"percent fee", true, "1%", &pb.Money{CurrencyCode: "EUR", Units: 3, Nanos: 589950000}, &pb.Money{CurrencyCode: "EUR", Units: 8, Nanos: 84950000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			ins, err := parseShippingInsurance(tt.fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cs.shippingInsurance = ins
+			cs.chargeAmountCheck = true
+			cs.chargeAmountTolerance = defaultChargeAmountTolerance
+			m := &recordingMetrics{}
+			cs.metrics = m
+
+			req := testOrderRequest()
+			req.UserCurrency = "EUR"
+			req.Insured = tt.insured
+			resp, err := cs.PlaceOrder(context.Background(), req)
+			if err != nil {
+				t.Fatalf("PlaceOrder() failed: %v", err)
+			}
+			order := resp.GetOrder()
+			if !proto.Equal(order.GetInsuranceCost(), tt.wantFee) {
+				t.Errorf("insurance cost = %v, want %v", order.GetInsuranceCost(), tt.wantFee)
+			}
+			if !proto.Equal(order.GetShippingCost(), tt.wantShipping) {
+				t.Errorf("shipping cost = %v, want %v", order.GetShippingCost(), tt.wantShipping)
+			}
+			if n := m.count(chargeDiscrepancyMetric, nil); n != 0 {
+				t.Errorf("insurance fee counted as %d charge amount discrepancies", n)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderInsuranceNotOffered(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.Insured = true
+	if _, err := cs.PlaceOrder(context.Background(), req); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("PlaceOrder() error = %v, want FailedPrecondition", err)
+	}
+	if n := f.payment.chargeCount(); n != 0 {
+		t.Errorf("card was charged %d times", n)
+	}
+}
+
+func TestParseShippingInsurance(t *testing.T) {
+	if ins, err := parseShippingInsurance("1.5%"); err != nil || ins.percent != 1.5 || ins.flat != nil {
+		t.Errorf("parseShippingInsurance(1.5%%) = %+v, %v", ins, err)
+	}
+	if ins, err := parseShippingInsurance("2.50"); err != nil || ins.flat.GetUnits() != 2 || ins.flat.GetNanos() != 500000000 {
+		t.Errorf("parseShippingInsurance(2.50) = %+v, %v", ins, err)
+	}
+	for _, bad := range []string{"abc", "-1%", "x%", "-2"} {
+		if _, err := parseShippingInsurance(bad); err == nil {
+			t.Errorf("parseShippingInsurance(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPlaceOrderNativeShippingFee(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	fees, err := parseShippingFees("EUR=5.90,eu:EUR=4.90")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.shippingFees = fees
+
+	for country, want := range map[string]*pb.Money{
+		"Germany":       {CurrencyCode: "EUR", Units: 4, Nanos: 900000000},
+		"United States": {CurrencyCode: "EUR", Units: 5, Nanos: 900000000},
+	} {
+		req := testOrderRequest()
+		req.UserCurrency = "EUR"
+		req.Address.Country = country
+		resp, err := cs.PlaceOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+		order := resp.GetOrder()
+		if !proto.Equal(order.GetShippingCost(), want) {
+			t.Errorf("shipping cost to %s = %v, want %v", country, order.GetShippingCost(), want)
+		}
+		for _, c := range order.GetConversions() {
+			if c.GetDescription() == "shipping" {
+				t.Errorf("shipping cost to %s was converted, want the native fee", country)
+			}
+		}
+	}
+
+	// Currencies without a fee fall back to converting the quote.
+	req := testOrderRequest()
+	req.UserCurrency = "JPY"
+	resp, err := cs.PlaceOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if want := (&pb.Money{CurrencyCode: "JPY", Units: 899}); !proto.Equal(resp.GetOrder().GetShippingCost(), want) {
+		t.Errorf("shipping cost = %v, want the converted quote %v", resp.GetOrder().GetShippingCost(), want)
+	}
+}
+
+func TestParseShippingFees(t *testing.T) {
+	got, err := parseShippingFees("eur=5.90, APAC:jpy=800")
+	if err != nil {
+		t.Fatalf("parseShippingFees() failed: %v", err)
+	}
+	if !proto.Equal(got["EUR"], &pb.Money{CurrencyCode: "EUR", Units: 5, Nanos: 900000000}) || !proto.Equal(got["apac:JPY"], &pb.Money{CurrencyCode: "JPY", Units: 800}) {
+		t.Errorf("parseShippingFees() = %v", got)
+	}
+	for _, bad := range []string{"EUR", "EURO=5", "EUR=-1", "eu:=5"} {
+		if _, err := parseShippingFees(bad); err == nil {
+			t.Errorf("parseShippingFees(%q) should fail", bad)
+		}
+	}
+}
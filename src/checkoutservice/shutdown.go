@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultShutdownGracePeriod = 25 * time.Second
+	defaultDrainLogInterval    = time.Second
+)
+
+// inFlightCounter tracks the number of requests currently being served.
+type inFlightCounter struct {
+	n int64
+}
+
+func (c *inFlightCounter) count() int64 { return atomic.LoadInt64(&c.n) }
+
+func (c *inFlightCounter) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	atomic.AddInt64(&c.n, 1)
+	defer atomic.AddInt64(&c.n, -1)
+	return handler(ctx, req)
+}
+
+// drain calls stop, which is expected to block until all in-flight requests
+// have completed, and periodically logs how many requests are still running.
+// If stop has not returned after grace, forceStop is called to abort the
+// remaining requests. It reports whether all requests drained in time.
+func drain(stop, forceStop func(), c *inFlightCounter, grace, interval time.Duration) bool {
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+
+	deadline := time.NewTimer(grace)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	drained := true
+loop:
+	for {
+		select {
+		case <-done:
+			break loop
+		case <-ticker.C:
+			log.WithField("in_flight", c.count()).Info("waiting for in-flight requests to drain")
+		case <-deadline.C:
+			drained = false
+			log.WithField("in_flight", c.count()).Warn("grace period expired, aborting remaining requests")
+			forceStop()
+			<-done
+			break loop
+		}
+	}
+	log.WithFields(logrus.Fields{
+		"drained":   drained,
+		"in_flight": c.count(),
+		"elapsed":   time.Since(start).String(),
+	}).Info("shutdown complete")
+	return drained
+}
+
+// Files, in the spill directory, the queued work left on shutdown is
+// appended to.
+const (
+	undeliveredWebhooksFile   = "webhook-notifications.jsonl"
+	deferredConfirmationsFile = "deferred-confirmations.jsonl"
+	pendingRedemptionsFile    = "point-redemptions.jsonl"
+)
+
+// flushQueues delivers the queued webhook notifications for up to timeout,
+// then persists those left undelivered, the deferred confirmations not sent
+// yet and the pending point redemptions as JSON lines in dir, to be
+// replayed. Without dir, they are only logged as lost.
+func (cs *checkoutService) flushQueues(webhook *webhookNotifier, timeout time.Duration, dir string) {
+	var orders, confirmations, redemptions []proto.Message
+	if webhook != nil {
+		for _, o := range webhook.flush(timeout) {
+			orders = append(orders, o)
+		}
+	}
+	if cs.emailLimiter != nil {
+		for _, c := range cs.emailLimiter.takeDeferred() {
+			confirmations = append(confirmations, c)
+		}
+	}
+	for _, r := range cs.redemptions.take() {
+		redemptions = append(redemptions, r.req)
+	}
+	for file, msgs := range map[string][]proto.Message{
+		undeliveredWebhooksFile:   orders,
+		deferredConfirmationsFile: confirmations,
+		pendingRedemptionsFile:    redemptions,
+	} {
+		if len(msgs) == 0 {
+			continue
+		}
+		if dir == "" {
+			log.Warnf("dropping %d unflushed items of %s on shutdown, no spill directory is configured", len(msgs), file)
+			continue
+		}
+		path := filepath.Join(dir, file)
+		if err := appendJSONLines(path, msgs); err != nil {
+			log.Errorf("failed to persist %d unflushed items to %s: %+v", len(msgs), path, err)
+			continue
+		}
+		log.Infof("persisted %d unflushed items to %s", len(msgs), path)
+	}
+}
+
+// appendJSONLines appends msgs to the file at path, one proto JSON object
+// per line.
+func appendJSONLines(path string, msgs []proto.Message) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, m := range msgs {
+		b, err := protojson.Marshal(proto.MessageV2(m))
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(b)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestDrainReportsInFlightRequests(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Out
+	log.Out = &buf
+	defer func() { log.Out = out }()
+
+	c := &inFlightCounter{}
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go c.unaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			defer wg.Done()
+			<-release
+			return nil, nil
+		})
+	}
+	for c.count() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	stop := func() { wg.Wait() }
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if !drain(stop, func() {}, c, time.Second, 10*time.Millisecond) {
+		t.Error("drain() reported requests were aborted")
+	}
+
+	got := buf.String()
+	if !strings.Contains(got, `"in_flight":2`) {
+		t.Errorf("drain log should report 2 in-flight requests, got %s", got)
+	}
+	if !strings.Contains(got, "shutdown complete") {
+		t.Errorf("drain log should contain a final summary, got %s", got)
+	}
+}
+
+func TestDrainForcesStopAfterGracePeriod(t *testing.T) {
+	out := log.Out
+	log.Out = &bytes.Buffer{}
+	defer func() { log.Out = out }()
+
+	block := make(chan struct{})
+	forced := false
+	forceStop := func() {
+		forced = true
+		close(block)
+	}
+	if drain(func() { <-block }, forceStop, &inFlightCounter{}, 20*time.Millisecond, time.Second) {
+		t.Error("drain() should report that requests did not drain")
+	}
+	if !forced {
+		t.Error("forceStop was not called after the grace period")
+	}
+}
+
+// readJSONLines decodes the proto JSON lines of the file at path, with
+// newMsg allocating each message.
+func readJSONLines(t *testing.T, path string, newMsg func() proto.Message) []proto.Message {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out []proto.Message
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		m := newMsg()
+		if err := protojson.Unmarshal(sc.Bytes(), proto.MessageV2(m)); err != nil {
+			t.Fatalf("invalid line in %s: %v", path, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestFlushQueuesPersistsUnflushedItems(t *testing.T) {
+	// The webhook keeps failing, its notifications cannot be delivered
+	// within the timeout.
+	rec := &webhookRecorder{statuses: []int{503, 503, 503, 503, 503, 503}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	n := newWebhookNotifier(srv.URL, "", 10, 5)
+	n.retryBackoff = time.Second
+
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.notifier = n
+	cs.emailLimiter = newEmailRateLimiter(1, time.Hour)
+	for i := 0; i < 2; i++ {
+		req := testOrderRequest()
+		req.Metadata = map[string]string{giftMessageKey: "Enjoy!"}
+		if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+			t.Fatalf("PlaceOrder() failed: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	cs.flushQueues(n, 50*time.Millisecond, dir)
+
+	orders := readJSONLines(t, filepath.Join(dir, undeliveredWebhooksFile), func() proto.Message { return new(pb.OrderResult) })
+	if len(orders) != 2 {
+		t.Errorf("persisted %d webhook notifications, want 2", len(orders))
+	}
+	confirmations := readJSONLines(t, filepath.Join(dir, deferredConfirmationsFile), func() proto.Message { return new(pb.SendOrderConfirmationRequest) })
+	if len(confirmations) != 1 || confirmations[0].(*pb.SendOrderConfirmationRequest).GetEmail() != testOrderRequest().GetEmail() {
+		t.Errorf("persisted confirmations %v, want the deferred one", confirmations)
+	} else if msg := confirmations[0].(*pb.SendOrderConfirmationRequest).GetGiftMessage(); msg != "Enjoy!" {
+		t.Errorf("persisted confirmation gift message = %q, want Enjoy!", msg)
+	}
+	if n := f.email.sentCount(); n != 1 {
+		t.Errorf("sent %d confirmations, want only the one not rate limited", n)
+	}
+}
+
+func TestFlushQueuesDeliversWithinTimeout(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	n := newWebhookNotifier(srv.URL, "", 10, 1)
+	n.OrderPlaced(&pb.OrderResult{OrderId: "order-1"})
+	n.OrderPlaced(&pb.OrderResult{OrderId: "order-2"})
+
+	dir := t.TempDir()
+	(&checkoutService{}).flushQueues(n, time.Second, dir)
+
+	if len(rec.bodies) != 2 {
+		t.Errorf("webhook received %d notifications, want 2", len(rec.bodies))
+	}
+	if _, err := os.Stat(filepath.Join(dir, undeliveredWebhooksFile)); !os.IsNotExist(err) {
+		t.Errorf("notifications were persisted although delivered: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// SimulateOrder validates and prices an order with the same downstream reads
+// as PlaceOrder, so that load tests get a realistic profile, but stops
+// before any side effect: no order number is allocated, the card is not
+// charged, nothing ships, no email is sent and the cart is left untouched.
+func (cs *checkoutService) SimulateOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.SimulateOrderResponse, error) {
+	log.Infof("[SimulateOrder] user_id=%q user_currency=%q", req.UserId, req.UserCurrency)
+
+	if !cs.orderSimulation {
+		return nil, status.Errorf(codes.Unimplemented, "order simulation is not enabled")
+	}
+	if err := cs.validateOrderRequest(req); err != nil {
+		return nil, err
+	}
+	prep, total, err := cs.priceOrder(cs.orderContext(ctx, "", req), req)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SimulateOrderResponse{
+		Order: withItemDetail(&pb.OrderResult{
+			ShippingCost:     prep.shippingCostLocalized,
+			ShippingAddress:  req.Address,
+			Items:            prep.orderItems,
+			Metadata:         req.GetMetadata(),
+			Conversions:      prep.conversions,
+			UnavailableItems: prep.unavailableItems,
+			InsuranceCost:    prep.insuranceCost,
+			GiftWrapCost:     prep.giftWrapCost,
+			TaxCost:          prep.taxCost,
+			Discount:         prep.discount,
+			Promotions:       prep.promotions,
+			DeliveryDate:     req.GetDeliveryDate(),
+			PointsRedeemed:   prep.pointsRedeemed,
+			PointsDiscount:   prep.pointsDiscount,
+			GrandTotal:       prep.grandTotal,
+
+			AppliedPromotions: prep.appliedPromotions,
+		}, cs.itemDetail(req.GetItemDetail())),
+		Total: cs.normalizeAmount(&total),
+	}, nil
+}
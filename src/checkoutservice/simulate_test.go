@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSimulateOrderHasNoSideEffects(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.orderSimulation = true
+
+	resp, err := cs.SimulateOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("SimulateOrder() failed: %v", err)
+	}
+	if n := len(resp.GetOrder().GetItems()); n != 2 {
+		t.Errorf("simulated order has %d items, want 2", n)
+	}
+	if total := resp.GetTotal(); total.GetCurrencyCode() != "USD" || total.GetUnits() != 726 || total.GetNanos() != 980000000 {
+		t.Errorf("simulated total = %v, want 726.98 USD", total)
+	}
+
+	// Reads reach the downstream services...
+	if f.cart.getCalls == 0 || f.catalog.calls == 0 || f.shipping.quoteCalls == 0 {
+		t.Errorf("got %d cart, %d catalog and %d quote reads, want all of them", f.cart.getCalls, f.catalog.calls, f.shipping.quoteCalls)
+	}
+	// ...but nothing is written.
+	if f.payment.calls != 0 || len(f.shipping.shipped) != 0 || len(f.email.sent) != 0 || len(f.cart.emptied) != 0 {
+		t.Errorf("simulation had side effects: %d charges, %d shipments, %d emails, %d emptied carts",
+			f.payment.calls, len(f.shipping.shipped), len(f.email.sent), len(f.cart.emptied))
+	}
+}
+
+func TestSimulateOrderDisabled(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	if _, err := cs.SimulateOrder(context.Background(), testOrderRequest()); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("SimulateOrder() error = %v, want Unimplemented", err)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// confirmBySMS sends the order confirmation to phoneNumber through the SMS
+// service and reports whether it was sent.
+func (cs *checkoutService) confirmBySMS(ctx context.Context, phoneNumber string, order *pb.OrderResult) bool {
+	smsStart := time.Now()
+	_, err := cs.clients().sms().SendOrderConfirmation(ctx, &pb.SendSmsConfirmationRequest{
+		PhoneNumber: phoneNumber,
+		Order:       order})
+	cs.observeStage(ctx, "sms", smsStart)
+	if err != nil {
+		log.Warnf("failed to send order confirmation SMS to %q: %+v", phoneNumber, err)
+		return false
+	}
+	orderLog.Infof("order confirmation SMS sent to %q", phoneNumber)
+	return true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderConfirmationChannels(t *testing.T) {
+	tests := []struct {
+		name      string
+		channel   pb.ConfirmationChannel
+		wantEmail int
+		wantSMS   int
+	}{
+		{"email only", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL, 1, 0},
+		{"sms only", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_SMS, 0, 1},
+		{"email and sms", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL_AND_SMS, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			req := testOrderRequest()
+			req.PhoneNumber = "+1 (415) 555-0123"
+			req.ConfirmationChannel = tt.channel
+
+			res, err := cs.PlaceOrder(context.Background(), req)
+			if err != nil {
+				t.Fatalf("PlaceOrder() failed: %v", err)
+			}
+			if n := f.email.sentCount(); n != tt.wantEmail {
+				t.Errorf("emails sent = %d, want %d", n, tt.wantEmail)
+			}
+			if n := f.sms.sentCount(); n != tt.wantSMS {
+				t.Fatalf("SMS sent = %d, want %d", n, tt.wantSMS)
+			}
+			if tt.wantSMS > 0 {
+				sms := f.sms.sent[0]
+				if sms.GetPhoneNumber() != "+14155550123" {
+					t.Errorf("SMS sent to %q, want +14155550123", sms.GetPhoneNumber())
+				}
+				if sms.GetOrder().GetOrderId() != res.GetOrder().GetOrderId() {
+					t.Errorf("SMS confirms order %q, want %q", sms.GetOrder().GetOrderId(), res.GetOrder().GetOrderId())
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceOrderValidatesPhoneNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		phone    string
+		channel  pb.ConfirmationChannel
+		smsAddr  bool
+		wantCode codes.Code
+	}{
+		{"valid", "+33 6 12 34 56 78", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_SMS, true, codes.OK},
+		{"missing country code", "0612345678", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL_AND_SMS, true, codes.InvalidArgument},
+		{"letters", "+1 415 CALL ME", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL, true, codes.InvalidArgument},
+		{"missing for sms", "", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_SMS, true, codes.InvalidArgument},
+		{"sms not available", "+14155550123", pb.ConfirmationChannel_CONFIRMATION_CHANNEL_SMS, false, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			if !tt.smsAddr {
+				cs.smsSvcAddr = ""
+			}
+			req := testOrderRequest()
+			req.PhoneNumber = tt.phone
+			req.ConfirmationChannel = tt.channel
+
+			_, err := cs.PlaceOrder(context.Background(), req)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("PlaceOrder() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// splitAuthorization is the amount held on one of the cards an order is
+// split across.
+type splitAuthorization struct {
+	txID   string
+	key    string
+	brand  string
+	amount *pb.Money
+}
+
+// validatePaymentSplits checks the cards and amounts of the payment splits
+// of req, which replace its credit card. The amounts must be positive and
+// in the user currency, which must already be normalized.
+func (cs *checkoutService) validatePaymentSplits(v *violations, req *pb.PlaceOrderRequest) {
+	if !cs.splitTender {
+		v.add("payment_splits", "split payments are not available")
+		return
+	}
+	if req.GetCreditCard() != nil {
+		v.add("credit_card", "must not be set along with payment_splits")
+	}
+	for i, split := range req.GetPaymentSplits() {
+		field := fmt.Sprintf("payment_splits[%d]", i)
+		cs.validateCreditCard(v, field+".credit_card", split.GetCreditCard())
+		amount := split.GetAmount()
+		switch {
+		case amount.GetCurrencyCode() != req.GetUserCurrency():
+			v.add(field+".amount", "must be in %s, got %q", req.GetUserCurrency(), amount.GetCurrencyCode())
+		case amount.GetUnits() < 0 || amount.GetNanos() < 0 || (amount.GetUnits() == 0 && amount.GetNanos() == 0):
+			v.add(field+".amount", "must be positive, got %s", displayAmount(amount))
+		}
+	}
+}
+
+// checkPaymentSplits verifies that the amounts of splits add up to total.
+func checkPaymentSplits(splits []*pb.PaymentSplit, total *pb.Money) error {
+	amounts := make([]namedAmount, len(splits))
+	for i, split := range splits {
+		amounts[i] = namedAmount{fmt.Sprintf("payment split %d", i), *split.GetAmount()}
+	}
+	sum, err := sumAmounts(total.GetCurrencyCode(), amounts)
+	if err != nil {
+		return err
+	}
+	if sum.GetUnits() != total.GetUnits() || sum.GetNanos() != total.GetNanos() {
+		return status.Errorf(codes.InvalidArgument, "payment splits add up to %s, the order total is %s",
+			displayAmount(&sum), displayAmount(total))
+	}
+	return nil
+}
+
+// authorizeSplits authorizes the amount of every split on its card, in
+// order. If one fails, the amounts already authorized are voided.
+func (cs *checkoutService) authorizeSplits(ctx context.Context, orderID string, splits []*pb.PaymentSplit) ([]splitAuthorization, error) {
+	var auths []splitAuthorization
+	for i, split := range splits {
+		brand := cardBrand(split.GetCreditCard().GetCreditCardNumber())
+		key := fmt.Sprintf("%s-%d", chargeIdempotencyKey(orderID), i)
+		txID, err := cs.charge(withCardBrand(ctx, brand), split.GetAmount(), split.GetCreditCard(), key, true)
+		if err != nil {
+			log.Warnf("payment split %d of order %s failed, voiding the %d already authorized", i, orderID, len(auths))
+			cs.voidSplits(ctx, auths)
+			return nil, err
+		}
+		orderLog.Infof("payment split %d authorized (transaction_id: %s)", i, txID)
+		auths = append(auths, splitAuthorization{txID: txID, key: key, brand: brand, amount: split.GetAmount()})
+	}
+	return auths, nil
+}
+
+// captureSplits captures the amounts authorized on every card, in order. If
+// one fails, the authorizations not captured yet are released; the amounts
+// already captured are not refunded.
+func (cs *checkoutService) captureSplits(ctx context.Context, auths []splitAuthorization) error {
+	for i, a := range auths {
+		if err := cs.capturePayment(withCardBrand(ctx, a.brand), a.txID, a.amount); err != nil {
+			log.Warnf("capture of payment split %d failed, releasing the %d authorizations left", i, len(auths)-i)
+			for _, left := range auths[i:] {
+				cs.releaseAuthorization(cs.paymentClient(withCardBrand(ctx, left.brand)), left.key, "a capture of the order failed")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// voidSplits releases the amounts authorized on every card.
+func (cs *checkoutService) voidSplits(ctx context.Context, auths []splitAuthorization) {
+	for _, a := range auths {
+		cs.voidPayment(withCardBrand(ctx, a.brand), a.txID)
+	}
+}
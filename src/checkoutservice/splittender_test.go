@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+const secondCardNumber = "5555555555554444"
+
+// splitOrderRequest returns an order of the default cart, 726.98 USD, paid
+// with the given amounts on a Visa then a Mastercard.
+func splitOrderRequest(first, second *pb.Money) *pb.PlaceOrderRequest {
+	req := testOrderRequest()
+	visa := req.CreditCard
+	mastercard := proto.Clone(visa).(*pb.CreditCardInfo)
+	mastercard.CreditCardNumber = secondCardNumber
+	req.CreditCard = nil
+	req.PaymentSplits = []*pb.PaymentSplit{
+		{CreditCard: visa, Amount: first},
+		{CreditCard: mastercard, Amount: second}}
+	return req
+}
+
+func TestPlaceOrderSplitsPayment(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.splitTender = true
+
+	first := &pb.Money{CurrencyCode: "USD", Units: 100}
+	second := &pb.Money{CurrencyCode: "USD", Units: 626, Nanos: 980000000}
+	if _, err := cs.PlaceOrder(context.Background(), splitOrderRequest(first, second)); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if len(f.payment.charges) != 2 {
+		t.Fatalf("got %d charges, want one per card", len(f.payment.charges))
+	}
+	for i, want := range []*pb.Money{first, second} {
+		c := f.payment.charges[i]
+		if !c.GetAuthorizeOnly() || !proto.Equal(c.GetAmount(), want) {
+			t.Errorf("charge %d = %v, want an authorization of %v", i, c, want)
+		}
+	}
+	if f.payment.charges[1].GetCreditCard().GetCreditCardNumber() != secondCardNumber {
+		t.Errorf("second split charged card %s", f.payment.charges[1].GetCreditCard().GetCreditCardNumber())
+	}
+	if len(f.payment.captured) != 2 || len(f.payment.voided) != 0 {
+		t.Errorf("captured %v and voided %v, want both authorizations captured", f.payment.captured, f.payment.voided)
+	}
+}
+
+func TestPlaceOrderSplitPaymentRollsBack(t *testing.T) {
+	f := newFakeDownstreams()
+	f.payment.declined = map[string]bool{secondCardNumber: true}
+	cs := newTestCheckoutService(t, f)
+	cs.splitTender = true
+
+	req := splitOrderRequest(&pb.Money{CurrencyCode: "USD", Units: 100}, &pb.Money{CurrencyCode: "USD", Units: 626, Nanos: 980000000})
+	if _, err := cs.PlaceOrder(context.Background(), req); err == nil {
+		t.Fatal("PlaceOrder() succeeded, want the decline of the second card")
+	}
+	if len(f.payment.voided) != 1 || f.payment.voided[0] != "tx-1" {
+		t.Errorf("voided %v, want the authorization of the first card", f.payment.voided)
+	}
+	if len(f.payment.captured) != 0 || len(f.shipping.shipped) != 0 {
+		t.Errorf("order was captured %v or shipped after a failed split", f.payment.captured)
+	}
+}
+
+func TestPlaceOrderSplitCaptureFailureReleasesAuthorizations(t *testing.T) {
+	f := newFakeDownstreams()
+	f.payment.failCaptures = map[string]bool{"tx-2": true}
+	cs := newTestCheckoutService(t, f)
+	cs.splitTender = true
+
+	req := splitOrderRequest(&pb.Money{CurrencyCode: "USD", Units: 100}, &pb.Money{CurrencyCode: "USD", Units: 300})
+	req.PaymentSplits = append(req.PaymentSplits, &pb.PaymentSplit{
+		CreditCard: proto.Clone(req.PaymentSplits[0].GetCreditCard()).(*pb.CreditCardInfo),
+		Amount:     &pb.Money{CurrencyCode: "USD", Units: 326, Nanos: 980000000}})
+	if _, err := cs.PlaceOrder(context.Background(), req); err == nil {
+		t.Fatal("PlaceOrder() succeeded, want the failed capture")
+	}
+	if len(f.payment.captured) != 1 || f.payment.captured[0] != "tx-1" {
+		t.Errorf("captured %v, want the first split only", f.payment.captured)
+	}
+	var want []string
+	for _, c := range f.payment.charges[1:] {
+		want = append(want, c.GetIdempotencyKey())
+	}
+	if len(want) != 2 || strings.Join(f.payment.voidedKeys, ",") != strings.Join(want, ",") {
+		t.Errorf("released authorizations %v, want those of the splits left, %v", f.payment.voidedKeys, want)
+	}
+}
+
+func TestPlaceOrderSplitPaymentMustAddUp(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.splitTender = true
+
+	req := splitOrderRequest(&pb.Money{CurrencyCode: "USD", Units: 100}, &pb.Money{CurrencyCode: "USD", Units: 200})
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("PlaceOrder() = %v, want InvalidArgument", err)
+	}
+	if n := f.payment.chargeCount(); n != 0 {
+		t.Errorf("cards were charged %d times", n)
+	}
+}
+
+func TestValidatePaymentSplits(t *testing.T) {
+	cs := newTestCheckoutService(t, newFakeDownstreams())
+	req := splitOrderRequest(&pb.Money{CurrencyCode: "EUR", Units: 1}, &pb.Money{CurrencyCode: "USD"})
+	if err := cs.validateOrderRequest(req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("validateOrderRequest() with split payments disabled = %v, want InvalidArgument", err)
+	}
+
+	cs.splitTender = true
+	err := cs.validateOrderRequest(req)
+	var fields []string
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, fv := range br.GetFieldViolations() {
+				fields = append(fields, fv.GetField())
+			}
+		}
+	}
+	want := []string{"payment_splits[0].amount", "payment_splits[1].amount"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("field violations = %v, want %v", fields, want)
+	}
+}
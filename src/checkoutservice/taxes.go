@@ -0,0 +1,43 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+	money "github.com/abruneau/hipstershop/src/checkoutservice/money"
+)
+
+// taxExempt reports whether a product is exempt from sales tax, either
+// according to the catalog or because it is listed in TAX_EXEMPT_PRODUCTS.
+func (cs *checkoutService) taxExempt(product *pb.Product) bool {
+	return product.GetTaxExempt() || cs.taxExemptProducts[product.GetId()]
+}
+
+// taxBase sums the cost, for their quantity, of the order items which are
+// not exempt from tax.
+func taxBase(items []*pb.OrderItem, exempt map[string]bool, currency string) (pb.Money, error) {
+	base := pb.Money{CurrencyCode: currency}
+	for _, it := range items {
+		if exempt[it.GetItem().GetProductId()] {
+			continue
+		}
+		sum, err := money.Sum(base, money.MultiplySlow(*it.GetCost(), uint32(it.GetItem().GetQuantity())))
+		if err != nil {
+			return pb.Money{}, err
+		}
+		base = sum
+	}
+	return base, nil
+}
+
+// applyTax computes the sales tax of the taxable items of prep at the
+// configured rate.
+func (cs *checkoutService) applyTax(prep *orderPrep, userCurrency string) error {
+	base, err := taxBase(prep.orderItems, prep.taxExempt, userCurrency)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to compute tax base: %v", err)
+	}
+	prep.taxCost = floatToMoney(moneyToFloat(&base)*cs.taxRate/100, userCurrency)
+	return nil
+}
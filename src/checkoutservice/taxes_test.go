@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestTaxBase(t *testing.T) {
+	items := []*pb.OrderItem{
+		{Item: &pb.CartItem{ProductId: "BOOK", Quantity: 2}, Cost: &pb.Money{CurrencyCode: "USD", Units: 12, Nanos: 500000000}},
+		{Item: &pb.CartItem{ProductId: "APPLES", Quantity: 4}, Cost: &pb.Money{CurrencyCode: "USD", Units: 3}},
+		{Item: &pb.CartItem{ProductId: "LAMP", Quantity: 1}, Cost: &pb.Money{CurrencyCode: "USD", Units: 40, Nanos: 250000000}},
+		{Item: &pb.CartItem{ProductId: "EBOOK", Quantity: 1}, Cost: &pb.Money{CurrencyCode: "USD", Units: 9, Nanos: 990000000}},
+	}
+	got, err := taxBase(items, map[string]bool{"APPLES": true, "EBOOK": true}, "USD")
+	if err != nil {
+		t.Fatalf("taxBase() failed: %v", err)
+	}
+	if want := (pb.Money{CurrencyCode: "USD", Units: 65, Nanos: 250000000}); !proto.Equal(&got, &want) {
+		t.Errorf("taxBase() = %v, want %v", got, want)
+	}
+}
+
+func TestPlaceOrderTaxesOnlyTaxableItems(t *testing.T) {
+	tests := []struct {
+		name       string
+		catalog    bool
+		configured map[string]bool
+		wantTax    pb.Money
+	}{
+		{"no exemption", false, nil, pb.Money{CurrencyCode: "USD", Units: 71, Nanos: 799000000}},
+		{"exempt in catalog", true, nil, pb.Money{CurrencyCode: "USD", Units: 1, Nanos: 999000000}},
+		{"exempt by configuration", false, map[string]bool{"OLJCESPC7Z": true}, pb.Money{CurrencyCode: "USD", Units: 69, Nanos: 800000000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			f.catalog.products["66VCHSJNUP"].TaxExempt = tt.catalog
+			cs := newTestCheckoutService(t, f)
+			cs.taxRate = 10
+			cs.taxExemptProducts = tt.configured
+
+			res, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+			if err != nil {
+				t.Fatalf("PlaceOrder() failed: %v", err)
+			}
+			if !proto.Equal(res.GetOrder().GetTaxCost(), &tt.wantTax) {
+				t.Errorf("tax = %v, want %v", res.GetOrder().GetTaxCost(), tt.wantTax)
+			}
+		})
+	}
+}
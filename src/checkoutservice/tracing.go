@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// orderIDMetadataKey carries the ID of the order a downstream call is made
+// for, so that the activity of an order can be followed across services.
+const orderIDMetadataKey = "x-order-id"
+
+// validationErrorTag names the first invalid field of a rejected request.
+const validationErrorTag = "checkout.validation_error"
+
+// rpcCountTag counts the downstream calls made to place an order. Tags
+// suffixed with the name of a service count the calls made to it.
+const rpcCountTag = "checkout.rpc_count"
+
+// fieldIndexRe matches the list indexes and map keys of field paths, e.g.
+// `[2]` in "guest_items[2].quantity".
+var fieldIndexRe = regexp.MustCompile(`\[[^]]*\]`)
+
+// Span is a traced unit of work.
+type Span interface {
+	// SetTag attaches a key/value pair to the span.
+	SetTag(key string, value interface{})
+	// Finish ends the span, marking it as failed if err is not nil.
+	Finish(err error)
+	// Link records that the span follows from other, usually of another
+	// trace, e.g. the request which scheduled the background work the span
+	// covers. other may already be finished.
+	Link(other Span)
+}
+
+// Tracer creates the spans reported by the service. Implementations must be
+// safe for concurrent use.
+type Tracer interface {
+	// StartSpan starts a span, child of the span in ctx if any, and returns
+	// a context carrying it.
+	StartSpan(ctx context.Context, operation string) (Span, context.Context)
+}
+
+// noopTracer discards all spans.
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(ctx context.Context, _ string) (Span, context.Context) {
+	return noopSpan{}, ctx
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetTag(string, interface{}) {}
+func (noopSpan) Finish(error)               {}
+func (noopSpan) Link(Span)                  {}
+
+// logTracer reports every finished span as an info log entry, carrying the
+// IDs of its trace, parent and linked spans so that traces can be rebuilt
+// from the logs.
+type logTracer struct{}
+
+type spanKey struct{}
+
+func (logTracer) StartSpan(ctx context.Context, operation string) (Span, context.Context) {
+	s := &logSpan{
+		operation: operation,
+		spanID:    uuid.New().String(),
+		start:     time.Now(),
+		tags:      make(map[string]interface{}),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*logSpan); ok {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		s.traceID = s.spanID
+	}
+	return s, context.WithValue(ctx, spanKey{}, s)
+}
+
+type logSpan struct {
+	operation string
+	traceID   string
+	spanID    string
+	parentID  string
+	start     time.Time
+
+	mu    sync.Mutex
+	tags  map[string]interface{}
+	links []string
+}
+
+func (s *logSpan) SetTag(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags[key] = value
+}
+
+func (s *logSpan) Link(other Span) {
+	if o, ok := other.(*logSpan); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.links = append(s.links, o.traceID+"/"+o.spanID)
+	}
+}
+
+func (s *logSpan) Finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fields := logrus.Fields{
+		"operation":   s.operation,
+		"trace_id":    s.traceID,
+		"span_id":     s.spanID,
+		"duration_ms": float64(time.Since(s.start)) / float64(time.Millisecond),
+		"tags":        s.tags,
+	}
+	if s.parentID != "" {
+		fields["parent_id"] = s.parentID
+	}
+	if len(s.links) > 0 {
+		fields["links"] = s.links
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	log.WithFields(fields).Info("span")
+}
+
+// newTracer returns the tracer exporting spans with exporter: "log" reports
+// them in the logs and "" or "none" discards them.
+func newTracer(exporter string) (Tracer, error) {
+	switch exporter {
+	case "", "none":
+		return noopTracer{}, nil
+	case "log":
+		return logTracer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected log or none", exporter)
+	}
+}
+
+// trace returns the configured tracer.
+func (cs *checkoutService) trace() Tracer {
+	if cs.tracer == nil {
+		return noopTracer{}
+	}
+	return cs.tracer
+}
+
+// tagValidationError tags span with the first field reported invalid by
+// err, without list indexes nor map keys so that failures can be grouped by
+// field.
+func tagValidationError(span Span, err error) {
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok && len(br.GetFieldViolations()) > 0 {
+			span.SetTag(validationErrorTag, fieldIndexRe.ReplaceAllString(br.GetFieldViolations()[0].GetField(), ""))
+			return
+		}
+	}
+}
+
+type originSpanKey struct{}
+
+// withOriginSpan returns a context recording span as the origin of the work
+// done with it, so that background follow-ups can be linked to it.
+func withOriginSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, originSpanKey{}, span)
+}
+
+// originSpan returns the origin span recorded in ctx, or nil if none.
+func originSpan(ctx context.Context) Span {
+	span, _ := ctx.Value(originSpanKey{}).(Span)
+	return span
+}
+
+// startLinkedSpan starts the root span of background work, linked to the
+// span of the request which scheduled it, if any.
+func (cs *checkoutService) startLinkedSpan(origin Span, operation string) (Span, context.Context) {
+	span, ctx := cs.trace().StartSpan(context.Background(), operation)
+	if origin != nil {
+		span.Link(origin)
+	}
+	return span, ctx
+}
+
+type callResourceKey struct{}
+
+// withCallResource names the purpose of the downstream calls made with ctx,
+// e.g. "currency.convert.shipping", to tell apart the spans of calls to the
+// same method.
+func withCallResource(ctx context.Context, resource string) context.Context {
+	return context.WithValue(ctx, callResourceKey{}, resource)
+}
+
+// callResource returns the resource name set on ctx, or method if none.
+func callResource(ctx context.Context, method string) string {
+	if r, ok := ctx.Value(callResourceKey{}).(string); ok {
+		return r
+	}
+	return method
+}
+
+type orderIDKey struct{}
+
+// withOrderID returns a context tagging the downstream calls made with it
+// with orderID.
+func withOrderID(ctx context.Context, orderID string) context.Context {
+	return context.WithValue(ctx, orderIDKey{}, orderID)
+}
+
+// rpcCounter counts the downstream calls made for a request, retries
+// included.
+type rpcCounter struct {
+	mu        sync.Mutex
+	total     int
+	byService map[string]int
+}
+
+type rpcCounterKey struct{}
+
+// withRPCCounter returns a context counting the downstream calls made with
+// it in the returned counter.
+func withRPCCounter(ctx context.Context) (context.Context, *rpcCounter) {
+	c := &rpcCounter{byService: make(map[string]int)}
+	return context.WithValue(ctx, rpcCounterKey{}, c), c
+}
+
+func (c *rpcCounter) add(service string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total++
+	c.byService[service]++
+}
+
+// tag reports the counts on span.
+func (c *rpcCounter) tag(span Span) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	span.SetTag(rpcCountTag, c.total)
+	for service, n := range c.byService {
+		span.SetTag(rpcCountTag+"."+service, n)
+	}
+}
+
+// downstreamCallInterceptor traces every call made to service at target and
+// logs its latency at debug level. Unlike stage durations, this isolates
+// the time spent in the network and the downstream service. Calls made for
+// an order carry its ID in their metadata and span.
+func (cs *checkoutService) downstreamCallInterceptor(service, target string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		span, ctx := cs.trace().StartSpan(ctx, "checkout.downstream_call")
+		span.SetTag("service", service)
+		span.SetTag("resource", callResource(ctx, method))
+		span.SetTag("rpc", method)
+		span.SetTag("target", target)
+		if c, ok := ctx.Value(rpcCounterKey{}).(*rpcCounter); ok {
+			c.add(service)
+		}
+		if orderID, ok := ctx.Value(orderIDKey{}).(string); ok {
+			span.SetTag("order_id", orderID)
+			ctx = metadata.AppendToOutgoingContext(ctx, orderIDMetadataKey, orderID)
+		}
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		latency := time.Since(start)
+		span.SetTag("latency_ms", float64(latency)/float64(time.Millisecond))
+		span.Finish(err)
+		if log.IsLevelEnabled(logrus.DebugLevel) {
+			log.WithFields(logrus.Fields{
+				"rpc":     method,
+				"target":  target,
+				"latency": latency.String(),
+				"code":    status.Code(err).String(),
+			}).Debug("downstream call")
+		}
+		return err
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+func TestPlaceOrderSpan(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	tr := &recordingTracer{}
+	cs.tracer = tr
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+
+	spans := tr.finished("checkout.place_order")
+	if len(spans) != 1 {
+		t.Fatalf("got %d place_order spans, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.err != nil {
+		t.Errorf("span error = %v, want none", s.err)
+	}
+	want := map[string]interface{}{
+		"user_id":       "user-1",
+		"user_currency": "USD",
+		"order_id":      resp.GetOrder().GetOrderId(),
+		"order_status":  "ORDER_STATUS_COMPLETED",
+		"item_count":    2,
+	}
+	for k, v := range want {
+		if s.tags[k] != v {
+			t.Errorf("span tag %q = %v, want %v", k, s.tags[k], v)
+		}
+	}
+}
+
+func TestPlaceOrderSpanRecordsError(t *testing.T) {
+	f := newFakeDownstreams()
+	f.payment.err = status.Error(codes.InvalidArgument, "card declined")
+	cs := newTestCheckoutService(t, f)
+	tr := &recordingTracer{}
+	cs.tracer = tr
+
+	if _, err := cs.PlaceOrder(context.Background(), testOrderRequest()); err == nil {
+		t.Fatal("PlaceOrder() should have failed")
+	}
+	spans := tr.finished("checkout.place_order")
+	if len(spans) != 1 || status.Code(spans[0].err) != codes.InvalidArgument {
+		t.Errorf("spans = %v, want one failed with InvalidArgument", spans)
+	}
+}
+
+func TestPlaceOrderSpanTagsValidationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*pb.PlaceOrderRequest)
+		want   string
+	}{
+		{"email", func(r *pb.PlaceOrderRequest) { r.Email = "not an email" }, "email"},
+		{"guest item", func(r *pb.PlaceOrderRequest) {
+			r.GuestItems = []*pb.CartItem{{ProductId: "OLJCESPC7Z", Quantity: -1}}
+		}, "guest_items.quantity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := newTestCheckoutService(t, newFakeDownstreams())
+			tr := &recordingTracer{}
+			cs.tracer = tr
+			req := testOrderRequest()
+			tt.modify(req)
+
+			if _, err := cs.PlaceOrder(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("PlaceOrder() = %v, want InvalidArgument", err)
+			}
+			spans := tr.finished("checkout.place_order")
+			if len(spans) != 1 {
+				t.Fatalf("got %d place_order spans, want 1", len(spans))
+			}
+			if got := spans[0].tags[validationErrorTag]; got != tt.want {
+				t.Errorf("span tag %q = %v, want %q", validationErrorTag, got, tt.want)
+			}
+			if status.Code(spans[0].err) != codes.InvalidArgument {
+				t.Errorf("span error = %v, want InvalidArgument", spans[0].err)
+			}
+		})
+	}
+}
+
+func TestDownstreamCallLogsLatency(t *testing.T) {
+	var buf bytes.Buffer
+	out, lvl := log.Out, log.GetLevel()
+	log.Out = &buf
+	log.SetLevel(logrus.DebugLevel)
+	defer func() {
+		log.Out = out
+		log.SetLevel(lvl)
+	}()
+
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	tr := &recordingTracer{}
+	cs.tracer = tr
+
+	if _, err := cs.getUserCart(context.Background(), "user-1"); err != nil {
+		t.Fatalf("getUserCart() failed: %v", err)
+	}
+
+	var entry map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]interface{}
+		if json.Unmarshal([]byte(line), &e) == nil && e["message"] == "downstream call" {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatalf("no downstream call logged: %s", buf.String())
+	}
+	if entry["rpc"] != "/hipstershop.CartService/GetCart" || entry["target"] != cs.cartSvcAddr {
+		t.Errorf("logged rpc %v to %v, want GetCart to %s", entry["rpc"], entry["target"], cs.cartSvcAddr)
+	}
+	if _, err := time.ParseDuration(fmt.Sprint(entry["latency"])); err != nil {
+		t.Errorf("logged latency %v is not a duration", entry["latency"])
+	}
+
+	spans := tr.finished("checkout.downstream_call")
+	if len(spans) != 1 {
+		t.Fatalf("got %d downstream call spans, want 1", len(spans))
+	}
+	if _, ok := spans[0].tags["latency_ms"].(float64); !ok || spans[0].tags["service"] != "cartservice" {
+		t.Errorf("span tags = %v, want the cartservice latency", spans[0].tags)
+	}
+}
+
+func TestDownstreamSpansNameTheirResource(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	tr := &recordingTracer{}
+	cs.tracer = tr
+
+	req := testOrderRequest()
+	req.UserCurrency = "EUR"
+	if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+
+	resources := make(map[interface{}]int)
+	for _, s := range tr.finished("checkout.downstream_call") {
+		resources[s.tags["resource"]]++
+	}
+	want := map[string]int{
+		"currency.convert.item":            2,
+		"currency.convert.shipping":        1,
+		"/hipstershop.CartService/GetCart": 1,
+	}
+	for r, n := range want {
+		if resources[r] != n {
+			t.Errorf("got %d spans with resource %q, want %d (all: %v)", resources[r], r, n, resources)
+		}
+	}
+}
+
+func TestDownstreamCallsCarryOrderID(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.tagOrderID = true
+	tr := &recordingTracer{}
+	cs.tracer = tr
+
+	resp, err := cs.PlaceOrder(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	orderID := resp.GetOrder().GetOrderId()
+	if len(f.cart.orderIDs) != 1 || f.cart.orderIDs[0] != orderID {
+		t.Errorf("GetCart got order IDs %v, want [%s]", f.cart.orderIDs, orderID)
+	}
+	for _, s := range tr.finished("checkout.downstream_call") {
+		if s.tags["order_id"] != orderID {
+			t.Errorf("span of %v has order_id %v, want %s", s.tags["rpc"], s.tags["order_id"], orderID)
+		}
+	}
+}
+
+func TestPlaceOrderTagsRPCCount(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.tagRPCCount = true
+	tr := &recordingTracer{}
+	cs.tracer = tr
+
+	req := testOrderRequest()
+	req.UserCurrency = "EUR"
+	if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	spans := tr.finished("checkout.place_order")
+	if len(spans) != 1 {
+		t.Fatalf("got %d place_order spans, want 1", len(spans))
+	}
+	tags := spans[0].tags
+	calls := len(tr.finished("checkout.downstream_call"))
+	if tags[rpcCountTag] != calls {
+		t.Errorf("%s = %v, want the %d downstream calls", rpcCountTag, tags[rpcCountTag], calls)
+	}
+	for service, n := range map[string]int{
+		"productcatalogservice": f.catalog.calls,
+		"currencyservice":       f.currency.callCount() + f.currency.batchCallCount(),
+		"paymentservice":        f.payment.chargeCount(),
+		"shippingservice":       len(f.shipping.shipped) + 1,
+	} {
+		if got := tags[rpcCountTag+"."+service]; got != n {
+			t.Errorf("%s.%s = %v, want %d", rpcCountTag, service, got, n)
+		}
+	}
+}
+
+func TestLogTracerReportsSpans(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Out
+	log.Out = &buf
+	defer func() { log.Out = out }()
+
+	tr, err := newTracer("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, ctx := tr.StartSpan(context.Background(), "root")
+	child, _ := tr.StartSpan(ctx, "child")
+	child.SetTag("order_id", "order-1")
+	child.Finish(errors.New("boom"))
+	root.Finish(nil)
+
+	spans := make(map[string]map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]interface{}
+		if json.Unmarshal([]byte(line), &e) == nil && e["message"] == "span" {
+			spans[fmt.Sprint(e["operation"])] = e
+		}
+	}
+	r, c := spans["root"], spans["child"]
+	if r == nil || c == nil {
+		t.Fatalf("spans not logged: %s", buf.String())
+	}
+	if c["trace_id"] != r["trace_id"] || c["parent_id"] != r["span_id"] {
+		t.Errorf("child span %v is not a child of %v", c, r)
+	}
+	if c["error"] != "boom" || c["tags"].(map[string]interface{})["order_id"] != "order-1" {
+		t.Errorf("child span = %v, want its error and tags", c)
+	}
+
+	if _, err := newTracer("zipkin"); err == nil {
+		t.Error("newTracer() should reject unknown exporters")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/abruneau/hipstershop/src/checkoutservice/genproto"
+)
+
+// addressLimits holds the maximum length, in characters, of each address
+// field. Zero means the default limit applies.
+type addressLimits struct {
+	street  int
+	city    int
+	state   int
+	country int
+	zipCode int
+}
+
+var defaultAddressLimits = addressLimits{
+	street:  256,
+	city:    128,
+	state:   128,
+	country: 128,
+	zipCode: 10,
+}
+
+var currencyCodeRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// phoneNumberRe matches E.164 phone numbers.
+var phoneNumberRe = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// phoneNumberSeparators are stripped from phone numbers before validation.
+var phoneNumberSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+
+// isoCurrencyCodes lists the active ISO 4217 currency codes, except the ones
+// reserved for testing and for transactions without currency.
+var isoCurrencyCodes = map[string]struct{}{
+	"AED": {}, "AFN": {}, "ALL": {}, "AMD": {}, "ANG": {}, "AOA": {},
+	"ARS": {}, "AUD": {}, "AWG": {}, "AZN": {}, "BAM": {}, "BBD": {},
+	"BDT": {}, "BGN": {}, "BHD": {}, "BIF": {}, "BMD": {}, "BND": {},
+	"BOB": {}, "BOV": {}, "BRL": {}, "BSD": {}, "BTN": {}, "BWP": {},
+	"BYN": {}, "BZD": {}, "CAD": {}, "CDF": {}, "CHE": {}, "CHF": {},
+	"CHW": {}, "CLF": {}, "CLP": {}, "CNY": {}, "COP": {}, "COU": {},
+	"CRC": {}, "CUC": {}, "CUP": {}, "CVE": {}, "CZK": {}, "DJF": {},
+	"DKK": {}, "DOP": {}, "DZD": {}, "EGP": {}, "ERN": {}, "ETB": {},
+	"EUR": {}, "FJD": {}, "FKP": {}, "GBP": {}, "GEL": {}, "GHS": {},
+	"GIP": {}, "GMD": {}, "GNF": {}, "GTQ": {}, "GYD": {}, "HKD": {},
+	"HNL": {}, "HTG": {}, "HUF": {}, "IDR": {}, "ILS": {}, "INR": {},
+	"IQD": {}, "IRR": {}, "ISK": {}, "JMD": {}, "JOD": {}, "JPY": {},
+	"KES": {}, "KGS": {}, "KHR": {}, "KMF": {}, "KPW": {}, "KRW": {},
+	"KWD": {}, "KYD": {}, "KZT": {}, "LAK": {}, "LBP": {}, "LKR": {},
+	"LRD": {}, "LSL": {}, "LYD": {}, "MAD": {}, "MDL": {}, "MGA": {},
+	"MKD": {}, "MMK": {}, "MNT": {}, "MOP": {}, "MRU": {}, "MUR": {},
+	"MVR": {}, "MWK": {}, "MXN": {}, "MXV": {}, "MYR": {}, "MZN": {},
+	"NAD": {}, "NGN": {}, "NIO": {}, "NOK": {}, "NPR": {}, "NZD": {},
+	"OMR": {}, "PAB": {}, "PEN": {}, "PGK": {}, "PHP": {}, "PKR": {},
+	"PLN": {}, "PYG": {}, "QAR": {}, "RON": {}, "RSD": {}, "RUB": {},
+	"RWF": {}, "SAR": {}, "SBD": {}, "SCR": {}, "SDG": {}, "SEK": {},
+	"SGD": {}, "SHP": {}, "SLE": {}, "SLL": {}, "SOS": {}, "SRD": {},
+	"SSP": {}, "STN": {}, "SVC": {}, "SYP": {}, "SZL": {}, "THB": {},
+	"TJS": {}, "TMT": {}, "TND": {}, "TOP": {}, "TRY": {}, "TTD": {},
+	"TWD": {}, "TZS": {}, "UAH": {}, "UGX": {}, "USD": {}, "USN": {},
+	"UYI": {}, "UYU": {}, "UYW": {}, "UZS": {}, "VED": {}, "VES": {},
+	"VND": {}, "VUV": {}, "WST": {}, "XAF": {}, "XAG": {}, "XAU": {},
+	"XBA": {}, "XBB": {}, "XBC": {}, "XBD": {}, "XCD": {}, "XDR": {},
+	"XOF": {}, "XPD": {}, "XPF": {}, "XPT": {}, "XSU": {}, "XUA": {},
+	"YER": {}, "ZAR": {}, "ZMW": {}, "ZWL": {},
+}
+
+// violations accumulates the invalid fields of a request so that they can
+// all be reported at once.
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, format string, args ...interface{}) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...)})
+}
+
+// err returns an InvalidArgument error listing every violation, both in its
+// message and as a BadRequest detail, or nil if there is none.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(v))
+	for i, fv := range v {
+		msgs[i] = fv.GetField() + ": " + fv.GetDescription()
+	}
+	st := status.New(codes.InvalidArgument, "invalid order request: "+strings.Join(msgs, "; "))
+	if withDetails, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// validateOrderRequest checks the parts of an order request that can be
+// verified without calling any downstream service. Every invalid field is
+// reported, not only the first one. The currency code and phone number are
+// normalized in place.
+func (cs *checkoutService) validateOrderRequest(req *pb.PlaceOrderRequest) error {
+	var v violations
+	if f := req.GetConfirmationAttachmentFormat(); f != "" && f != attachmentFormatCSV {
+		v.add("confirmation_attachment_format", "unsupported format %q", f)
+	}
+	cs.validateAddress(&v, req.GetAddress())
+	if len(req.GetPaymentSplits()) == 0 {
+		cs.validateCreditCard(&v, "credit_card", req.GetCreditCard())
+	}
+	if req.GetEmail() == "" {
+		v.add("email", "email address is required")
+	} else if _, err := mail.ParseAddress(req.GetEmail()); err != nil {
+		v.add("email", "invalid email address %q", req.GetEmail())
+	}
+	// Currency codes are case-insensitive, downstream services expect them
+	// in upper case.
+	req.UserCurrency = strings.ToUpper(strings.TrimSpace(req.GetUserCurrency()))
+	if !currencyCodeRe.MatchString(req.GetUserCurrency()) {
+		v.add("user_currency", "%q is not a 3-letter ISO 4217 currency code", req.GetUserCurrency())
+	} else if _, ok := isoCurrencyCodes[req.GetUserCurrency()]; cs.strictCurrencyCodes && !ok {
+		v.add("user_currency", "unknown currency code %q", req.GetUserCurrency())
+	}
+	if len(req.GetPaymentSplits()) > 0 {
+		cs.validatePaymentSplits(&v, req)
+	}
+	cs.validateConfirmationChannel(&v, req)
+	req.DeliveryDate = cs.validateDeliveryDate(&v, req.GetDeliveryDate())
+	switch points := req.GetPointsToRedeem(); {
+	case points < 0:
+		v.add("points_to_redeem", "points to redeem must not be negative, got %d", points)
+	case points > 0 && cs.loyaltySvcAddr == "":
+		v.add("points_to_redeem", "loyalty points redemption is not available")
+	}
+	for i, it := range req.GetGuestItems() {
+		if it.GetProductId() == "" {
+			v.add(fmt.Sprintf("guest_items[%d].product_id", i), "product id is required")
+		}
+		if it.GetQuantity() <= 0 {
+			v.add(fmt.Sprintf("guest_items[%d].quantity", i), "quantity must be positive, got %d", it.GetQuantity())
+		}
+	}
+	cs.validateItemNotes(&v, req)
+	validateMetadata(&v, req.GetMetadata())
+	cs.metadataSchema.validate(&v, req.GetMetadata())
+	return v.err()
+}
+
+// validateConfirmationChannel checks that the phone number is valid, and
+// present when the confirmation is sent by SMS.
+func (cs *checkoutService) validateConfirmationChannel(v *violations, req *pb.PlaceOrderRequest) {
+	req.PhoneNumber = phoneNumberSeparators.Replace(req.GetPhoneNumber())
+	sms := req.GetConfirmationChannel() != pb.ConfirmationChannel_CONFIRMATION_CHANNEL_EMAIL
+	if sms && cs.smsSvcAddr == "" {
+		v.add("confirmation_channel", "SMS confirmations are not available")
+	}
+	switch {
+	case req.GetPhoneNumber() == "":
+		if sms {
+			v.add("phone_number", "phone number is required for SMS confirmations")
+		}
+	case !phoneNumberRe.MatchString(req.GetPhoneNumber()):
+		v.add("phone_number", "%q is not an E.164 phone number", req.GetPhoneNumber())
+	}
+}
+
+// defaultCardExpirySkew is how long after the end of their expiry month,
+// by the server clock, cards are still accepted by default. It covers the
+// clock skew and the time zone of the card holder.
+const defaultCardExpirySkew = 24 * time.Hour
+
+// validateCreditCard rejects missing cards and cards which expired, a card
+// being valid until the end of its expiry month plus the configured skew.
+// field is the path of the card in the request.
+func (cs *checkoutService) validateCreditCard(v *violations, field string, card *pb.CreditCardInfo) {
+	if card == nil {
+		v.add(field, "payment method required")
+		return
+	}
+	month := card.GetCreditCardExpirationMonth()
+	if month < 1 || month > 12 {
+		v.add(field+".credit_card_expiration_month", "invalid expiration month %d", month)
+		return
+	}
+	end := time.Date(int(card.GetCreditCardExpirationYear()), time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	if !cs.now().Before(end.Add(cs.cardExpirySkew)) {
+		v.add(field, "card expired in %02d/%d", month, card.GetCreditCardExpirationYear())
+	}
+}
+
+// validateAddress rejects missing addresses and address fields longer than
+// the configured limits.
+func (cs *checkoutService) validateAddress(v *violations, addr *pb.Address) {
+	if addr == nil {
+		v.add("address", "shipping address is required")
+		return
+	}
+	fields := []struct {
+		name  string
+		value string
+		limit int
+		def   int
+	}{
+		{"street_address", addr.GetStreetAddress(), cs.addressLimits.street, defaultAddressLimits.street},
+		{"city", addr.GetCity(), cs.addressLimits.city, defaultAddressLimits.city},
+		{"state", addr.GetState(), cs.addressLimits.state, defaultAddressLimits.state},
+		{"country", addr.GetCountry(), cs.addressLimits.country, defaultAddressLimits.country},
+		{"zip_code", strconv.Itoa(int(addr.GetZipCode())), cs.addressLimits.zipCode, defaultAddressLimits.zipCode},
+	}
+	for _, f := range fields {
+		limit := f.limit
+		if limit <= 0 {
+			limit = f.def
+		}
+		if n := utf8.RuneCountInString(f.value); n > limit {
+			v.add("address."+f.name, "is %d characters long, at most %d are allowed", n, limit)
+		}
+	}
+}
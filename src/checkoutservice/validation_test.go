@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateAddressLengths(t *testing.T) {
+	tests := []struct {
+		name      string
+		street    string
+		limits    addressLimits
+		wantCode  codes.Code
+		wantField string
+	}{
+		{"valid lengths", strings.Repeat("a", 256), addressLimits{}, codes.OK, ""},
+		{"overlong street", strings.Repeat("a", 257), addressLimits{}, codes.InvalidArgument, "street_address"},
+		{"multi-byte characters", strings.Repeat("é", 256), addressLimits{}, codes.OK, ""},
+		{"configured limit", strings.Repeat("a", 33), addressLimits{street: 32}, codes.InvalidArgument, "street_address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := &checkoutService{addressLimits: tt.limits}
+			addr := testOrderRequest().Address
+			addr.StreetAddress = tt.street
+
+			var v violations
+			cs.validateAddress(&v, addr)
+			err := v.err()
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("validateAddress() code = %v, want %v (err: %v)", status.Code(err), tt.wantCode, err)
+			}
+			if tt.wantField != "" && !strings.Contains(err.Error(), tt.wantField) {
+				t.Errorf("error %q should name the field %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderRejectsOverlongAddress(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.Address.City = strings.Repeat("x", 500)
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "city") {
+		t.Errorf("PlaceOrder() = %v, want InvalidArgument naming the city", err)
+	}
+	if f.cart.getCalls != 0 {
+		t.Error("invalid address should be rejected before fetching the cart")
+	}
+}
+
+func TestPlaceOrderRequiresPaymentMethod(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.CreditCard = nil
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "payment method required") {
+		t.Errorf("PlaceOrder() = %v, want InvalidArgument requiring a payment method", err)
+	}
+	if f.cart.getCalls != 0 || f.payment.chargeCount() != 0 {
+		t.Error("request without a payment method should be rejected before reaching downstream services")
+	}
+}
+
+func TestPlaceOrderCardExpiry(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		year, month int32
+		now         time.Time
+		wantErr     bool
+	}{
+		{"expiring this month", 2020, 6, time.Date(2020, 6, 30, 23, 0, 0, 0, time.UTC), false},
+		{"within the skew", 2020, 6, time.Date(2020, 7, 1, 2, 0, 0, 0, time.UTC), false},
+		{"past the skew", 2020, 6, time.Date(2020, 7, 1, 4, 0, 0, 0, time.UTC), true},
+		{"expired last year", 2019, 12, time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDownstreams()
+			cs := newTestCheckoutService(t, f)
+			cs.cardExpirySkew = 3 * time.Hour
+			clock := &fakeClock{now: tt.now}
+			cs.clock = clock.Now
+
+			req := testOrderRequest()
+			req.CreditCard.CreditCardExpirationYear = tt.year
+			req.CreditCard.CreditCardExpirationMonth = tt.month
+			_, err := cs.PlaceOrder(context.Background(), req)
+			if tt.wantErr && status.Code(err) != codes.InvalidArgument {
+				t.Errorf("PlaceOrder() error = %v, want InvalidArgument", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("PlaceOrder() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderReportsAllViolations(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+
+	req := testOrderRequest()
+	req.Address.City = strings.Repeat("x", 500)
+	req.CreditCard = nil
+	req.Email = "not an email"
+	req.UserCurrency = "euro"
+	_, err := cs.PlaceOrder(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("PlaceOrder() = %v, want InvalidArgument", err)
+	}
+
+	var fields []string
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, fv := range br.GetFieldViolations() {
+				fields = append(fields, fv.GetField())
+			}
+		}
+	}
+	want := []string{"address.city", "credit_card", "email", "user_currency"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("field violations = %v, want %v", fields, want)
+	}
+}
+
+func TestPlaceOrderNormalizesCurrencyCode(t *testing.T) {
+	f := newFakeDownstreams()
+	cs := newTestCheckoutService(t, f)
+	cs.strictCurrencyCodes = true
+
+	req := testOrderRequest()
+	req.UserCurrency = " eur"
+	if _, err := cs.PlaceOrder(context.Background(), req); err != nil {
+		t.Fatalf("PlaceOrder() failed: %v", err)
+	}
+	if got := f.payment.charges[0].GetAmount().GetCurrencyCode(); got != "EUR" {
+		t.Errorf("charged in %q, want EUR", got)
+	}
+}
+
+func TestPlaceOrderRejectsUnknownCurrencyCode(t *testing.T) {
+	for _, strict := range []bool{true, false} {
+		cs := &checkoutService{strictCurrencyCodes: strict}
+
+		req := testOrderRequest()
+		req.UserCurrency = "ABC"
+		err := cs.validateOrderRequest(req)
+		if got, want := status.Code(err), codes.InvalidArgument; strict && got != want {
+			t.Errorf("strict validation of ABC = %v, want %v", got, want)
+		} else if !strict && err != nil {
+			t.Errorf("lenient validation of ABC failed: %v", err)
+		}
+
+		req.UserCurrency = "US"
+		if status.Code(cs.validateOrderRequest(req)) != codes.InvalidArgument {
+			t.Errorf("strict=%v: validation of US should fail", strict)
+		}
+	}
+}